@@ -1,16 +1,20 @@
 package main
 
 import (
+	"context"
 	_ "embed"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
 	awslambda "github.com/aws/aws-lambda-go/lambda"
 	"github.com/stahnma/gh-flox/internal/commands"
 	"github.com/stahnma/gh-flox/internal/config"
 	lambdapkg "github.com/stahnma/gh-flox/internal/lambda"
+	"github.com/stahnma/gh-flox/internal/logging"
 )
 
 //go:embed additional_repos.json
@@ -23,27 +27,47 @@ var (
 
 func main() {
 	cfg := config.FromEnvironment()
+	if err := logging.Setup(os.Stderr, cfg.LogLevel, cfg.LogFormat); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
 	var additionalRepos []string
 	if err := json.Unmarshal(additionalReposJSON, &additionalRepos); err != nil {
-		log.Fatalf("Error parsing additional repositories JSON: %v", err)
+		fatal("parsing additional repositories JSON", err)
 	}
 
 	app, err := commands.NewApp(cfg, additionalRepos, GitSHA, GitDirty)
 	if err != nil {
-		log.Fatalf("Error initializing application: %v", err)
+		fatal("initializing application", err)
 	}
 
-	if os.Getenv("LAMBDA_TASK_ROOT") != "" {
+	if cfg.LambdaMode {
 		awslambda.Start(lambdapkg.NewHandler(app))
 	} else {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		// Restore default signal handling after the first signal so that a
+		// second Ctrl-C kills the process immediately.
+		go func() {
+			<-ctx.Done()
+			stop()
+		}()
+
 		rootCmd := app.NewRootCommand()
-		if err := rootCmd.Execute(); err != nil {
+		err := rootCmd.ExecuteContext(ctx)
+		app.WriteStats(os.Stderr)
+		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
 		if err := app.SaveCache(); err != nil {
-			log.Fatalf("Error saving cache: %v", err)
+			fatal("saving cache", err)
 		}
 	}
 }
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
+}
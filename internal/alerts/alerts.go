@@ -0,0 +1,255 @@
+// Package alerts evaluates notification rules against the latest snapshots
+// of the history store and delivers the alerts they raise to
+// Slack, a generic webhook or standard output, remembering what was sent so
+// nothing is announced twice.
+package alerts
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/stahnma/gh-flox/internal/history"
+)
+
+// Rule types.
+const (
+	// TypeAdoption alerts when a repository with more than MinStars stars
+	// adopts flox.
+	TypeAdoption = "adoption"
+	// TypeMilestone alerts when Repo's stars cross a multiple of Every or
+	// one of Milestones.
+	TypeMilestone = "milestone"
+	// TypeCountDrop alerts when the repository count of Series drops by
+	// more than Percent.
+	TypeCountDrop = "count-drop"
+	// TypeRemoval alerts when a tracked adopter removes .flox: one of Repos,
+	// or with no Repos any adopter with at least MinStars stars.
+	TypeRemoval = "removal"
+)
+
+// Rule is one alert condition.
+type Rule struct {
+	// Name labels the rule's alerts; it defaults to Type.
+	Name       string
+	Type       string
+	MinStars   int
+	Repo       string
+	Every      int
+	Milestones []int
+	Percent    float64
+	Series     string
+	Repos      []string
+}
+
+// Validate checks r and fills in defaults.
+func (r *Rule) Validate() error {
+	switch r.Type {
+	case TypeAdoption, TypeRemoval:
+	case TypeMilestone:
+		if r.Repo == "" {
+			r.Repo = "flox/flox"
+		}
+		if r.Every <= 0 && len(r.Milestones) == 0 {
+			return fmt.Errorf("%s rule needs every or milestones", r.Type)
+		}
+	case TypeCountDrop:
+		if r.Series == "" {
+			r.Series = history.TypeDotflox
+		}
+		if !slices.Contains(history.AdopterTypes, r.Series) {
+			return fmt.Errorf("%s rule: invalid series %q: use dotflox, readme or additional", r.Type, r.Series)
+		}
+		if r.Percent <= 0 {
+			return fmt.Errorf("%s rule needs a positive percent", r.Type)
+		}
+	default:
+		return fmt.Errorf("invalid rule type %q: use adoption, milestone, count-drop or removal", r.Type)
+	}
+	if r.Name == "" {
+		r.Name = r.Type
+	}
+	return nil
+}
+
+// Alert is one notable event.
+type Alert struct {
+	// Key identifies the event, so it is only sent once.
+	Key  string `json:"key"`
+	Rule string `json:"rule"`
+	// Repository is the repository the alert is about, if any; Text starts
+	// with it.
+	Repository string `json:"repository,omitempty"`
+	Text       string `json:"text"`
+	URL        string `json:"url,omitempty"`
+}
+
+// Evaluate returns the alerts rules raise, in rule order. Each rule compares
+// the two latest snapshots holding its data: repository lists or counts, or
+// the star count of its repository. Rules without two such snapshots raise
+// nothing.
+func Evaluate(s *history.Store, rules []Rule) []Alert {
+	var out []Alert
+	for _, r := range rules {
+		var prev, cur *history.Snapshot
+		if r.Type == TypeMilestone {
+			prev, cur = latestPair(s, func(snap *history.Snapshot) bool {
+				_, ok := snap.RepoStars(r.Repo)
+				return ok
+			})
+		} else {
+			prev, cur = latestPair(s, func(snap *history.Snapshot) bool { return len(snap.Types) > 0 })
+		}
+		if cur == nil {
+			continue
+		}
+		switch r.Type {
+		case TypeAdoption:
+			out = append(out, adoptions(prev, cur, r)...)
+		case TypeMilestone:
+			out = append(out, milestone(prev, cur, r)...)
+		case TypeCountDrop:
+			out = append(out, countDrop(prev, cur, r)...)
+		case TypeRemoval:
+			out = append(out, removals(prev, cur, r)...)
+		}
+	}
+	return out
+}
+
+// latestPair returns the two latest snapshots for which has is true, or nils.
+func latestPair(s *history.Store, has func(*history.Snapshot) bool) (prev, cur *history.Snapshot) {
+	for i := len(s.Snapshots) - 1; i >= 0; i-- {
+		if !has(s.Snapshots[i]) {
+			continue
+		}
+		if cur != nil {
+			return s.Snapshots[i], cur
+		}
+		cur = s.Snapshots[i]
+	}
+	return nil, nil
+}
+
+func adoptions(prev, cur *history.Snapshot, r Rule) []Alert {
+	before, okBefore := prev.Adopters()
+	after, okAfter := cur.Adopters()
+	if !okBefore || !okAfter || !sameTypes(prev, cur, history.AdopterTypes...) {
+		return nil
+	}
+	var out []Alert
+	for _, name := range sortedNames(after) {
+		a := after[name]
+		if _, ok := before[name]; ok || a.Stars == nil || *a.Stars <= r.MinStars {
+			continue
+		}
+		out = append(out, Alert{
+			Key:        "adoption/" + name,
+			Rule:       r.Name,
+			Repository: name,
+			Text:       fmt.Sprintf("%s (%d stars) adopted flox (%s)", name, *a.Stars, a.Type),
+			URL:        repoURL(name),
+		})
+	}
+	return out
+}
+
+func milestone(prev, cur *history.Snapshot, r Rule) []Alert {
+	before, okBefore := prev.RepoStars(r.Repo)
+	after, okAfter := cur.RepoStars(r.Repo)
+	if !okBefore || !okAfter || after <= before {
+		return nil
+	}
+	// Only the highest milestone crossed is announced.
+	crossed := 0
+	if r.Every > 0 && after/r.Every > before/r.Every {
+		crossed = after / r.Every * r.Every
+	}
+	for _, m := range r.Milestones {
+		if before < m && m <= after && m > crossed {
+			crossed = m
+		}
+	}
+	if crossed == 0 {
+		return nil
+	}
+	return []Alert{{
+		Key:        fmt.Sprintf("milestone/%s/%d", r.Repo, crossed),
+		Rule:       r.Name,
+		Repository: r.Repo,
+		Text:       fmt.Sprintf("%s reached %d stars (now %d)", r.Repo, crossed, after),
+		URL:        repoURL(r.Repo),
+	}}
+}
+
+func countDrop(prev, cur *history.Snapshot, r Rule) []Alert {
+	before, okBefore := prev.Types[r.Series]
+	after, okAfter := cur.Types[r.Series]
+	// A change in how repositories were counted is not a drop.
+	if !okBefore || !okAfter || before.Count == 0 || before.Origin != after.Origin || before.Scope != after.Scope {
+		return nil
+	}
+	drop := float64(before.Count-after.Count) * 100 / float64(before.Count)
+	if drop <= r.Percent {
+		return nil
+	}
+	return []Alert{{
+		Key:  fmt.Sprintf("count-drop/%s/%s", r.Series, cur.Date),
+		Rule: r.Name,
+		Text: fmt.Sprintf("%s repositories dropped %.1f%% from %d on %s to %d on %s", r.Series, drop, before.Count, prev.Date, after.Count, cur.Date),
+	}}
+}
+
+func removals(prev, cur *history.Snapshot, r Rule) []Alert {
+	before, okBefore := prev.Adopters(history.TypeDotflox)
+	after, okAfter := cur.Adopters(history.TypeDotflox)
+	if !okBefore || !okAfter || !sameTypes(prev, cur, history.TypeDotflox) {
+		return nil
+	}
+	var out []Alert
+	for _, name := range sortedNames(before) {
+		if _, ok := after[name]; ok || !tracked(r, before[name]) {
+			continue
+		}
+		out = append(out, Alert{
+			Key:        fmt.Sprintf("removal/%s/%s", name, cur.Date),
+			Rule:       r.Name,
+			Repository: name,
+			Text:       fmt.Sprintf("%s no longer has a .flox directory", name),
+			URL:        repoURL(name),
+		})
+	}
+	return out
+}
+
+// sameTypes reports whether prev and cur observed the same of types, so
+// repositories missing from one were not merely left unscanned.
+func sameTypes(prev, cur *history.Snapshot, types ...string) bool {
+	for _, t := range types {
+		if (prev.Types[t] == nil) != (cur.Types[t] == nil) {
+			return false
+		}
+	}
+	return true
+}
+
+// tracked reports whether removal rule r covers a.
+func tracked(r Rule, a history.Adopter) bool {
+	if len(r.Repos) > 0 {
+		return slices.Contains(r.Repos, a.Repository)
+	}
+	return a.Stars != nil && *a.Stars >= r.MinStars
+}
+
+func sortedNames(m map[string]history.Adopter) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func repoURL(name string) string {
+	return "https://github.com/" + name
+}
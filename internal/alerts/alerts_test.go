@@ -0,0 +1,140 @@
+package alerts
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stahnma/gh-flox/internal/history"
+)
+
+func testStore(t *testing.T) *history.Store {
+	t.Helper()
+	s := &history.Store{Version: history.StoreVersion}
+	sources := map[string]string{
+		"2024-03-01": `{"schema_version":3,"scope":"all","repositories":[
+			{"repository":"a/keep","type":"dotflox","starcount":900,"classification":"external"},
+			{"repository":"a/gone","type":"dotflox","starcount":50,"classification":"external"},
+			{"repository":"b/small","type":"dotflox","starcount":2,"classification":"external"},
+			{"repository":"c/docs","type":"readme","starcount":5,"classification":"external"}]}`,
+		"2024-03-01 stars": "The repository flox/flox has 2950 stars\n",
+		"2024-03-08": `{"schema_version":3,"scope":"all","repositories":[
+			{"repository":"a/keep","type":"dotflox","starcount":950,"classification":"external"},
+			{"repository":"d/big","type":"dotflox","starcount":1200,"classification":"external"},
+			{"repository":"e/tiny","type":"readme","starcount":3,"classification":"external"},
+			{"repository":"flox/own","type":"readme","starcount":5000,"classification":"flox-org"}]}`,
+		"2024-03-08 stars": "The repository flox/flox has 3010 stars\n",
+	}
+	for name, text := range sources {
+		imp, err := history.Parse(name, []byte(text), name[:10])
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Apply(imp)
+	}
+	return s
+}
+
+func keys(alerts []Alert) []string {
+	var out []string
+	for _, a := range alerts {
+		out = append(out, a.Key)
+	}
+	return out
+}
+
+func TestEvaluate(t *testing.T) {
+	s := testStore(t)
+	// Snapshots without the data a rule needs are passed over.
+	s.Apply(&history.Import{Date: "2024-03-09", Stars: map[string]int{"other/repo": 1}})
+	tests := []struct {
+		name string
+		rule Rule
+		want []string
+	}{
+		{"adoption", Rule{Type: TypeAdoption, MinStars: 100}, []string{"adoption/d/big"}},
+		{"adoption of any size", Rule{Type: TypeAdoption}, []string{"adoption/d/big", "adoption/e/tiny"}},
+		{"milestone every", Rule{Type: TypeMilestone, Every: 1000}, []string{"milestone/flox/flox/3000"}},
+		{"milestone list", Rule{Type: TypeMilestone, Milestones: []int{2500, 3005, 4000}}, []string{"milestone/flox/flox/3005"}},
+		{"milestone not crossed", Rule{Type: TypeMilestone, Every: 5000}, nil},
+		{"count drop", Rule{Type: TypeCountDrop, Percent: 25}, []string{"count-drop/dotflox/2024-03-08"}},
+		{"count drop below threshold", Rule{Type: TypeCountDrop, Percent: 40}, nil},
+		{"removal of tracked repos", Rule{Type: TypeRemoval, Repos: []string{"a/gone", "a/keep"}}, []string{"removal/a/gone/2024-03-08"}},
+		{"removal by stars", Rule{Type: TypeRemoval, MinStars: 10}, []string{"removal/a/gone/2024-03-08"}},
+		{"removal of any adopter", Rule{Type: TypeRemoval}, []string{"removal/a/gone/2024-03-08", "removal/b/small/2024-03-08"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			got := keys(Evaluate(s, []Rule{tt.rule}))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluate_MissingData(t *testing.T) {
+	rules := []Rule{
+		{Type: TypeAdoption, Name: "adoption"},
+		{Type: TypeRemoval, Name: "removal"},
+		{Type: TypeMilestone, Name: "milestone", Repo: "flox/flox", Every: 10},
+		{Type: TypeCountDrop, Name: "count-drop", Series: history.TypeDotflox, Percent: 1},
+	}
+	s := testStore(t)
+	s.Apply(&history.Import{Date: "2024-03-09", Types: map[string]*history.Observation{
+		history.TypeDotflox: {Origin: history.OriginText, Count: 1},
+	}})
+	if got := Evaluate(s, rules[:2]); len(got) != 0 {
+		t.Errorf("expected no alerts without repository lists, got %v", keys(got))
+	}
+	if got := Evaluate(s, rules[3:]); len(got) != 0 {
+		t.Errorf("expected no alerts across a change in counting, got %v", keys(got))
+	}
+	if got := Evaluate(&history.Store{}, rules); len(got) != 0 {
+		t.Errorf("expected no alerts from an empty store, got %v", keys(got))
+	}
+}
+
+func TestRule_Validate(t *testing.T) {
+	for _, r := range []Rule{
+		{Type: "growth"},
+		{Type: TypeMilestone},
+		{Type: TypeCountDrop, Percent: 10, Series: "floxindex"},
+		{Type: TypeCountDrop},
+	} {
+		if err := r.Validate(); err == nil {
+			t.Errorf("expected an error for %+v", r)
+		}
+	}
+	r := Rule{Type: TypeMilestone, Every: 100}
+	if err := r.Validate(); err != nil || r.Repo != "flox/flox" || r.Name != TypeMilestone {
+		t.Errorf("defaults not applied: %+v, %v", r, err)
+	}
+}
+
+func TestState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alerts := []Alert{{Key: "a"}, {Key: "b"}}
+	s.Record(alerts[:1], time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC))
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = LoadState(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Unsent(alerts); len(got) != 1 || got[0].Key != "b" {
+		t.Errorf("unsent = %+v", got)
+	}
+}
@@ -0,0 +1,106 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Destination types.
+const (
+	DestinationSlack   = "slack"
+	DestinationWebhook = "webhook"
+	DestinationStdout  = "stdout"
+)
+
+// Notifier delivers alerts.
+type Notifier interface {
+	Notify(ctx context.Context, alerts []Alert) error
+}
+
+// NewNotifier returns the notifier for a destination of type typ. Webhooks
+// post to url with client; stdout writes to w.
+func NewNotifier(typ, url string, w io.Writer, client *http.Client) (Notifier, error) {
+	switch typ {
+	case DestinationSlack, DestinationWebhook:
+		if url == "" {
+			return nil, fmt.Errorf("%s destination needs a url", typ)
+		}
+		return &webhook{url: url, client: client, slack: typ == DestinationSlack}, nil
+	case DestinationStdout:
+		return &writer{w: w}, nil
+	}
+	return nil, fmt.Errorf("invalid destination type %q: use slack, webhook or stdout", typ)
+}
+
+// writer prints one alert per line.
+type writer struct {
+	w io.Writer
+}
+
+func (n *writer) Notify(_ context.Context, alerts []Alert) error {
+	for _, a := range alerts {
+		line := fmt.Sprintf("[%s] %s", a.Rule, a.Text)
+		if a.URL != "" {
+			line += " " + a.URL
+		}
+		if _, err := fmt.Fprintln(n.w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// webhook posts all alerts in one request: a Slack message, or
+// {"alerts": [...]} for generic webhooks.
+type webhook struct {
+	url    string
+	client *http.Client
+	slack  bool
+}
+
+func (n *webhook) Notify(ctx context.Context, alerts []Alert) error {
+	var payload any = map[string][]Alert{"alerts": alerts}
+	if n.slack {
+		payload = map[string]string{"text": SlackText(alerts)}
+	}
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(payload); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// SlackText formats alerts as a Slack message.
+func SlackText(alerts []Alert) string {
+	var b strings.Builder
+	b.WriteString(":rotating_light: *gh-flox alerts*")
+	for _, a := range alerts {
+		text := a.Text
+		if rest, ok := strings.CutPrefix(text, a.Repository); ok && a.Repository != "" && a.URL != "" {
+			text = "<" + a.URL + "|" + a.Repository + ">" + rest
+		}
+		fmt.Fprintf(&b, "\n• %s", text)
+	}
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var testAlerts = []Alert{
+	{Key: "adoption/d/big", Rule: "big", Repository: "d/big", Text: "d/big (1200 stars) adopted flox (dotflox)", URL: "https://github.com/d/big"},
+	{Key: "count-drop/dotflox/2024-03-08", Rule: "drop", Text: "dotflox repositories dropped 25.0%"},
+}
+
+func TestNotify_Webhooks(t *testing.T) {
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("bad request: %v", err)
+		}
+		bodies = append(bodies, body)
+	}))
+	defer srv.Close()
+
+	for _, typ := range []string{DestinationSlack, DestinationWebhook} {
+		n, err := NewNotifier(typ, srv.URL, nil, srv.Client())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := n.Notify(context.Background(), testAlerts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	text, _ := bodies[0]["text"].(string)
+	if !strings.Contains(text, "• <https://github.com/d/big|d/big> (1200 stars) adopted flox") || !strings.Contains(text, "• dotflox repositories dropped 25.0%") {
+		t.Errorf("slack text = %q", text)
+	}
+	if list, _ := bodies[1]["alerts"].([]any); len(list) != 2 {
+		t.Errorf("webhook body = %v", bodies[1])
+	}
+}
+
+func TestNotify_WebhookError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	n, _ := NewNotifier(DestinationSlack, srv.URL, nil, srv.Client())
+	if err := n.Notify(context.Background(), testAlerts); err == nil || !strings.Contains(err.Error(), "403 Forbidden: invalid_token") {
+		t.Errorf("expected the status and body in the error, got %v", err)
+	}
+}
+
+func TestNotify_Stdout(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := NewNotifier(DestinationStdout, "", &buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(context.Background(), testAlerts); err != nil {
+		t.Fatal(err)
+	}
+	want := "[big] d/big (1200 stars) adopted flox (dotflox) https://github.com/d/big\n[drop] dotflox repositories dropped 25.0%\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+	if _, err := NewNotifier(DestinationWebhook, "", nil, nil); err == nil {
+		t.Error("expected an error for a webhook without url")
+	}
+}
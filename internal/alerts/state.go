@@ -0,0 +1,64 @@
+package alerts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/stahnma/gh-flox/internal/download"
+)
+
+// State records the alerts already sent.
+type State struct {
+	// Sent maps alert keys to when they were sent.
+	Sent map[string]time.Time `json:"sent"`
+}
+
+// LoadState reads the state at path. A missing file yields an empty state.
+func LoadState(path string) (*State, error) {
+	s := &State{Sent: map[string]time.Time{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if s.Sent == nil {
+		s.Sent = map[string]time.Time{}
+	}
+	return s, nil
+}
+
+// Save writes the state to path atomically.
+func (s *State) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return download.WriteFile(path, append(data, '\n'))
+}
+
+// Unsent returns the alerts not sent before.
+func (s *State) Unsent(alerts []Alert) []Alert {
+	var out []Alert
+	for _, a := range alerts {
+		if _, ok := s.Sent[a.Key]; !ok {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// Record marks alerts as sent at now.
+func (s *State) Record(alerts []Alert, now time.Time) {
+	for _, a := range alerts {
+		s.Sent[a.Key] = now.UTC()
+	}
+}
@@ -3,7 +3,7 @@ package cache
 import (
 	"bytes"
 	"encoding/gob"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"time"
@@ -15,6 +15,9 @@ func init() {
 	gob.Register(0) // register int for gob encoding of cached star counts
 }
 
+// NoExpiration marks an item that never expires.
+const NoExpiration = gocache.NoExpiration
+
 // Cache wraps go-cache with GOB persistence.
 type Cache struct {
 	inner *gocache.Cache
@@ -38,7 +41,7 @@ func LoadFromFile(filename string) (*Cache, error) {
 	dec := gob.NewDecoder(buf)
 	items := map[string]gocache.Item{}
 	if err := dec.Decode(&items); err != nil {
-		log.Printf("Cache decode error (starting fresh): %v", err)
+		slog.Warn("cache decode failed, starting fresh", "file", filename, "err", err)
 		return New(), nil
 	}
 	return &Cache{inner: gocache.NewFrom(4*time.Hour, 6*time.Hour, items)}, nil
@@ -67,6 +70,12 @@ func (c *Cache) Set(key string, val any) {
 	c.inner.Set(key, val, gocache.DefaultExpiration)
 }
 
+// SetWithExpiration stores a value that expires after d, or never if d is
+// NoExpiration.
+func (c *Cache) SetWithExpiration(key string, val any, d time.Duration) {
+	c.inner.Set(key, val, d)
+}
+
 // Flush clears all cached items.
 func (c *Cache) Flush() {
 	c.inner.Flush()
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
@@ -111,3 +112,17 @@ func TestLoadFromFile_CorruptData(t *testing.T) {
 		t.Error("expected empty cache from corrupt file")
 	}
 }
+
+func TestSetWithExpiration(t *testing.T) {
+	c := New()
+	c.SetWithExpiration("forever", "a", NoExpiration)
+	c.SetWithExpiration("gone", "b", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	if _, found := c.Get("forever"); !found {
+		t.Error("expected non-expiring key to be found")
+	}
+	if _, found := c.Get("gone"); found {
+		t.Error("expected expired key to be missing")
+	}
+}
@@ -0,0 +1,195 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	ghub "github.com/stahnma/gh-flox/internal/github"
+	"github.com/stahnma/gh-flox/internal/progress"
+	"github.com/stahnma/gh-flox/internal/score"
+)
+
+func (a *App) newAdoptionDateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "adoption-date [owner/repo...]",
+		Short: "Show when repositories adopted flox, from their commit history",
+		Long: `Show when repositories adopted flox, from their commit history.
+
+A manifest repository adopted flox with the earliest commit touching any of
+its .flox/env/manifest.toml files; a README repository with the commit that introduced
+"flox install" into its README. Without arguments, every repository found by
+the repos and readmes searches is listed, oldest adoption first. Dates never
+change, so they are cached permanently.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.runAdoptionDate(cmd, args)
+		},
+	}
+	cmd.Flags().BoolP("full", "f", false, "Include repositories from excluded organizations")
+	cmd.Flags().Bool("by-month", false, "Print new and cumulative adoptions per month instead of per repository")
+	addFilterFlags(cmd)
+	return cmd
+}
+
+// adoption is a repository and the date it adopted flox, zero if unknown.
+type adoption struct {
+	typedRepo
+	Date time.Time
+}
+
+func (a *App) runAdoptionDate(cmd *cobra.Command, args []string) error {
+	if err := a.ensureClient(); err != nil {
+		return err
+	}
+	ctx, cancel := a.commandContext(cmd)
+	defer cancel()
+	showFull, _ := cmd.Flags().GetBool("full")
+	byMonth, _ := cmd.Flags().GetBool("by-month")
+	w := cmd.OutOrStdout()
+	f, err := filterFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	p := a.progressReporter(cmd)
+	defer p.Done()
+
+	var repos []typedRepo
+	if len(args) > 0 {
+		for _, arg := range args {
+			owner, name, ok := strings.Cut(arg, "/")
+			if !ok || owner == "" || name == "" {
+				return fmt.Errorf("invalid repository %q: expected owner/repo", arg)
+			}
+			repos = append(repos, typedRepo{Type: score.TypeAdditional, Repo: ghub.Repo{Owner: owner, Name: name}})
+		}
+	} else {
+		var d *dataset
+		d, err = a.loadDataset(ctx, showFull, f, p, allRecordTypes...)
+		if err != nil && !isInterrupted(err) {
+			return fmt.Errorf("finding repositories: %w", err)
+		}
+		repos = d.unique()
+	}
+
+	var adoptions []adoption
+	if err == nil {
+		adoptions, err = a.adoptionDates(ctx, repos, p)
+		if err != nil && !isInterrupted(err) {
+			return fmt.Errorf("finding adoption dates: %w", err)
+		}
+	}
+
+	if a.Config.SlackMode {
+		fmt.Fprintln(w, "```")
+	}
+	if byMonth {
+		writeAdoptionsByMonth(w, adoptions)
+	} else {
+		for _, ad := range adoptions {
+			date := "unknown"
+			if !ad.Date.IsZero() {
+				date = ad.Date.UTC().Format(time.DateOnly)
+			}
+			fmt.Fprintf(w, "%s,%s,%s\n", ad.FullName(), ad.Type, date)
+		}
+	}
+	if a.Config.SlackMode {
+		fmt.Fprintln(w, "```")
+	}
+
+	if err != nil {
+		return a.incomplete(w, err)
+	}
+	return nil
+}
+
+// adoptionDates looks up the adoption date of each repository and returns
+// them oldest first, unknown dates last. A repository whose date cannot be
+// looked up, such as a deleted or empty one, has an unknown date. If ctx is
+// canceled, the dates found so far are returned with the context's error.
+func (a *App) adoptionDates(ctx context.Context, repos []typedRepo, p progress.Reporter) ([]adoption, error) {
+	p = progress.OrNop(p)
+	p.Report(progress.Event{Kind: progress.StageStarted, Stage: "finding adoption dates", Total: len(repos)})
+	adoptions := make([]adoption, 0, len(repos))
+	var err error
+	for _, r := range repos {
+		date, lookupErr := a.adoptionDate(ctx, r.Type, r.Owner, r.Name, r.DefaultBranch)
+		if lookupErr != nil {
+			if err = ctx.Err(); err != nil {
+				break
+			}
+			slog.Warn("finding adoption date failed", "repo", r.FullName(), "err", lookupErr)
+		}
+		adoptions = append(adoptions, adoption{typedRepo: r, Date: date})
+		p.Report(progress.Event{Kind: progress.RepoProcessed})
+	}
+	sort.SliceStable(adoptions, func(i, j int) bool {
+		di, dj := adoptions[i].Date, adoptions[j].Date
+		if di.IsZero() != dj.IsZero() {
+			return dj.IsZero()
+		}
+		if !di.Equal(dj) {
+			return di.Before(dj)
+		}
+		return adoptions[i].FullName() < adoptions[j].FullName()
+	})
+	return adoptions, err
+}
+
+// adoptionDate returns when a repository of the given type adopted flox.
+// Additional repositories were added by hand for any reason, so the manifest
+// history is tried first and the README second. Manifests are looked for at
+// ref, the default branch if empty.
+func (a *App) adoptionDate(ctx context.Context, repoType, owner, name, ref string) (time.Time, error) {
+	switch repoType {
+	case score.TypeDotflox:
+		return ghub.GetManifestAdoptionDate(ctx, a.GHClient, a.Cache, owner, name, ref, a.Config.NoCache)
+	case score.TypeReadme:
+		return ghub.GetReadmeAdoptionDate(ctx, a.GHClient, a.Cache, owner, name, a.Config.NoCache)
+	}
+	date, err := ghub.GetManifestAdoptionDate(ctx, a.GHClient, a.Cache, owner, name, ref, a.Config.NoCache)
+	if err != nil || !date.IsZero() {
+		return date, err
+	}
+	return ghub.GetReadmeAdoptionDate(ctx, a.GHClient, a.Cache, owner, name, a.Config.NoCache)
+}
+
+// writeAdoptionsByMonth prints the number of repositories adopting flox in
+// each month and the running total, for charting adoption over time.
+func writeAdoptionsByMonth(w io.Writer, adoptions []adoption) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MONTH\tNEW\tTOTAL")
+	total, unknown := 0, 0
+	var month string
+	var n int
+	flush := func() {
+		if month != "" {
+			fmt.Fprintf(tw, "%s\t%d\t%d\n", month, n, total)
+		}
+	}
+	for _, ad := range adoptions {
+		if ad.Date.IsZero() {
+			unknown++
+			continue
+		}
+		m := ad.Date.UTC().Format("2006-01")
+		if m != month {
+			flush()
+			month, n = m, 0
+		}
+		n++
+		total++
+	}
+	flush()
+	if unknown > 0 {
+		fmt.Fprintf(tw, "unknown\t%d\t%d\n", unknown, total+unknown)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stahnma/gh-flox/internal/alerts"
+	"github.com/stahnma/gh-flox/internal/config"
+	"github.com/stahnma/gh-flox/internal/format"
+	"github.com/stahnma/gh-flox/internal/history"
+)
+
+// alertHTTPClient posts alerts to webhooks.
+var alertHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+func (a *App) newAlertsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "alerts",
+		Short: "Send alerts for notable changes in the history store",
+		Long: `Evaluate the alert rules in the config file's alerts section against the
+latest snapshots of the history store and send the alerts they raise to the
+configured destinations: a Slack incoming webhook, a generic webhook
+receiving {"alerts": [...]}, or standard output, the default.
+
+Rule types:
+  adoption    a repository with more than min_stars stars adopts flox
+  milestone   repo (default flox/flox) crosses a multiple of every, or one of
+              milestones, in stars
+  count-drop  the repository count of series (default dotflox) drops by more
+              than percent
+  removal     a tracked adopter removes .flox: one of repos, or without repos
+              any adopter with at least min_stars stars
+
+Each rule compares the two latest snapshots holding its data: repository
+lists and counts, or the milestone repository's stars. Alerts already sent,
+as recorded in the state file, are not sent again. The state is only updated
+when every destination accepted the alerts. With --refresh, a fresh export
+and the star counts the rules need are first recorded in the store for
+today.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.runAlerts(cmd)
+		},
+	}
+	cmd.Flags().String("store", "", "History store file (default $GH_FLOX_HISTORY or history.json in the config directory)")
+	cmd.Flags().String("state", "", "Alert state file (default the config file's alerts.state or alerts-state.json in the config directory)")
+	cmd.Flags().Bool("refresh", false, "Record a fresh export and star counts in the history store first")
+	cmd.Flags().Bool("dry-run", false, "Print the new alerts without sending them or updating the state")
+	cmd.Flags().Bool("json", false, "Write the new alerts as JSON instead of sending them")
+	return cmd
+}
+
+func (a *App) runAlerts(cmd *cobra.Command) error {
+	refresh, _ := cmd.Flags().GetBool("refresh")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	w := cmd.OutOrStdout()
+
+	rules, err := a.alertRules()
+	if err != nil {
+		return err
+	}
+	notifiers, err := a.alertNotifiers(cmd)
+	if err != nil {
+		return err
+	}
+	statePath, _ := cmd.Flags().GetString("state")
+	statePath = defaultString(statePath, defaultString(a.File.Alerts.State, config.DefaultAlertStateFile()))
+	state, err := alerts.LoadState(statePath)
+	if err != nil {
+		return fmt.Errorf("loading alert state: %w", err)
+	}
+	path := a.historyPath(cmd)
+	store, err := history.Load(path)
+	if err != nil {
+		return fmt.Errorf("loading history: %w", err)
+	}
+
+	ctx, cancel := a.commandContext(cmd)
+	defer cancel()
+	if refresh {
+		p := a.progressReporter(cmd)
+		err := a.RecordHistory(ctx, store, milestoneRepos(rules), time.Now(), p)
+		p.Done()
+		if err != nil {
+			return fmt.Errorf("refreshing history: %w", err)
+		}
+		if err := store.Save(path); err != nil {
+			return fmt.Errorf("saving history: %w", err)
+		}
+	}
+
+	raised := alerts.Evaluate(store, rules)
+	unsent := state.Unsent(raised)
+	if jsonOut {
+		if unsent == nil {
+			unsent = []alerts.Alert{}
+		}
+		return format.WriteJSON(w, unsent, a.Config.SlackMode)
+	}
+	if len(unsent) == 0 {
+		fmt.Fprintf(w, "No new alerts (%d already sent).\n", len(raised))
+		return nil
+	}
+	if dryRun {
+		stdout, _ := alerts.NewNotifier(alerts.DestinationStdout, "", w, nil)
+		return stdout.Notify(ctx, unsent)
+	}
+
+	var errs []error
+	for i, n := range notifiers {
+		if err := n.Notify(ctx, unsent); err != nil {
+			errs = append(errs, fmt.Errorf("destination %d: %w", i+1, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("sending alerts: %w", errors.Join(errs...))
+	}
+	state.Record(unsent, time.Now())
+	if err := state.Save(statePath); err != nil {
+		return fmt.Errorf("saving alert state: %w", err)
+	}
+	return nil
+}
+
+// alertRules returns the validated rules from the config file.
+func (a *App) alertRules() ([]alerts.Rule, error) {
+	if len(a.File.Alerts.Rules) == 0 {
+		return nil, errors.New("no alert rules: add them to the alerts section of the config file")
+	}
+	var rules []alerts.Rule
+	for i, r := range a.File.Alerts.Rules {
+		rule := alerts.Rule{
+			Name:       r.Name,
+			Type:       r.Type,
+			MinStars:   r.MinStars,
+			Repo:       r.Repo,
+			Every:      r.Every,
+			Milestones: r.Milestones,
+			Percent:    r.Percent,
+			Series:     r.Series,
+			Repos:      r.Repos,
+		}
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("config file: alert rule %d: %w", i+1, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// alertNotifiers returns a notifier per configured destination, or one for
+// standard output if there are none.
+func (a *App) alertNotifiers(cmd *cobra.Command) ([]alerts.Notifier, error) {
+	dests := a.File.Alerts.Destinations
+	if len(dests) == 0 {
+		dests = []config.AlertDestination{{Type: alerts.DestinationStdout}}
+	}
+	var notifiers []alerts.Notifier
+	for i, d := range dests {
+		n, err := alerts.NewNotifier(d.Type, d.URL, cmd.OutOrStdout(), alertHTTPClient)
+		if err != nil {
+			return nil, fmt.Errorf("config file: alert destination %d: %w", i+1, err)
+		}
+		notifiers = append(notifiers, n)
+	}
+	return notifiers, nil
+}
+
+// milestoneRepos returns the repositories whose stars the milestone rules
+// watch, or flox/flox if there are none.
+func milestoneRepos(rules []alerts.Rule) []string {
+	var repos []string
+	for _, r := range rules {
+		if r.Type == alerts.TypeMilestone && !slices.Contains(repos, r.Repo) {
+			repos = append(repos, r.Repo)
+		}
+	}
+	if len(repos) == 0 {
+		repos = []string{"flox/flox"}
+	}
+	return repos
+}
@@ -4,27 +4,55 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	gh "github.com/google/go-github/v68/github"
 	"github.com/stahnma/gh-flox/internal/cache"
 	"github.com/stahnma/gh-flox/internal/config"
+	"github.com/stahnma/gh-flox/internal/download"
 	ghub "github.com/stahnma/gh-flox/internal/github"
+	"github.com/stahnma/gh-flox/internal/history"
 )
 
 // mockClient implements ghub.Client for testing commands.
 type mockClient struct {
-	searchCodeFn    func(ctx context.Context, query string, opts *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error)
-	getRepositoryFn func(ctx context.Context, owner, repo string) (*gh.Repository, *gh.Response, error)
-	isOrgMemberFn   func(ctx context.Context, org, user string) (bool, *gh.Response, error)
+	searchCodeFn       func(ctx context.Context, query string, opts *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error)
+	searchReposFn      func(ctx context.Context, query string, opts *gh.SearchOptions) (*gh.RepositoriesSearchResult, *gh.Response, error)
+	getRepositoryFn    func(ctx context.Context, owner, repo string) (*gh.Repository, *gh.Response, error)
+	isOrgMemberFn      func(ctx context.Context, org, user string) (bool, *gh.Response, error)
+	rateLimitsFn       func(ctx context.Context) (*gh.RateLimits, *gh.Response, error)
+	listCommitsFn      func(ctx context.Context, owner, repo string, opts *gh.CommitsListOptions) ([]*gh.RepositoryCommit, *gh.Response, error)
+	listContributorsFn func(ctx context.Context, owner, repo string, opts *gh.ListContributorsOptions) ([]*gh.Contributor, *gh.Response, error)
+	getReadmeFn        func(ctx context.Context, owner, repo string, opts *gh.RepositoryContentGetOptions) (*gh.RepositoryContent, *gh.Response, error)
+	getTreeFn          func(ctx context.Context, owner, repo, sha string, recursive bool) (*gh.Tree, *gh.Response, error)
+	getCommitSHA1Fn    func(ctx context.Context, owner, repo, ref, lastSHA string) (string, *gh.Response, error)
+	getBlobRawFn       func(ctx context.Context, owner, repo, sha string) ([]byte, *gh.Response, error)
+	listStargazersFn   func(ctx context.Context, owner, repo string, opts *gh.ListOptions) ([]*gh.Stargazer, *gh.Response, error)
 }
 
 func (m *mockClient) SearchCode(ctx context.Context, query string, opts *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error) {
 	return m.searchCodeFn(ctx, query, opts)
 }
 
+func (m *mockClient) SearchRepositories(ctx context.Context, query string, opts *gh.SearchOptions) (*gh.RepositoriesSearchResult, *gh.Response, error) {
+	return m.searchReposFn(ctx, query, opts)
+}
+
 func (m *mockClient) GetRepository(ctx context.Context, owner, repo string) (*gh.Repository, *gh.Response, error) {
 	return m.getRepositoryFn(ctx, owner, repo)
 }
@@ -33,6 +61,38 @@ func (m *mockClient) IsOrgMember(ctx context.Context, org, user string) (bool, *
 	return m.isOrgMemberFn(ctx, org, user)
 }
 
+func (m *mockClient) GetRateLimits(ctx context.Context) (*gh.RateLimits, *gh.Response, error) {
+	return m.rateLimitsFn(ctx)
+}
+
+func (m *mockClient) ListCommits(ctx context.Context, owner, repo string, opts *gh.CommitsListOptions) ([]*gh.RepositoryCommit, *gh.Response, error) {
+	return m.listCommitsFn(ctx, owner, repo, opts)
+}
+
+func (m *mockClient) ListContributors(ctx context.Context, owner, repo string, opts *gh.ListContributorsOptions) ([]*gh.Contributor, *gh.Response, error) {
+	return m.listContributorsFn(ctx, owner, repo, opts)
+}
+
+func (m *mockClient) GetReadme(ctx context.Context, owner, repo string, opts *gh.RepositoryContentGetOptions) (*gh.RepositoryContent, *gh.Response, error) {
+	return m.getReadmeFn(ctx, owner, repo, opts)
+}
+
+func (m *mockClient) GetTree(ctx context.Context, owner, repo, sha string, recursive bool) (*gh.Tree, *gh.Response, error) {
+	return m.getTreeFn(ctx, owner, repo, sha, recursive)
+}
+
+func (m *mockClient) GetCommitSHA1(ctx context.Context, owner, repo, ref, lastSHA string) (string, *gh.Response, error) {
+	return m.getCommitSHA1Fn(ctx, owner, repo, ref, lastSHA)
+}
+
+func (m *mockClient) GetBlobRaw(ctx context.Context, owner, repo, sha string) ([]byte, *gh.Response, error) {
+	return m.getBlobRawFn(ctx, owner, repo, sha)
+}
+
+func (m *mockClient) ListStargazers(ctx context.Context, owner, repo string, opts *gh.ListOptions) ([]*gh.Stargazer, *gh.Response, error) {
+	return m.listStargazersFn(ctx, owner, repo, opts)
+}
+
 func emptyResponse() *gh.Response {
 	return &gh.Response{Response: &http.Response{StatusCode: 200}}
 }
@@ -70,12 +130,49 @@ func defaultMockClient() *mockClient {
 				},
 			}, emptyResponse(), nil
 		},
+		searchReposFn: func(_ context.Context, query string, _ *gh.SearchOptions) (*gh.RepositoriesSearchResult, *gh.Response, error) {
+			res := &gh.RepositoriesSearchResult{}
+			for _, term := range strings.Fields(query) {
+				if name, ok := strings.CutPrefix(term, "repo:"); ok {
+					res.Repositories = append(res.Repositories, &gh.Repository{FullName: gh.Ptr(name), StargazersCount: &stars})
+				}
+			}
+			return res, emptyResponse(), nil
+		},
 		getRepositoryFn: func(_ context.Context, _, _ string) (*gh.Repository, *gh.Response, error) {
 			return &gh.Repository{StargazersCount: &stars}, emptyResponse(), nil
 		},
 		isOrgMemberFn: func(_ context.Context, _, _ string) (bool, *gh.Response, error) {
 			return false, emptyResponse(), nil
 		},
+		rateLimitsFn: func(_ context.Context) (*gh.RateLimits, *gh.Response, error) {
+			return &gh.RateLimits{
+				Core:       &gh.Rate{Limit: 5000, Remaining: 5000},
+				Search:     &gh.Rate{Limit: 30, Remaining: 30},
+				CodeSearch: &gh.Rate{Limit: 10, Remaining: 10},
+			}, emptyResponse(), nil
+		},
+		listCommitsFn: func(_ context.Context, _, _ string, _ *gh.CommitsListOptions) ([]*gh.RepositoryCommit, *gh.Response, error) {
+			return nil, emptyResponse(), nil
+		},
+		listContributorsFn: func(_ context.Context, _, _ string, _ *gh.ListContributorsOptions) ([]*gh.Contributor, *gh.Response, error) {
+			return nil, emptyResponse(), nil
+		},
+		getReadmeFn: func(_ context.Context, _, _ string, _ *gh.RepositoryContentGetOptions) (*gh.RepositoryContent, *gh.Response, error) {
+			resp := &gh.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}
+			return nil, resp, &gh.ErrorResponse{Response: resp.Response}
+		},
+		getTreeFn: func(_ context.Context, _, _, _ string, _ bool) (*gh.Tree, *gh.Response, error) {
+			return &gh.Tree{Entries: []*gh.TreeEntry{
+				{Path: gh.Ptr(".flox/env/manifest.toml"), Type: gh.Ptr("blob")},
+			}}, emptyResponse(), nil
+		},
+		getCommitSHA1Fn: func(_ context.Context, owner, repo, _, _ string) (string, *gh.Response, error) {
+			return "commit-" + owner, emptyResponse(), nil
+		},
+		getBlobRawFn: func(_ context.Context, _, _, _ string) ([]byte, *gh.Response, error) {
+			return nil, nil, errors.New("unexpected blob download")
+		},
 	}
 }
 
@@ -188,6 +285,107 @@ func TestStarsCommand_SlackMode(t *testing.T) {
 	}
 }
 
+func TestStarsCommand_Watchlist(t *testing.T) {
+	client := defaultMockClient()
+	client.getRepositoryFn = func(_ context.Context, owner, repo string) (*gh.Repository, *gh.Response, error) {
+		stars := map[string]int{"flox/flox": 3000, "jetify-com/devbox": 9000}[owner+"/"+repo]
+		return &gh.Repository{StargazersCount: gh.Ptr(stars), ForksCount: gh.Ptr(stars / 10), SubscribersCount: gh.Ptr(stars / 100)}, emptyResponse(), nil
+	}
+	app := newTestApp(client)
+	app.Config.HistoryFile = filepath.Join(t.TempDir(), "history.json")
+	app.File.Stars.Watchlist = []string{"flox/flox", "jetify-com/devbox"}
+	store := &history.Store{Version: history.StoreVersion}
+	store.Apply(&history.Import{Date: time.Now().AddDate(0, 0, -40).Format(history.DateLayout), Stars: map[string]int{"flox/flox": 2800}})
+	store.Apply(&history.Import{Date: time.Now().AddDate(0, 0, -8).Format(history.DateLayout), Stars: map[string]int{"flox/flox": 2950, "jetify-com/devbox": 8990}})
+	if err := store.Save(app.Config.HistoryFile); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"stars", "--record"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	want := `REPOSITORY         STARS  FORKS  WATCHERS  7D   30D
+flox/flox          3000   300    30        +50  +200
+jetify-com/devbox  9000   900    90        +10  -
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	saved, err := history.Load(app.Config.HistoryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stars, ok := saved.StarsOn("jetify-com/devbox", time.Now().Format(history.DateLayout)); !ok || stars != 9000 {
+		t.Errorf("expected today's count recorded, got %d, %v", stars, ok)
+	}
+
+	app.Config.SlackMode = true
+	cmd = app.NewRootCommand()
+	buf.Reset()
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"stars", "jetify-com/devbox"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "The repository :star2: `jetify-com/devbox` has 9000 stars :star2:.\n" {
+		t.Errorf("expected the Slack one-liner for one repository, got %q", buf.String())
+	}
+
+	cmd.SetArgs([]string{"stars", "devbox"})
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected an error for a repository without owner")
+	}
+}
+
+// --- Stargazers ---
+
+func TestStargazersCommand(t *testing.T) {
+	client := defaultMockClient()
+	client.listStargazersFn = func(_ context.Context, owner, repo string, _ *gh.ListOptions) ([]*gh.Stargazer, *gh.Response, error) {
+		if owner+"/"+repo != "flox/flox" {
+			t.Errorf("listed stargazers of %s/%s, want flox/flox", owner, repo)
+		}
+		var stars []*gh.Stargazer
+		for _, s := range []struct {
+			login string
+			day   int
+		}{{"carol", 1}, {"Alice", 4}, {"dave", 5}, {"bob", 12}} {
+			stars = append(stars, &gh.Stargazer{
+				User:      &gh.User{Login: gh.Ptr(s.login)},
+				StarredAt: &gh.Timestamp{Time: time.Date(2024, 3, s.day, 9, 0, 0, 0, time.UTC)},
+			})
+		}
+		return stars, emptyResponse(), nil
+	}
+	app := newTestApp(client)
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"stargazers", "--adopters", "--since", "2024-03-04"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	want := `flox/flox: 3 new stars from 2024-03-04 to 2024-03-11, 4 in total
+WEEK        NEW  TOTAL  ADOPTERS
+2024-03-04  2    3      1
+2024-03-11  1    4      1
+2 of 3 new stargazers own a flox-adopting repository: Alice, bob
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	cmd.SetArgs([]string{"stargazers", "--bucket", "year"})
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected an error for an unknown bucket")
+	}
+}
+
 // --- Repos ---
 
 func TestReposCommand(t *testing.T) {
@@ -231,6 +429,36 @@ func TestReposCommand_Verbose(t *testing.T) {
 	}
 }
 
+func TestReposCommand_VerboseEnvironmentCount(t *testing.T) {
+	client := defaultMockClient()
+	client.getTreeFn = func(_ context.Context, owner, _, _ string, _ bool) (*gh.Tree, *gh.Response, error) {
+		if owner == "bob" {
+			return nil, nil, errors.New("tree error")
+		}
+		return &gh.Tree{Entries: []*gh.TreeEntry{
+			{Path: gh.Ptr("backend/.flox/env/manifest.toml"), Type: gh.Ptr("blob")},
+			{Path: gh.Ptr("frontend/.flox/env/manifest.toml"), Type: gh.Ptr("blob")},
+		}}, emptyResponse(), nil
+	}
+	app := newTestApp(client)
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"repos", "-v"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"Total environments: 2", "alice/project1,42,2\n", "bob/project2,42,?\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, out)
+		}
+	}
+}
+
 // --- Readmes ---
 
 func TestReadmesCommand(t *testing.T) {
@@ -294,6 +522,103 @@ func TestFloxIndexCommand(t *testing.T) {
 	}
 }
 
+func TestFloxIndexCommand_InsufficientBudget(t *testing.T) {
+	client := defaultMockClient()
+	client.rateLimitsFn = func(_ context.Context) (*gh.RateLimits, *gh.Response, error) {
+		return &gh.RateLimits{Core: &gh.Rate{Limit: 5000, Remaining: 3}}, emptyResponse(), nil
+	}
+	app := newTestApp(client)
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetErr(&buf)
+	cmd.SetArgs([]string{"floxindex"})
+
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "insufficient API budget") {
+		t.Fatalf("expected budget error, got %v", err)
+	}
+
+	cmd = app.NewRootCommand()
+	cmd.SetOut(&buf)
+	cmd.SetErr(&buf)
+	cmd.SetArgs([]string{"floxindex", "--force"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("expected --force to bypass budget check, got %v", err)
+	}
+}
+
+// --- RateLimit ---
+
+func TestRateLimitCommand(t *testing.T) {
+	client := defaultMockClient()
+	app := newTestApp(client)
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"ratelimit"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"core", "search", "code_search", "5000"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestStatsFlag(t *testing.T) {
+	client := defaultMockClient()
+	app := newTestApp(client)
+
+	cmd := app.NewRootCommand()
+	var out, errOut bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	cmd.SetArgs([]string{"repos", "--stats"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	app.WriteStats(&errOut)
+
+	if !strings.Contains(errOut.String(), "SearchCode") {
+		t.Errorf("expected call summary on stderr, got:\n%s", errOut.String())
+	}
+	if strings.Contains(out.String(), "SearchCode") {
+		t.Error("call summary should not be written to stdout")
+	}
+	if app.Stats.Calls("GetRepository") != 2 {
+		t.Errorf("got %d GetRepository calls, want 2", app.Stats.Calls("GetRepository"))
+	}
+}
+
+func TestStatsFlag_FailedCommand(t *testing.T) {
+	client := defaultMockClient()
+	client.searchCodeFn = func(_ context.Context, _ string, _ *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error) {
+		return nil, nil, errors.New("search failed")
+	}
+	app := newTestApp(client)
+
+	cmd := app.NewRootCommand()
+	var errOut bytes.Buffer
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(&errOut)
+	cmd.SetArgs([]string{"repos", "--stats"})
+	if err := cmd.Execute(); err == nil {
+		t.Fatal("expected the search error")
+	}
+	app.WriteStats(&errOut)
+	if !strings.Contains(errOut.String(), "SearchCode") {
+		t.Errorf("expected a call summary after a failed command, got:\n%s", errOut.String())
+	}
+}
+
 // --- Export ---
 
 func TestExportCommand(t *testing.T) {
@@ -303,16 +628,20 @@ func TestExportCommand(t *testing.T) {
 	cmd := app.NewRootCommand()
 	var buf bytes.Buffer
 	cmd.SetOut(&buf)
-	cmd.SetArgs([]string{"export", "--full"})
+	cmd.SetArgs([]string{"export"})
 
 	if err := cmd.Execute(); err != nil {
 		t.Fatal(err)
 	}
 
-	var result []ghub.RepoInfo
-	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
-		t.Fatalf("output is not valid JSON: %v\nOutput:\n%s", err, buf.String())
+	export, err := ghub.DecodeExport(buf.Bytes())
+	if err != nil {
+		t.Fatalf("output is not valid export JSON: %v\nOutput:\n%s", err, buf.String())
 	}
+	if export.SchemaVersion != ghub.ExportSchemaVersion || export.Scope != ghub.ScopeAll {
+		t.Errorf("schema_version = %d, scope = %q, want %d, %q", export.SchemaVersion, export.Scope, ghub.ExportSchemaVersion, ghub.ScopeAll)
+	}
+	result := export.Repositories
 
 	if len(result) == 0 {
 		t.Fatal("expected non-empty export")
@@ -337,22 +666,1223 @@ func TestExportCommand(t *testing.T) {
 	}
 }
 
-// --- No client error ---
+func TestDatasetViews_Consistent(t *testing.T) {
+	app := newTestApp(defaultMockClient())
+	// alice/project1 is also found by the README search, so only carol/extra
+	// is an additional record.
+	app.AdditionalRepos = []string{"carol/extra", "alice/project1"}
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := app.NewRootCommand()
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		return buf.String()
+	}
 
-func TestReposCommand_NoClient(t *testing.T) {
-	app := &App{
-		Config:          config.Config{NoCache: true},
-		Cache:           cache.New(),
-		MembershipCache: ghub.NewMembershipCache(),
+	export, err := ghub.DecodeExport([]byte(run("export")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exportStars, readmeRecords := 0, 0
+	for _, r := range export.Repositories {
+		exportStars += r.StarCount
+		switch r.Type {
+		case "readme":
+			readmeRecords++
+		case "additional":
+			readmeRecords++
+			if r.Repository != "carol/extra" || r.Classification != ghub.ClassHandAdded {
+				t.Errorf("unexpected additional record %+v", r)
+			}
+		}
+	}
+	if len(export.Repositories) != 5 || readmeRecords != 3 {
+		t.Fatalf("got %d records, %d readme or additional, want 5 and 3", len(export.Repositories), readmeRecords)
+	}
+
+	readmes := run("readmes", "--verbose")
+	want := fmt.Sprintf("found: %d, Total stars: %d", readmeRecords, 3*42)
+	if !strings.Contains(readmes, want) {
+		t.Errorf("readmes output does not contain %q:\n%s", want, readmes)
+	}
+
+	repos := run("repos", "--verbose")
+	want = fmt.Sprintf("found: %d, Total stars: %d,", len(export.Repositories)-readmeRecords, 2*42)
+	if !strings.Contains(repos, want) {
+		t.Errorf("repos output does not contain %q:\n%s", want, repos)
+	}
+
+	floxindex := run("floxindex")
+	want = fmt.Sprintf("Total floxindex (sum of stars): %d\n", exportStars)
+	if floxindex != want {
+		t.Errorf("floxindex = %q, want %q", floxindex, want)
+	}
+}
+
+func TestDatasetViews_OnlyNeededSearches(t *testing.T) {
+	client := defaultMockClient()
+	var queries []string
+	search := client.searchCodeFn
+	client.searchCodeFn = func(ctx context.Context, query string, opts *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error) {
+		queries = append(queries, query)
+		return search(ctx, query, opts)
+	}
+	for _, tc := range []struct {
+		cmd  string
+		want string
+	}{
+		{"readmes", ghub.ReadmeSignature.SearchQuery()},
+		{"repos", ghub.ManifestSignature.SearchQuery()},
+	} {
+		queries = nil
+		app := newTestApp(client)
+		cmd := app.NewRootCommand()
+		cmd.SetOut(io.Discard)
+		cmd.SetArgs([]string{tc.cmd})
+		if err := cmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		if len(queries) != 1 || queries[0] != tc.want {
+			t.Errorf("%s searched %q, want only %q", tc.cmd, queries, tc.want)
+		}
+	}
+}
+
+func TestExportCommand_Classification(t *testing.T) {
+	client := defaultMockClient()
+	client.isOrgMemberFn = func(_ context.Context, _, user string) (bool, *gh.Response, error) {
+		return user == "bob", emptyResponse(), nil
+	}
+
+	run := func(args ...string) *ghub.Export {
+		app := newTestApp(client)
+		cmd := app.NewRootCommand()
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs(append([]string{"export"}, args...))
+		if err := cmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		export, err := ghub.DecodeExport(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return export
+	}
+
+	all := run()
+	classes := map[string]string{}
+	for _, r := range all.Repositories {
+		classes[r.Repository] = r.Classification
+	}
+	if classes["alice/project1"] != ghub.ClassExternal || classes["bob/project2"] != ghub.ClassEmployee {
+		t.Errorf("classifications = %v", classes)
+	}
+
+	scoped := run("--scoped")
+	if scoped.Scope != ghub.ScopeExternal || len(scoped.Repositories) != 2 {
+		t.Fatalf("scoped export = %+v", scoped)
+	}
+	for _, r := range scoped.Repositories {
+		if r.Repository != "alice/project1" {
+			t.Errorf("scoped export includes %s", r.Repository)
+		}
+	}
+
+	filtered := run("--filter", `classification == "external"`)
+	if len(filtered.Repositories) != len(scoped.Repositories) {
+		t.Errorf("classification filter kept %d records, --scoped %d", len(filtered.Repositories), len(scoped.Repositories))
+	}
+}
+
+func TestExportCommand_Metadata(t *testing.T) {
+	client := defaultMockClient()
+	client.getRepositoryFn = func(_ context.Context, _, _ string) (*gh.Repository, *gh.Response, error) {
+		return &gh.Repository{
+			StargazersCount:  gh.Ptr(7),
+			ForksCount:       gh.Ptr(3),
+			SubscribersCount: gh.Ptr(2),
+			Language:         gh.Ptr("Go"),
+			Topics:           []string{"nix", "flox"},
+			License:          &gh.License{SPDXID: gh.Ptr("MIT")},
+			Archived:         gh.Ptr(true),
+			Owner:            &gh.User{Type: gh.Ptr("Organization")},
+			DefaultBranch:    gh.Ptr("trunk"),
+			PushedAt:         &gh.Timestamp{Time: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		}, emptyResponse(), nil
 	}
+	app := newTestApp(client)
 
 	cmd := app.NewRootCommand()
 	var buf bytes.Buffer
 	cmd.SetOut(&buf)
-	cmd.SetArgs([]string{"repos"})
+	cmd.SetArgs([]string{"export"})
 
-	err := cmd.Execute()
-	if err == nil {
-		t.Error("expected error when GITHUB_TOKEN is empty and no client")
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	var raw struct {
+		Repositories []map[string]any `json:"repositories"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	r := raw.Repositories[0]
+	want := map[string]any{
+		"starcount":      float64(7),
+		"forks":          float64(3),
+		"watchers":       float64(2),
+		"language":       "Go",
+		"license":        "MIT",
+		"archived":       true,
+		"owner_type":     "Organization",
+		"default_branch": "trunk",
+		"pushed_at":      "2024-05-01T00:00:00Z",
+	}
+	for k, v := range want {
+		if r[k] != v {
+			t.Errorf("%s = %v, want %v", k, r[k], v)
+		}
+	}
+}
+
+// --- Score ---
+
+func scoreMockClient() *mockClient {
+	client := defaultMockClient()
+	client.getRepositoryFn = func(_ context.Context, owner, _ string) (*gh.Repository, *gh.Response, error) {
+		stars := map[string]int{"alice": 999, "bob": 9}[owner]
+		return &gh.Repository{StargazersCount: gh.Ptr(stars)}, emptyResponse(), nil
+	}
+	client.listCommitsFn = func(_ context.Context, owner, _ string, _ *gh.CommitsListOptions) ([]*gh.RepositoryCommit, *gh.Response, error) {
+		resp := emptyResponse()
+		if owner == "bob" {
+			resp.LastPage = 99
+		}
+		return []*gh.RepositoryCommit{{}}, resp, nil
+	}
+	return client
+}
+
+func TestScoreCommand(t *testing.T) {
+	app := newTestApp(scoreMockClient())
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"score", "--weight", "recency=0"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "Adoption score:") || !strings.Contains(out, "(2 repositories)") {
+		t.Errorf("expected score summary for 2 deduplicated repositories, got:\n%s", out)
+	}
+	if !strings.Contains(out, "recency=0") {
+		t.Errorf("expected weight override in output, got:\n%s", out)
+	}
+	for _, want := range []string{"alice/project1", "bob/project2", "STARS", "ACTIVITY", "SHARE"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestScoreCommand_SkipsFailedRepos(t *testing.T) {
+	client := scoreMockClient()
+	client.listContributorsFn = func(_ context.Context, owner, _ string, _ *gh.ListContributorsOptions) ([]*gh.Contributor, *gh.Response, error) {
+		if owner == "bob" {
+			resp := &gh.Response{Response: &http.Response{StatusCode: http.StatusUnavailableForLegalReasons}}
+			return nil, resp, &gh.ErrorResponse{Response: resp.Response}
+		}
+		return nil, emptyResponse(), nil
+	}
+	app := newTestApp(client)
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"score"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "(2 repositories)") || strings.Contains(out, "INCOMPLETE") {
+		t.Errorf("a repository failing to fetch should be scored without activity, got:\n%s", out)
+	}
+}
+
+func TestScoreCommand_ChecksScoringBudget(t *testing.T) {
+	client := scoreMockClient()
+	checks := 0
+	client.rateLimitsFn = func(_ context.Context) (*gh.RateLimits, *gh.Response, error) {
+		checks++
+		// Enough for the searches, not for scoring what they found.
+		remaining := 5000
+		if checks > 1 {
+			remaining = 1
+		}
+		return &gh.RateLimits{Core: &gh.Rate{Limit: 5000, Remaining: remaining}}, emptyResponse(), nil
+	}
+	app := newTestApp(client)
+
+	cmd := app.NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"score"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "insufficient API budget") {
+		t.Errorf("expected the scoring budget check to fail, got %v", err)
+	}
+	if checks != 2 {
+		t.Errorf("budget checked %d times, want before searching and before scoring", checks)
+	}
+}
+
+func TestScoreCommand_InvalidWeight(t *testing.T) {
+	app := newTestApp(scoreMockClient())
+
+	cmd := app.NewRootCommand()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{"score", "--weight", "popularity=2"})
+
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "unknown score signal") {
+		t.Errorf("expected unknown signal error, got %v", err)
+	}
+}
+
+func TestExportCommand_Score(t *testing.T) {
+	app := newTestApp(scoreMockClient())
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"export", "--score"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	export, err := ghub.DecodeExport(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if export.Score == nil {
+		t.Fatal("expected score in export")
+	}
+	sum := 0.0
+	for _, r := range export.Repositories {
+		sum += r.Score
+	}
+	if diff := sum - export.Score.Total; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("per-repository scores sum to %v, want total %v", sum, export.Score.Total)
+	}
+	if export.Repositories[0].ScoreComponents["stars"] == 0 {
+		t.Errorf("expected star component for %s", export.Repositories[0].Repository)
+	}
+}
+
+// --- Compare ---
+
+func compareMockClient() *mockClient {
+	client := defaultMockClient()
+	results := map[string][]string{
+		".flox/env/manifest.toml in:path":        {"alice/project1", "bob/project2"},
+		`"flox install" in:file filename:README`: {"bob/project2"},
+		"devbox.json in:path":                    {"bob/project2", "carol/web"},
+		"flake.nix in:path":                      {"alice/project1"},
+		`"nix develop" in:file filename:README`:  {"dave/os", "alice/project1"},
+	}
+	client.searchCodeFn = func(_ context.Context, query string, _ *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error) {
+		var found []*gh.CodeResult
+		for _, repo := range results[query] {
+			owner, name, _ := strings.Cut(repo, "/")
+			found = append(found, makeCodeResult(owner, name))
+		}
+		return &gh.CodeSearchResult{CodeResults: found}, emptyResponse(), nil
+	}
+	return client
+}
+
+func TestCompareCommand(t *testing.T) {
+	app := newTestApp(compareMockClient())
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"compare", "-v"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	want := `TOOL        REPOS  STARS  ALSO FLOX
+flox        2      84     -
+devbox      2      84     1
+devenv      0      0      0
+nix flakes  2      84     1
+devbox,bob/project2
+nix flakes,alice/project1
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	app.File.Compare.Tools = []config.CompareTool{{Name: "devbox", Searches: []config.CompareSearch{{Path: "devbox.json", Phrase: "devbox shell"}}}}
+	cmd.SetArgs([]string{"compare"})
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected an error for a search with both a path and a phrase")
+	}
+}
+
+func TestCompareCommand_ColdRunFitsBudget(t *testing.T) {
+	client := compareMockClient()
+	var checked []string
+	client.isOrgMemberFn = func(_ context.Context, _, user string) (bool, *gh.Response, error) {
+		checked = append(checked, user)
+		return false, emptyResponse(), nil
+	}
+	app := newTestApp(client)
+
+	// The mock reports a full core budget of 5000 calls and nothing cached.
+	cmd := app.NewRootCommand()
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"compare"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("a default cold run should pass the budget check: %v", err)
+	}
+	for _, user := range checked {
+		if user == "carol" || user == "dave" {
+			t.Errorf("membership of %s, found only by other tools, should not be checked", user)
+		}
+	}
+}
+
+func TestExportCommand_Compare(t *testing.T) {
+	app := newTestApp(compareMockClient())
+	app.File.Compare.Tools = []config.CompareTool{{Name: "devbox", Searches: []config.CompareSearch{{Path: "devbox.json"}}}}
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"export", "--compare"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	export, err := ghub.DecodeExport(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ghub.ToolAdoption{
+		{Tool: "flox", Repositories: 2, Stars: 84},
+		{Tool: "devbox", Repositories: 2, Stars: 84, Overlap: 1, OverlapRepositories: []string{"bob/project2"}},
+	}
+	if !reflect.DeepEqual(export.Comparison, want) {
+		t.Errorf("comparison = %+v, want %+v", export.Comparison, want)
+	}
+	if len(export.Repositories) != 3 {
+		t.Errorf("expected the flox records unchanged, got %d", len(export.Repositories))
+	}
+}
+
+// --- Adoption dates ---
+
+func adoptionMockClient() *mockClient {
+	client := defaultMockClient()
+	dates := map[string]time.Time{
+		"alice": time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+		"bob":   time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+	}
+	client.listCommitsFn = func(_ context.Context, owner, _ string, opts *gh.CommitsListOptions) ([]*gh.RepositoryCommit, *gh.Response, error) {
+		if opts.Path != ".flox/env/manifest.toml" {
+			return nil, emptyResponse(), nil
+		}
+		commit := &gh.RepositoryCommit{Commit: &gh.Commit{Author: &gh.CommitAuthor{Date: &gh.Timestamp{Time: dates[owner]}}}}
+		return []*gh.RepositoryCommit{commit}, emptyResponse(), nil
+	}
+	return client
+}
+
+func TestAdoptionDateCommand(t *testing.T) {
+	app := newTestApp(adoptionMockClient())
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"adoption-date", "alice/project1", "bob/project2", "carol/none"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "bob/project2,additional,2024-01-05\nalice/project1,additional,2024-03-10\ncarol/none,additional,unknown\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestAdoptionDateCommand_ByMonth(t *testing.T) {
+	app := newTestApp(adoptionMockClient())
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"adoption-date", "--by-month"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"2024-01  1    1", "2024-03  1    2"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestExportCommand_AdoptionDates(t *testing.T) {
+	app := newTestApp(adoptionMockClient())
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"export", "--adoption-dates"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	export, err := ghub.DecodeExport(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range export.Repositories {
+		switch {
+		case r.Type == "dotflox" && r.AdoptedAt == "":
+			t.Errorf("expected adopted_at for %s", r.Repository)
+		case r.Type == "readme" && r.AdoptedAt != "":
+			t.Errorf("expected no adopted_at for readme record %s without a README, got %s", r.Repository, r.AdoptedAt)
+		}
+	}
+}
+
+func TestExportCommand_AdoptionDatesSkipFailures(t *testing.T) {
+	client := adoptionMockClient()
+	listCommits := client.listCommitsFn
+	client.listCommitsFn = func(ctx context.Context, owner, repo string, opts *gh.CommitsListOptions) ([]*gh.RepositoryCommit, *gh.Response, error) {
+		if owner == "bob" {
+			resp := &gh.Response{Response: &http.Response{StatusCode: http.StatusUnavailableForLegalReasons}}
+			return nil, resp, &gh.ErrorResponse{Response: resp.Response}
+		}
+		return listCommits(ctx, owner, repo, opts)
+	}
+	app := newTestApp(client)
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"export", "--adoption-dates"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	export, err := ghub.DecodeExport(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range export.Repositories {
+		if r.Type != "dotflox" {
+			continue
+		}
+		if got := r.AdoptedAt != ""; got != (r.Repository == "alice/project1") {
+			t.Errorf("%s adopted_at = %q; only bob's lookup fails", r.Repository, r.AdoptedAt)
+		}
+	}
+}
+
+// --- Download manifests ---
+
+func TestDownloadManifestsCommand_Unchanged(t *testing.T) {
+	dir := t.TempDir()
+	manifest := []byte("version = 1\n")
+	lock := []byte("{}\n")
+	for _, f := range []struct {
+		path string
+		data []byte
+	}{
+		{"alice/project1/backend/.flox/env/manifest.toml", manifest},
+		{"alice/project1/backend/.flox/env/manifest.lock", lock},
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(f.path))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, f.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := defaultMockClient()
+	client.searchCodeFn = func(_ context.Context, _ string, _ *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error) {
+		return &gh.CodeSearchResult{CodeResults: []*gh.CodeResult{makeCodeResult("alice", "project1")}}, emptyResponse(), nil
+	}
+	client.getTreeFn = func(_ context.Context, _, _, _ string, _ bool) (*gh.Tree, *gh.Response, error) {
+		return &gh.Tree{Entries: []*gh.TreeEntry{
+			{Path: gh.Ptr("backend/.flox/env/manifest.toml"), Type: gh.Ptr("blob"), SHA: gh.Ptr(ghub.BlobSHA(manifest))},
+			{Path: gh.Ptr("backend/.flox/env/manifest.lock"), Type: gh.Ptr("blob"), SHA: gh.Ptr(ghub.BlobSHA(lock))},
+			{Path: gh.Ptr("backend/.flox/env.json"), Type: gh.Ptr("blob"), SHA: gh.Ptr("changed")},
+		}}, emptyResponse(), nil
+	}
+	app := newTestApp(client)
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"download-manifests", "-o", dir, "--files", "manifest.toml,manifest.lock"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "STATUS      FILES\n" +
+		"downloaded  0\n" +
+		"unchanged   2\n" +
+		"missing     0\n" +
+		"failed      0\n"
+	if out := buf.String(); out != want {
+		t.Errorf("expected both files unchanged and env.json not selected, got:\n%s", out)
+	}
+}
+
+func TestDownloadManifestsCommand_PinnedBlobs(t *testing.T) {
+	dir := t.TempDir()
+	blobs := map[string][]byte{
+		ghub.BlobSHA([]byte("version = 1\n")): []byte("version = 1\n"),
+		ghub.BlobSHA([]byte("{}\n")):          []byte("{}\n"),
+	}
+	client := defaultMockClient()
+	client.searchCodeFn = func(_ context.Context, _ string, _ *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error) {
+		return &gh.CodeSearchResult{CodeResults: []*gh.CodeResult{makeCodeResult("alice", "project1")}}, emptyResponse(), nil
+	}
+	client.getCommitSHA1Fn = func(_ context.Context, _, _, ref, _ string) (string, *gh.Response, error) {
+		if ref != "HEAD" {
+			t.Errorf("ref = %q, want HEAD for a repository without a known default branch", ref)
+		}
+		return "c0ffee", emptyResponse(), nil
+	}
+	client.getTreeFn = func(_ context.Context, _, _, sha string, _ bool) (*gh.Tree, *gh.Response, error) {
+		if sha != "c0ffee" {
+			t.Errorf("tree sha = %q, want the resolved commit", sha)
+		}
+		return &gh.Tree{Entries: []*gh.TreeEntry{
+			{Path: gh.Ptr(".flox/env/manifest.toml"), Type: gh.Ptr("blob"), SHA: gh.Ptr(ghub.BlobSHA([]byte("version = 1\n")))},
+			{Path: gh.Ptr(".flox/env/manifest.lock"), Type: gh.Ptr("blob"), SHA: gh.Ptr(ghub.BlobSHA([]byte("{}\n")))},
+		}}, emptyResponse(), nil
+	}
+	blobCalls := 0
+	client.getBlobRawFn = func(_ context.Context, _, _, sha string) ([]byte, *gh.Response, error) {
+		blobCalls++
+		return blobs[sha], emptyResponse(), nil
+	}
+
+	for run := 0; run < 2; run++ {
+		app := newTestApp(client)
+		cmd := app.NewRootCommand()
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"download-manifests", "-o", dir, "--files", "manifest.toml,manifest.lock", "--json"})
+		if err := cmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		var report download.Report
+		if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+			t.Fatalf("invalid JSON report: %v\n%s", err, buf.String())
+		}
+		if run == 1 && report.Summary != (download.Summary{Unchanged: 2}) {
+			t.Errorf("expected rerun to skip unchanged blobs, got %+v", report.Summary)
+		}
+	}
+	if blobCalls != 2 {
+		t.Errorf("blob downloads = %d, want 2", blobCalls)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "alice", "project1", ".flox", "env", "manifest.toml"))
+	if err != nil || string(data) != "version = 1\n" {
+		t.Errorf("manifest = %q, %v", data, err)
+	}
+	index, err := download.LoadIndex(filepath.Join(dir, download.IndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := index.Lookup("alice", "project1", ".flox/env/manifest.lock")
+	if !ok || e.CommitSHA != "c0ffee" || e.BlobSHA != ghub.BlobSHA([]byte("{}\n")) {
+		t.Errorf("index entry = %+v, %v", e, ok)
+	}
+}
+
+func TestDownloadManifestsCommand_RetriesAndFailures(t *testing.T) {
+	downloadRetryDelay = 0
+	manifest := []byte("version = 1\n")
+	client := defaultMockClient()
+	client.getTreeFn = func(_ context.Context, owner, _, _ string, _ bool) (*gh.Tree, *gh.Response, error) {
+		if owner == "bob" {
+			return &gh.Tree{}, emptyResponse(), nil
+		}
+		return &gh.Tree{Entries: []*gh.TreeEntry{
+			{Path: gh.Ptr(".flox/env/manifest.toml"), Type: gh.Ptr("blob"), SHA: gh.Ptr(ghub.BlobSHA(manifest))},
+			{Path: gh.Ptr(".flox/env/manifest.lock"), Type: gh.Ptr("blob"), SHA: gh.Ptr("lock")},
+		}}, emptyResponse(), nil
+	}
+	var mu sync.Mutex
+	calls := map[string]int{}
+	client.getBlobRawFn = func(_ context.Context, _, _, sha string) ([]byte, *gh.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[sha]++
+		if sha == "lock" {
+			resp := &http.Response{StatusCode: http.StatusNotFound, Request: &http.Request{Method: "GET", URL: &url.URL{}}}
+			return nil, &gh.Response{Response: resp}, &gh.ErrorResponse{Response: resp, Message: "Not Found"}
+		}
+		if calls[sha] < 3 {
+			resp := &http.Response{StatusCode: http.StatusBadGateway, Request: &http.Request{Method: "GET", URL: &url.URL{}}}
+			return nil, &gh.Response{Response: resp}, &gh.ErrorResponse{Response: resp, Message: "Bad Gateway"}
+		}
+		return manifest, emptyResponse(), nil
+	}
+	app := newTestApp(client)
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"download-manifests", "-o", t.TempDir(), "--files", "manifest.toml,manifest.lock", "--concurrency", "2"})
+
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 files failed") {
+		t.Errorf("expected failure exit, got %v", err)
+	}
+	if calls[ghub.BlobSHA(manifest)] != 3 {
+		t.Errorf("manifest blob calls = %d, want 3 after two retried 502s", calls[ghub.BlobSHA(manifest)])
+	}
+	if calls["lock"] != 1 {
+		t.Errorf("lock blob calls = %d, want 1 since 404 is not retried", calls["lock"])
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"downloaded  1\n",
+		"missing     1\n",
+		"failed      1\n",
+		"alice/project1  .flox/env/manifest.lock  failed",
+		"bob/project2    -                        missing  no .flox/env/manifest.toml at commit commit-bob",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestDownloadManifestsCommand_InvalidFiles(t *testing.T) {
+	app := newTestApp(defaultMockClient())
+
+	cmd := app.NewRootCommand()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{"download-manifests", "-o", t.TempDir(), "--files", "flake.nix"})
+
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "invalid --files") {
+		t.Errorf("expected invalid --files error, got %v", err)
+	}
+}
+
+// --- Filters ---
+
+func filterMockClient() *mockClient {
+	client := defaultMockClient()
+	client.getRepositoryFn = func(_ context.Context, owner, _ string) (*gh.Repository, *gh.Response, error) {
+		return &gh.Repository{
+			StargazersCount: gh.Ptr(10),
+			Fork:            gh.Ptr(owner == "bob"),
+		}, emptyResponse(), nil
+	}
+	return client
+}
+
+func TestReposCommand_ExcludeForks(t *testing.T) {
+	app := newTestApp(filterMockClient())
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"repos", "--verbose", "--exclude-forks"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "Total unique repositories found: 1") || strings.Contains(out, "bob/project2") {
+		t.Errorf("expected fork to be excluded, got:\n%s", out)
+	}
+}
+
+func TestFilters_ConsistentAcrossCommands(t *testing.T) {
+	args := []string{"--filter", `owner != "bob"`}
+
+	run := func(name string) string {
+		app := newTestApp(filterMockClient())
+		cmd := app.NewRootCommand()
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs(append([]string{name}, args...))
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return buf.String()
+	}
+
+	if out := run("readmes"); !strings.Contains(out, "found: 1") {
+		t.Errorf("readmes: expected 1 repo, got:\n%s", out)
+	}
+	// alice/project1 counted once as a manifest repo and once as a readme repo.
+	if out := run("floxindex"); !strings.Contains(out, "sum of stars): 20") {
+		t.Errorf("floxindex: expected 20 stars, got:\n%s", out)
+	}
+
+	export, err := ghub.DecodeExport([]byte(run("export")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(export.Repositories) != 2 {
+		t.Errorf("export: expected 2 records, got %d", len(export.Repositories))
+	}
+	if export.Filter != `(owner != "bob")` {
+		t.Errorf("export: filter = %q", export.Filter)
+	}
+}
+
+func TestFilters_InvalidFlag(t *testing.T) {
+	for _, args := range [][]string{
+		{"repos", "--filter", "stars >"},
+		{"repos", "--active-within", "later"},
+		{"repos", "--owner-type", "team"},
+	} {
+		app := newTestApp(defaultMockClient())
+		cmd := app.NewRootCommand()
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+// --- Logging ---
+
+func TestLogFlags_JSONToStderr(t *testing.T) {
+	client := defaultMockClient()
+	app := newTestApp(client)
+
+	cmd := app.NewRootCommand()
+	var out, errOut bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	cmd.SetArgs([]string{"repos", "--log-level", "debug", "--log-format", "json"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(errOut.String(), `"msg":"search page fetched"`) || !strings.Contains(errOut.String(), `"query":`) {
+		t.Errorf("expected structured debug logs on stderr, got:\n%s", errOut.String())
+	}
+	if strings.Contains(out.String(), "search page fetched") {
+		t.Errorf("logs leaked into stdout:\n%s", out.String())
+	}
+}
+
+func TestLogFlags_Invalid(t *testing.T) {
+	app := newTestApp(defaultMockClient())
+	cmd := app.NewRootCommand()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"version", "--log-level", "loud"})
+
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected error for invalid log level")
+	}
+}
+
+// --- Cancellation ---
+
+func TestReposCommand_Timeout(t *testing.T) {
+	client := defaultMockClient()
+	client.searchCodeFn = func(ctx context.Context, _ string, _ *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error) {
+		<-ctx.Done()
+		return nil, nil, ctx.Err()
+	}
+	app := newTestApp(client)
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"repos", "--timeout", "10ms"})
+
+	err := cmd.Execute()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "INCOMPLETE: run timed out") {
+		t.Errorf("expected incomplete marker, got:\n%s", buf.String())
+	}
+}
+
+func TestReposCommand_CanceledPartialResults(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := defaultMockClient()
+	page := 0
+	client.searchCodeFn = func(ctx context.Context, _ string, _ *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error) {
+		page++
+		if page > 1 {
+			cancel()
+			return nil, nil, ctx.Err()
+		}
+		resp := emptyResponse()
+		resp.NextPage = 2
+		return &gh.CodeSearchResult{CodeResults: []*gh.CodeResult{makeCodeResult("alice", "project1")}}, resp, nil
+	}
+	app := newTestApp(client)
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"repos", "--verbose"})
+
+	err := cmd.ExecuteContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "alice/project1") {
+		t.Errorf("expected partial results, got:\n%s", out)
+	}
+	if !strings.Contains(out, "INCOMPLETE: run interrupted") {
+		t.Errorf("expected incomplete marker, got:\n%s", out)
+	}
+}
+
+// --- No client error ---
+
+func TestReposCommand_NoClient(t *testing.T) {
+	app := &App{
+		Config:          config.Config{NoCache: true},
+		Cache:           cache.New(),
+		MembershipCache: ghub.NewMembershipCache(),
+	}
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"repos"})
+
+	err := cmd.Execute()
+	if err == nil {
+		t.Error("expected error when GITHUB_TOKEN is empty and no client")
+	}
+}
+
+// --- Diff ---
+
+func TestDiffCommand(t *testing.T) {
+	dir := t.TempDir()
+	from := filepath.Join(dir, "from.json")
+	to := filepath.Join(dir, "to.json")
+	if err := os.WriteFile(from, []byte(`[{"date":"2025-Mar-01","repository":"alice/a","type":"dotflox","starcount":10},
+		{"date":"2025-Mar-01","repository":"bob/b","type":"readme","starcount":3}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(to, []byte(`{"schema_version":2,"date":"2025-Mar-02","repositories":[
+		{"repository":"alice/a","type":"dotflox","starcount":12},
+		{"repository":"carol/c","type":"dotflox","starcount":1}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	app := newTestApp(defaultMockClient())
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"diff", from, to})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"Stars: 13 -> 13 (+0)\n",
+		"Added: 1, removed: 1, changed: 1\n",
+		"added    carol/c  dotflox  0       1      +1",
+		"removed  bob/b    readme   3       0      -3",
+		"changed  alice/a  dotflox  10      12     +2",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestDiffCommand_FreshExportFollowsScope(t *testing.T) {
+	from := filepath.Join(t.TempDir(), "from.json")
+	if err := os.WriteFile(from, []byte(`{"schema_version":2,"date":"2025-Mar-01","repositories":[
+		{"repository":"alice/project1","type":"dotflox","starcount":42}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mc := defaultMockClient()
+	mc.searchCodeFn = func(_ context.Context, _ string, _ *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error) {
+		return &gh.CodeSearchResult{CodeResults: []*gh.CodeResult{
+			makeCodeResult("alice", "project1"),
+			makeCodeResult("flox", "flox"),
+		}}, emptyResponse(), nil
+	}
+	app := newTestApp(mc)
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"diff", from})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); strings.Contains(out, "flox/flox") {
+		t.Errorf("a version 2 export should be compared with a scoped fresh export, got:\n%s", out)
+	}
+}
+
+// --- History ---
+
+// fakeHistoryS3 serves objects from a map for s3:// imports.
+type fakeHistoryS3 map[string]string
+
+func (f fakeHistoryS3) ListObjectsV2(_ context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	out := &s3.ListObjectsV2Output{}
+	for key := range f {
+		if strings.HasPrefix(key, aws.ToString(in.Prefix)) {
+			out.Contents = append(out.Contents, s3types.Object{Key: aws.String(key)})
+		}
+	}
+	return out, nil
+}
+
+func (f fakeHistoryS3) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(f[aws.ToString(in.Key)]))}, nil
+}
+
+func TestHistoryImportCommand(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"2024-Mar-01":     "Total unique repositories found: 2\nalice/app\nbob/lib\n",
+		"2024-03-02.txt":  "Total unique repositories found: 1, Total stars: 7, Total environments: 1\nalice/app,7,1\n",
+		"bad-2024-03-03":  "Total unique repositories found: 1\nnot a repo line\n",
+		".hidden-ignored": "junk",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	orig := newHistoryS3Client
+	t.Cleanup(func() { newHistoryS3Client = orig })
+	newHistoryS3Client = func(context.Context) (history.S3Client, error) {
+		return fakeHistoryS3{
+			"exports/2024/03/04/export.json": `{"schema_version":3,"date":"2024-Mar-04","scope":"all","repositories":[{"repository":"alice/app","type":"dotflox","starcount":9}]}`,
+			"exports/2024/03/04/diff.json":   `{"from_date":"2024-Mar-03"}`,
+			"other/2024/03/04/export.json":   `[]`,
+		}, nil
+	}
+
+	app := newTestApp(defaultMockClient())
+	app.Config.HistoryFile = filepath.Join(t.TempDir(), "history.json")
+	run := func(args ...string) (string, error) {
+		cmd := app.NewRootCommand()
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return buf.String(), err
+	}
+
+	out, err := run("history", "import", dir, "s3://bucket/exports/")
+	if err == nil || !strings.Contains(err.Error(), "1 of 5 sources failed") {
+		t.Fatalf("expected one failure, got %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "5 sources: 3 added, 0 updated, 0 unchanged, 1 skipped, 1 failed") {
+		t.Errorf("unexpected summary:\n%s", out)
+	}
+	if !strings.Contains(out, "line 2: unrecognized line") {
+		t.Errorf("expected the failure reason, got:\n%s", out)
+	}
+
+	out, _ = run("history", "import", dir, "s3://bucket/exports/")
+	if !strings.Contains(out, "3 unchanged") {
+		t.Errorf("re-import should change nothing, got:\n%s", out)
+	}
+
+	out, err = run("history", "show")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"2024-03-01  2", "2024-03-02  1", "2024-03-04  1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("show output missing %q:\n%s", want, out)
+		}
+	}
+	out, _ = run("history", "show", "alice/app")
+	if !strings.Contains(out, "First seen: 2024-03-01") || !strings.Contains(out, "2024-03-04  9") {
+		t.Errorf("unexpected repo history:\n%s", out)
+	}
+}
+
+func TestTrendCommand(t *testing.T) {
+	dir := t.TempDir()
+	store := &history.Store{Version: history.StoreVersion}
+	for name, text := range map[string]string{
+		"2024-03-01": "Total unique repositories found: 10\nThe repository flox/flox has 100 stars\n",
+		"2024-03-08": "Total unique repositories found: 14\nThe repository flox/flox has 130 stars\n",
+	} {
+		imp, err := history.Parse(name, []byte(text), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.Apply(imp)
+	}
+	app := newTestApp(defaultMockClient())
+	app.Config.HistoryFile = filepath.Join(dir, "history.json")
+	if err := store.Save(app.Config.HistoryFile); err != nil {
+		t.Fatal(err)
+	}
+	app.File.Trend.Annotations = []config.TrendAnnotation{{Date: "2024-03-08", Note: "launch week"}}
+
+	svgPath := filepath.Join(dir, "trend.svg")
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"trend", "--bucket", "week", "--series", "dotflox,flox/flox", "--svg", svgPath})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"2 week buckets", "dotflox    ▁█  10 -> 14 (+4)", "flox/flox  ▁█  100 -> 130 (+30)", "^ 2024-03-08  launch week"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	svg, err := os.ReadFile(svgPath)
+	if err != nil || !strings.Contains(string(svg), "<svg") || !strings.Contains(string(svg), "launch week") {
+		t.Errorf("unexpected SVG file: %v\n%s", err, svg)
+	}
+}
+
+func TestDigestCommand_Refresh(t *testing.T) {
+	dir := t.TempDir()
+	prevDate := time.Now().AddDate(0, 0, -7).Format(history.DateLayout)
+	store := &history.Store{Version: history.StoreVersion}
+	for _, text := range []string{
+		`{"schema_version":3,"scope":"all","repositories":[
+			{"repository":"alice/project1","type":"dotflox","starcount":40,"classification":"external"},
+			{"repository":"carol/gone","type":"dotflox","starcount":5,"classification":"external"}]}`,
+		"The repository flox/flox has 30 stars\n",
+	} {
+		imp, err := history.Parse("previous", []byte(text), prevDate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.Apply(imp)
+	}
+	app := newTestApp(defaultMockClient())
+	app.Config.HistoryFile = filepath.Join(dir, "history.json")
+	if err := store.Save(app.Config.HistoryFile); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := app.NewRootCommand()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"digest", "--refresh", "--format", "slack"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"*flox/flox stars:* 42 (+12)",
+		"*New adopters (1)*\n• <https://github.com/bob/project2|bob/project2> (dotflox, 42 stars)",
+		"*Lost adopters (1)*\n• <https://github.com/carol/gone|carol/gone>",
+		"<https://github.com/alice/project1|alice/project1> +2, now 42",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	saved, err := history.Load(app.Config.HistoryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Snapshots) != 2 || saved.Snapshots[1].FloxIndex == nil || *saved.Snapshots[1].FloxIndex != 168 {
+		t.Errorf("expected today's snapshot with floxindex 168 saved, got %+v", saved.Snapshots)
+	}
+}
+
+func TestAlertsCommand(t *testing.T) {
+	dir := t.TempDir()
+	store := &history.Store{Version: history.StoreVersion}
+	for name, text := range map[string]string{
+		"2024-03-01": "The repository flox/flox has 990 stars\n",
+		"2024-03-08": "The repository flox/flox has 1010 stars\n",
+	} {
+		imp, err := history.Parse(name, []byte(text), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.Apply(imp)
+	}
+	var posts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		posts = append(posts, string(body))
+	}))
+	defer srv.Close()
+
+	app := newTestApp(defaultMockClient())
+	app.Config.HistoryFile = filepath.Join(dir, "history.json")
+	if err := store.Save(app.Config.HistoryFile); err != nil {
+		t.Fatal(err)
+	}
+	app.File.Alerts = config.AlertsConfig{
+		State:        filepath.Join(dir, "state.json"),
+		Rules:        []config.AlertRule{{Name: "stars", Type: "milestone", Every: 1000}},
+		Destinations: []config.AlertDestination{{Type: "slack", URL: srv.URL}},
+	}
+
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := app.NewRootCommand()
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs(append([]string{"alerts"}, args...))
+		if err := cmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	if out := run("--dry-run"); out != "[stars] flox/flox reached 1000 stars (now 1010) https://github.com/flox/flox\n" || len(posts) != 0 {
+		t.Errorf("dry run printed %q and posted %d times", out, len(posts))
+	}
+	run()
+	if len(posts) != 1 || !strings.Contains(posts[0], "<https://github.com/flox/flox|flox/flox> reached 1000 stars") {
+		t.Errorf("posts = %q", posts)
+	}
+	if out := run(); !strings.Contains(out, "No new alerts (1 already sent)") || len(posts) != 1 {
+		t.Errorf("expected no duplicate alert, got %q and %d posts", out, len(posts))
 	}
 }
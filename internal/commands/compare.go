@@ -0,0 +1,154 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stahnma/gh-flox/internal/compare"
+	"github.com/stahnma/gh-flox/internal/filter"
+	"github.com/stahnma/gh-flox/internal/format"
+	ghub "github.com/stahnma/gh-flox/internal/github"
+	"github.com/stahnma/gh-flox/internal/progress"
+	"github.com/stahnma/gh-flox/internal/score"
+)
+
+func (a *App) newCompareCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "compare [flags]",
+		Short: "Compare flox adoption with other environment managers",
+		Long: `Compare flox adoption with other environment managers: how many
+repositories code search finds for each tool, their stars and how many of
+them also use flox.
+
+Flox repositories are those found by repos and readmes. By default flox is
+compared with devbox (devbox.json), devenv (devenv.nix) and nix flakes (any
+flake.nix, or a README mentioning nix develop); the compare section of the
+config file replaces these. Other tools' repositories are scoped by owner
+alone, so flox members' own repositories count for them. Code search returns
+at most 1000 results per search, so large counts are lower bounds.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.runCompare(cmd)
+		},
+	}
+	cmd.Flags().BoolP("full", "f", false, "Include repositories from excluded organizations")
+	cmd.Flags().BoolP("verbose", "v", false, "List the repositories using flox and another tool")
+	cmd.Flags().Bool("force", false, "Run even if the API rate limit budget looks insufficient")
+	cmd.Flags().Bool("json", false, "Output as JSON")
+	addFilterFlags(cmd)
+	return cmd
+}
+
+func (a *App) runCompare(cmd *cobra.Command) error {
+	showFull, _ := cmd.Flags().GetBool("full")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	force, _ := cmd.Flags().GetBool("force")
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	w := cmd.OutOrStdout()
+	f, err := filterFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	tools, err := a.compareTools()
+	if err != nil {
+		return err
+	}
+	if err := a.ensureClient(); err != nil {
+		return err
+	}
+	ctx, cancel := a.commandContext(cmd)
+	defer cancel()
+
+	budget := ghub.EstimateSearchBudget(a.Cache, a.MembershipCache, ghub.SearchOptions{ShowFull: showFull, NoCache: a.Config.NoCache}, ghub.ManifestSignature, ghub.ReadmeSignature)
+	for _, t := range tools {
+		b := ghub.EstimateSearchBudget(a.Cache, a.MembershipCache, compareSearchOptions(showFull, a.Config.NoCache, nil), t.Signatures...)
+		budget.Core += b.Core
+		budget.Search += b.Search
+		budget.CodeSearch += b.CodeSearch
+	}
+	if err := a.checkBudget(ctx, cmd, budget, force); err != nil {
+		return err
+	}
+
+	p := a.progressReporter(cmd)
+	defer p.Done()
+	d, err := a.loadDataset(ctx, showFull, f, p, score.TypeDotflox, score.TypeReadme)
+	if err != nil && !isInterrupted(err) {
+		return fmt.Errorf("finding repositories: %w", err)
+	}
+	rows := compare.Build(d.ofType(score.TypeDotflox, score.TypeReadme), nil)
+	if err == nil {
+		rows, err = a.compareAdoption(ctx, d, tools, showFull, f, p)
+		if err != nil && !isInterrupted(err) {
+			return fmt.Errorf("comparing tools: %w", err)
+		}
+	}
+
+	if jsonOut {
+		if writeErr := format.WriteJSON(w, rows, a.Config.SlackMode); writeErr != nil {
+			return writeErr
+		}
+	} else {
+		compare.WriteText(w, rows, verbose, a.Config.SlackMode)
+	}
+	if err != nil {
+		return a.incomplete(w, err)
+	}
+	return nil
+}
+
+// compareTools returns the tools configured in the config file, or the
+// default ones.
+func (a *App) compareTools() ([]compare.Tool, error) {
+	if len(a.File.Compare.Tools) == 0 {
+		return compare.DefaultTools(), nil
+	}
+	var tools []compare.Tool
+	for i, ct := range a.File.Compare.Tools {
+		t := compare.Tool{Name: ct.Name}
+		for _, s := range ct.Searches {
+			t.Signatures = append(t.Signatures, ghub.Signature{Name: ct.Name + " search", Query: s.Query, Path: s.Path, Phrase: s.Phrase})
+		}
+		if err := t.Validate(); err != nil {
+			return nil, fmt.Errorf("config file: compare tool %d: %w", i+1, err)
+		}
+		tools = append(tools, t)
+	}
+	return tools, nil
+}
+
+// compareSearchOptions returns the options of the other tools' searches.
+// Their repositories are classified by owner alone and their stars looked up
+// in batches, so a comparison fits the core rate limit.
+func compareSearchOptions(showFull, noCache bool, p progress.Reporter) ghub.SearchOptions {
+	return ghub.SearchOptions{
+		ShowFull:           showFull,
+		NoCache:            noCache,
+		SkipClassification: true,
+		Progress:           p,
+	}
+}
+
+// compareAdoption runs the searches of tools and compares what they find
+// with the manifest and README repositories of d; hand-added repositories
+// are left out, as no other tool has them. Repositories are filtered as in d
+// and scoped by owner alone. If ctx is canceled, the rows of the tools searched so
+// far are returned with the context's error.
+func (a *App) compareAdoption(ctx context.Context, d *dataset, tools []compare.Tool, showFull bool, f filter.Filter, p progress.Reporter) ([]ghub.ToolAdoption, error) {
+	now := time.Now()
+	opts := compareSearchOptions(showFull, a.Config.NoCache, p)
+	var found []compare.Found
+	for _, t := range tools {
+		tf := compare.Found{Tool: t.Name}
+		for _, sig := range t.Signatures {
+			repos, err := ghub.FindRepos(ctx, a.GHClient, a.Cache, a.MembershipCache, sig, opts)
+			if err != nil {
+				return compare.Build(d.ofType(score.TypeDotflox, score.TypeReadme), found), err
+			}
+			tf.Repos = append(tf.Repos, f.Apply(repos, now)...)
+		}
+		found = append(found, tf)
+	}
+	return compare.Build(d.ofType(score.TypeDotflox, score.TypeReadme), found), nil
+}
@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+)
+
+// commandContext returns the command's context, bounded by --timeout if set.
+func (a *App) commandContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if a.timeout > 0 {
+		return context.WithTimeout(ctx, a.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// isInterrupted reports whether err was caused by cancellation or a timeout.
+func isInterrupted(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// incomplete marks output written so far as partial, saves whatever cache
+// progress exists and returns an error describing why the run stopped.
+func (a *App) incomplete(w io.Writer, err error) error {
+	reason := "interrupted"
+	if errors.Is(err, context.DeadlineExceeded) {
+		reason = "timed out"
+	}
+	fmt.Fprintf(w, "INCOMPLETE: run %s, results above are partial.\n", reason)
+	if saveErr := a.SaveCache(); saveErr != nil {
+		slog.Error("saving cache failed", "err", saveErr)
+	}
+	return fmt.Errorf("incomplete results: %w", err)
+}
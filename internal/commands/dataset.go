@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/stahnma/gh-flox/internal/filter"
+	ghub "github.com/stahnma/gh-flox/internal/github"
+	"github.com/stahnma/gh-flox/internal/progress"
+	"github.com/stahnma/gh-flox/internal/score"
+)
+
+// typedRepo is a repository together with how it was found.
+type typedRepo struct {
+	Type string
+	ghub.Repo
+}
+
+// dataset is the canonical set of flox-related repositories, built once from
+// every source: the manifest search (type dotflox), the README search (type
+// readme) and additional_repos.json (type additional). Commands are views
+// over it, loading only the types they show, so their counts agree.
+//
+// A repository appears at most once per type. It may be both a dotflox and a
+// readme record; hand-added repositories stand in for README mentions, so
+// those the README search already found are not repeated as additional.
+type dataset struct {
+	Records []typedRepo
+}
+
+// allRecordTypes is every record type of the dataset.
+var allRecordTypes = []string{score.TypeDotflox, score.TypeReadme, score.TypeAdditional}
+
+// loadDataset builds the records of types, keeping only repositories passing
+// f. Only the searches those types need are run; additional records need the
+// README search to leave out repositories it already found. If ctx is
+// canceled, the records found so far are returned with the context's error.
+func (a *App) loadDataset(ctx context.Context, showFull bool, f filter.Filter, p progress.Reporter, types ...string) (*dataset, error) {
+	now := time.Now()
+	d := &dataset{}
+	wants := func(repoType string) bool {
+		return slices.Contains(types, repoType)
+	}
+	add := func(repoType string, rs []ghub.Repo) {
+		if !wants(repoType) {
+			return
+		}
+		for _, r := range f.Apply(rs, now) {
+			d.Records = append(d.Records, typedRepo{Type: repoType, Repo: r})
+		}
+	}
+
+	opts := ghub.SearchOptions{
+		ShowFull: showFull,
+		NoCache:  a.Config.NoCache,
+		Progress: p,
+	}
+	if wants(score.TypeDotflox) {
+		manifestRepos, err := ghub.FindManifestRepos(ctx, a.GHClient, a.Cache, a.MembershipCache, opts)
+		add(score.TypeDotflox, manifestRepos)
+		if err != nil {
+			return d, err
+		}
+	}
+	if !wants(score.TypeReadme) && !wants(score.TypeAdditional) {
+		return d, nil
+	}
+	readmeRepos, err := ghub.FindReadmeRepos(ctx, a.GHClient, a.Cache, a.MembershipCache, opts)
+	add(score.TypeReadme, readmeRepos)
+	if err != nil || !wants(score.TypeAdditional) {
+		return d, err
+	}
+	additional, err := a.additionalRepos(ctx, readmeRepos)
+	add(score.TypeAdditional, additional)
+	return d, err
+}
+
+// additionalRepos returns the hand-added repositories not in found, with
+// their metadata and classified as hand-added.
+func (a *App) additionalRepos(ctx context.Context, found []ghub.Repo) ([]ghub.Repo, error) {
+	seen := make(map[string]bool, len(found))
+	for _, r := range found {
+		seen[r.FullName()] = true
+	}
+	var repos []ghub.Repo
+	for _, repoName := range a.AdditionalRepos {
+		owner, name, ok := strings.Cut(repoName, "/")
+		if !ok || seen[repoName] {
+			continue
+		}
+		seen[repoName] = true
+		meta, err := ghub.GetRepoMetadata(ctx, a.GHClient, a.Cache, owner, name, a.Config.NoCache)
+		if err != nil {
+			return repos, fmt.Errorf("getting metadata for %s: %w", repoName, err)
+		}
+		repos = append(repos, ghub.Repo{Owner: owner, Name: name, RepoMetadata: meta, Classification: ghub.ClassHandAdded})
+	}
+	return repos, nil
+}
+
+// ofType returns the repositories of the records with one of types, in
+// dataset order.
+func (d *dataset) ofType(types ...string) []ghub.Repo {
+	var repos []ghub.Repo
+	for _, r := range d.Records {
+		for _, t := range types {
+			if r.Type == t {
+				repos = append(repos, r.Repo)
+				break
+			}
+		}
+	}
+	return repos
+}
+
+// unique returns each repository once, keeping its first record: manifest
+// repositories first, then README mentions, then additional repositories.
+func (d *dataset) unique() []typedRepo {
+	seen := make(map[string]bool, len(d.Records))
+	var repos []typedRepo
+	for _, r := range d.Records {
+		if !seen[r.FullName()] {
+			seen[r.FullName()] = true
+			repos = append(repos, r)
+		}
+	}
+	return repos
+}
+
+// stars sums the stars of all records. A repository found both by its
+// manifest and its README counts once for each.
+func (d *dataset) stars() int {
+	total := 0
+	for _, r := range d.Records {
+		total += r.Stars
+	}
+	return total
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/stahnma/gh-flox/internal/format"
+	ghub "github.com/stahnma/gh-flox/internal/github"
+)
+
+func (a *App) newDiffCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <from.json> [to.json]",
+		Short: "Compare two exports",
+		Long: `Compare two exports, listing added and removed repositories and star
+changes. With a single file, it is compared against a fresh export of the
+same scope: exports before schema version 3 are taken to be scoped, unless
+--full says otherwise. If only one export is scoped, only the repositories in
+scope are compared.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.runDiff(cmd, args)
+		},
+	}
+	cmd.Flags().Bool("scoped", false, "Only include external and hand-added repositories in a fresh export")
+	cmd.Flags().BoolP("full", "f", false, "Treat from.json as a full export, for exports before schema version 3")
+	_ = cmd.Flags().MarkDeprecated("full", "a fresh export now follows the scope recorded in from.json")
+	cmd.Flags().Bool("json", false, "Write the difference as JSON")
+	return cmd
+}
+
+func (a *App) runDiff(cmd *cobra.Command, args []string) error {
+	scoped, _ := cmd.Flags().GetBool("scoped")
+	full, _ := cmd.Flags().GetBool("full")
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	w := cmd.OutOrStdout()
+
+	from, err := readExport(args[0])
+	if err != nil {
+		return err
+	}
+	if full && from.SchemaVersion < 3 {
+		from.Scope = ghub.ScopeAll
+	}
+	var to *ghub.Export
+	if len(args) == 2 {
+		if to, err = readExport(args[1]); err != nil {
+			return err
+		}
+	} else {
+		ctx, cancel := a.commandContext(cmd)
+		defer cancel()
+		p := a.progressReporter(cmd)
+		defer p.Done()
+		var buf bytes.Buffer
+		if err := a.ExportJSON(ctx, &buf, ExportOptions{Scoped: scoped || from.Scoped(), Progress: p}); err != nil {
+			return err
+		}
+		if to, err = ghub.DecodeExport(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	d := ghub.DiffExports(from, to)
+	if jsonOut {
+		return format.WriteJSON(w, d, a.Config.SlackMode)
+	}
+	writeDiff(w, d)
+	return nil
+}
+
+// readExport reads and decodes an export file.
+func readExport(path string) (*ghub.Export, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	exp, err := ghub.DecodeExport(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return exp, nil
+}
+
+// writeDiff prints the star totals and a table of every changed record.
+func writeDiff(w io.Writer, d ghub.ExportDiff) {
+	fmt.Fprintf(w, "Stars: %d -> %d (%+d)\n", d.StarsBefore, d.StarsAfter, d.StarsAfter-d.StarsBefore)
+	fmt.Fprintf(w, "Added: %d, removed: %d, changed: %d\n", len(d.Added), len(d.Removed), len(d.Changed))
+	if len(d.Added)+len(d.Removed)+len(d.Changed) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHANGE\tREPO\tTYPE\tBEFORE\tAFTER\tDELTA")
+	for _, group := range []struct {
+		name    string
+		changes []ghub.RepoChange
+	}{{"added", d.Added}, {"removed", d.Removed}, {"changed", d.Changed}} {
+		for _, c := range group.changes {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%+d\n", group.name, c.Repository, c.Type, c.Before, c.After, c.Delta)
+		}
+	}
+	tw.Flush()
+}
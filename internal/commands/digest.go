@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stahnma/gh-flox/internal/digest"
+	"github.com/stahnma/gh-flox/internal/format"
+	"github.com/stahnma/gh-flox/internal/history"
+	"github.com/stahnma/gh-flox/internal/progress"
+)
+
+func (a *App) newDigestCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "digest",
+		Short: "Summarize the last week: stars, floxindex and adopters",
+		Long: `Summarize a period, by default the last week, from the history store:
+flox/flox stars and their change, the floxindex change, repository counts per
+type, new and lost adopters with links, the top new repositories by stars and
+the manifest repositories gaining the most stars.
+
+The digest reads the history store only (see history import). With
+--refresh, a fresh export and the star count are first recorded in the store
+for today, so the digest covers up to now.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.runDigest(cmd)
+		},
+	}
+	cmd.Flags().String("store", "", "History store file (default $GH_FLOX_HISTORY or history.json in the config directory)")
+	cmd.Flags().String("format", "", "Output format: markdown, slack or html (default slack in Slack mode, else markdown)")
+	cmd.Flags().String("date", "", "Last date of the period, YYYY-MM-DD (default the latest snapshot)")
+	cmd.Flags().Int("days", 7, "Length of the period in days")
+	cmd.Flags().Int("top", 5, "Number of repositories in the top lists")
+	cmd.Flags().String("repo", "flox/flox", "Repository whose stars are reported")
+	cmd.Flags().Bool("refresh", false, "Record a fresh export and star count in the history store first")
+	cmd.Flags().Bool("json", false, "Write the digest data as JSON")
+	return cmd
+}
+
+func (a *App) runDigest(cmd *cobra.Command) error {
+	formatFlag, _ := cmd.Flags().GetString("format")
+	days, _ := cmd.Flags().GetInt("days")
+	top, _ := cmd.Flags().GetInt("top")
+	repo, _ := cmd.Flags().GetString("repo")
+	refresh, _ := cmd.Flags().GetBool("refresh")
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	w := cmd.OutOrStdout()
+
+	if formatFlag == "" {
+		formatFlag = digest.FormatMarkdown
+		if a.Config.SlackMode {
+			formatFlag = digest.FormatSlack
+		}
+	}
+	if days < 1 || top < 1 {
+		return fmt.Errorf("--days and --top must be at least 1")
+	}
+	date, err := dateFlag(cmd, "date")
+	if err != nil {
+		return err
+	}
+	path := a.historyPath(cmd)
+	store, err := history.Load(path)
+	if err != nil {
+		return fmt.Errorf("loading history: %w", err)
+	}
+
+	if refresh {
+		ctx, cancel := a.commandContext(cmd)
+		defer cancel()
+		p := a.progressReporter(cmd)
+		err := a.RecordHistory(ctx, store, []string{repo}, time.Now(), p)
+		p.Done()
+		if err != nil {
+			return fmt.Errorf("refreshing history: %w", err)
+		}
+		if err := store.Save(path); err != nil {
+			return fmt.Errorf("saving history: %w", err)
+		}
+	}
+
+	d, err := digest.Build(store, digest.Options{Date: date, Days: days, Top: top, Repo: repo})
+	if err != nil {
+		return err
+	}
+	if jsonOut {
+		return format.WriteJSON(w, d, a.Config.SlackMode)
+	}
+	return digest.Render(w, d, formatFlag)
+}
+
+// RecordHistory records a fresh export, the floxindex and the star counts of
+// repos in store for now's date.
+func (a *App) RecordHistory(ctx context.Context, store *history.Store, repos []string, now time.Time, p progress.Reporter) error {
+	if err := a.ensureClient(); err != nil {
+		return err
+	}
+	for _, repo := range repos {
+		if _, _, ok := strings.Cut(repo, "/"); !ok {
+			return fmt.Errorf("invalid repository %q: expected owner/repo", repo)
+		}
+	}
+	var buf bytes.Buffer
+	if err := a.ExportJSON(ctx, &buf, ExportOptions{Progress: p}); err != nil {
+		return err
+	}
+	imp, err := history.Parse("gh-flox export", buf.Bytes(), now.Format(history.DateLayout))
+	if err != nil {
+		return err
+	}
+	imp.Stars = make(map[string]int)
+	for _, repo := range repos {
+		owner, name, _ := strings.Cut(repo, "/")
+		if imp.Stars[repo], err = a.StarCount(ctx, owner, name); err != nil {
+			return err
+		}
+	}
+	floxIndex := imp.ScopedStars()
+	imp.FloxIndex = &floxIndex
+	store.Apply(imp)
+	return nil
+}
@@ -4,114 +4,209 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
-	"net/http"
-	"os"
-	"path/filepath"
+	"log/slog"
+	"path"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/stahnma/gh-flox/internal/download"
+	"github.com/stahnma/gh-flox/internal/format"
 	ghub "github.com/stahnma/gh-flox/internal/github"
+	"github.com/stahnma/gh-flox/internal/progress"
 )
 
+// fileSelectors maps --files values to environment files.
+var fileSelectors = map[string]string{
+	"manifest.toml": ghub.FileManifest,
+	"manifest.lock": ghub.FileLock,
+	"env.json":      ghub.FileEnvJSON,
+}
+
+// downloadRetryDelay is the initial backoff between download retries.
+var downloadRetryDelay = time.Second
+
 func (a *App) newDownloadManifestsCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "download-manifests",
-		Short: "Download manifest.toml files from repositories with .flox directory",
+		Short: "Download flox environment files from repositories with .flox directory",
+		Long: `Download flox environment files from repositories with .flox directory.
+
+Every flox environment in a repository is downloaded, saved under
+<output-dir>/<owner>/<repo>/ with its path in the repository preserved, e.g.
+manifests/alice/app/backend/.flox/env/manifest.toml.
+
+Each repository's default branch is resolved to a commit SHA once and files
+are fetched as git blobs at that commit through the API. The owner, repo,
+path, commit SHA and blob SHA of every file are recorded in
+<output-dir>/index.json; files whose blob is unchanged are not downloaded
+again, so an interrupted run can simply be repeated.
+
+Repositories are processed concurrently and transient failures are retried
+with exponential backoff. A summary of downloaded, unchanged, missing and
+failed files is printed at the end, or a JSON report with --json. The
+command exits non-zero if any file failed.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.runDownloadManifests(cmd)
 		},
 	}
 	cmd.Flags().StringP("output-dir", "o", "manifests", "Directory to save downloaded manifests")
+	cmd.Flags().StringSlice("files", []string{"manifest.toml", "manifest.lock", "env.json"}, "Environment files to download: manifest.toml, manifest.lock, env.json")
+	cmd.Flags().Int("concurrency", 4, "Number of repositories to download at once")
+	cmd.Flags().Int("retries", 3, "Number of retries for transient failures")
+	cmd.Flags().Bool("json", false, "Write a JSON report instead of the summary table")
 	return cmd
 }
 
-func (a *App) runDownloadManifests(cmd *cobra.Command) error {
+// DownloadOptions configures DownloadManifests.
+type DownloadOptions struct {
+	// OutputDir receives the files and the download index.
+	OutputDir string
+	// Files selects environment files; empty means all of them.
+	Files       []string
+	Concurrency int
+	Retries     int
+	// Progress receives search and download progress events. Nil disables
+	// reporting.
+	Progress progress.Reporter
+}
+
+// DownloadManifests finds repositories with a flox manifest and downloads
+// their environment files into do.OutputDir. If ctx is done first, the
+// partial report is returned, marked incomplete, together with the error.
+func (a *App) DownloadManifests(ctx context.Context, do DownloadOptions) (*download.Report, error) {
 	if err := a.ensureClient(); err != nil {
-		return err
+		return nil, err
 	}
-	ctx := context.Background()
-	w := cmd.OutOrStdout()
-	outputDir, _ := cmd.Flags().GetString("output-dir")
-
 	repos, err := ghub.FindManifestRepos(ctx, a.GHClient, a.Cache, a.MembershipCache, ghub.SearchOptions{
-		NoCache:   a.Config.NoCache,
-		DebugMode: a.Config.DebugMode,
+		NoCache:  a.Config.NoCache,
+		Progress: do.Progress,
 	})
-	if err != nil {
-		return fmt.Errorf("finding repositories: %w", err)
+	if err != nil && !isInterrupted(err) {
+		return nil, fmt.Errorf("finding repositories: %w", err)
 	}
 
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("creating output directory: %w", err)
+	d := &download.Downloader{
+		Client:      a.GHClient,
+		Cache:       a.Cache,
+		NoCache:     a.Config.NoCache,
+		OutputDir:   do.OutputDir,
+		Files:       do.Files,
+		Concurrency: do.Concurrency,
+		Retries:     do.Retries,
+		RetryDelay:  downloadRetryDelay,
+		Progress:    do.Progress,
 	}
-
-	for _, repo := range repos {
-		filePath := a.fetchManifestFile(ctx, outputDir, repo.Owner, repo.Name)
-		if filePath != "" {
-			fmt.Fprintf(w, "Downloaded manifest.toml for %s/%s to %s\n", repo.Owner, repo.Name, filePath)
-		}
+	report, runErr := d.Run(ctx, repos)
+	if report == nil || (runErr != nil && !isInterrupted(runErr)) {
+		return nil, runErr
 	}
-	return nil
+	if err == nil {
+		err = runErr
+	}
+	report.Incomplete = err != nil
+	return report, err
 }
 
-func (a *App) fetchManifestFile(ctx context.Context, outputDir, owner, repo string) string {
-	manifestPath, err := ghub.FetchManifestPath(ctx, a.GHClient, owner, repo)
+func (a *App) runDownloadManifests(cmd *cobra.Command) error {
+	outputDir, _ := cmd.Flags().GetString("output-dir")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	retries, _ := cmd.Flags().GetInt("retries")
+	jsonReport, _ := cmd.Flags().GetBool("json")
+	files, err := selectedFiles(cmd)
 	if err != nil {
-		log.Printf("Error searching for manifest.toml in %s/%s: %v", owner, repo, err)
-		return ""
+		return err
 	}
-	if manifestPath == "" {
-		log.Printf("No manifest.toml found in %s/%s", owner, repo)
-		return ""
+	if concurrency < 1 {
+		return fmt.Errorf("invalid --concurrency %d: must be at least 1", concurrency)
 	}
-
-	repository, _, err := a.GHClient.GetRepository(ctx, owner, repo)
-	if err != nil {
-		log.Printf("Error fetching repository info for %s/%s: %v", owner, repo, err)
-		return ""
+	if retries < 0 {
+		return fmt.Errorf("invalid --retries %d: must not be negative", retries)
 	}
-	branch := repository.GetDefaultBranch()
-	if branch == "" {
-		branch = "main"
+	if err := a.ensureClient(); err != nil {
+		return err
 	}
+	ctx, cancel := a.commandContext(cmd)
+	defer cancel()
+	p := a.progressReporter(cmd)
+	defer p.Done()
+	w := cmd.OutOrStdout()
 
-	rawURL := fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s/%s", owner, repo, branch, manifestPath)
-
-	httpClient := &http.Client{Timeout: 30 * time.Second}
-	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
-	if err != nil {
-		log.Printf("Error creating request for %s: %v", rawURL, err)
-		return ""
+	report, err := a.DownloadManifests(ctx, DownloadOptions{
+		OutputDir:   outputDir,
+		Files:       files,
+		Concurrency: concurrency,
+		Retries:     retries,
+		Progress:    p,
+	})
+	if report == nil {
+		return err
 	}
-	if a.Config.GitHubToken != "" {
-		req.Header.Set("Authorization", "token "+a.Config.GitHubToken)
+
+	if jsonReport {
+		if writeErr := format.WriteJSON(w, report, a.Config.SlackMode); writeErr != nil {
+			return writeErr
+		}
+		if err != nil {
+			if saveErr := a.SaveCache(); saveErr != nil {
+				slog.Error("saving cache failed", "err", saveErr)
+			}
+			return fmt.Errorf("incomplete download: %w", err)
+		}
+	} else {
+		writeDownloadReport(w, report)
+		if err != nil {
+			return a.incomplete(w, err)
+		}
 	}
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		log.Printf("Error fetching raw content from %s: %v", rawURL, err)
-		return ""
+	if report.Summary.Failed > 0 {
+		return fmt.Errorf("%d of %d files failed to download", report.Summary.Failed, len(report.Results))
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Failed to fetch file from %s, status code: %d", rawURL, resp.StatusCode)
-		return ""
+// selectedFiles returns the environment files chosen with --files.
+func selectedFiles(cmd *cobra.Command) ([]string, error) {
+	names, _ := cmd.Flags().GetStringSlice("files")
+	var files []string
+	for _, name := range names {
+		file, ok := fileSelectors[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid --files value %q: use manifest.toml, manifest.lock or env.json", name)
+		}
+		files = append(files, file)
 	}
+	return files, nil
+}
 
-	localFilePath := filepath.Join(outputDir, fmt.Sprintf("%s_%s_manifest.toml", owner, repo))
-	file, err := os.Create(localFilePath)
-	if err != nil {
-		log.Printf("Error creating local file for %s/%s: %v", owner, repo, err)
-		return ""
+// writeDownloadReport prints the downloaded files, a summary table and the
+// reason for every missing or failed file.
+func writeDownloadReport(w io.Writer, report *download.Report) {
+	for _, r := range report.Results {
+		if r.Status == download.StatusDownloaded {
+			fmt.Fprintf(w, "Downloaded %s for %s/%s to %s\n", path.Base(r.Path), r.Owner, r.Repo, r.LocalPath)
+		}
 	}
-	defer file.Close()
 
-	_, err = io.Copy(file, resp.Body)
-	if err != nil {
-		log.Printf("Error saving manifest.toml for %s/%s: %v", owner, repo, err)
-		return ""
-	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tFILES")
+	fmt.Fprintf(tw, "%s\t%d\n", download.StatusDownloaded, report.Summary.Downloaded)
+	fmt.Fprintf(tw, "%s\t%d\n", download.StatusUnchanged, report.Summary.Unchanged)
+	fmt.Fprintf(tw, "%s\t%d\n", download.StatusMissing, report.Summary.Missing)
+	fmt.Fprintf(tw, "%s\t%d\n", download.StatusFailed, report.Summary.Failed)
+	tw.Flush()
 
-	return localFilePath
+	if report.Summary.Missing+report.Summary.Failed == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPO\tPATH\tSTATUS\tREASON")
+	for _, r := range report.Results {
+		if r.Status != download.StatusMissing && r.Status != download.StatusFailed {
+			continue
+		}
+		fmt.Fprintf(tw, "%s/%s\t%s\t%s\t%s\n", r.Owner, r.Repo, defaultString(r.Path, "-"), r.Status, r.Reason)
+	}
+	tw.Flush()
 }
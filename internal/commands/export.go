@@ -4,11 +4,17 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/stahnma/gh-flox/internal/compare"
+	"github.com/stahnma/gh-flox/internal/filter"
 	"github.com/stahnma/gh-flox/internal/format"
 	ghub "github.com/stahnma/gh-flox/internal/github"
+	"github.com/stahnma/gh-flox/internal/progress"
+	"github.com/stahnma/gh-flox/internal/score"
 )
 
 func (a *App) newExportCommand() *cobra.Command {
@@ -16,56 +22,181 @@ func (a *App) newExportCommand() *cobra.Command {
 		Use:   "export [flags]",
 		Short: "Export data in JSON format",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			showFull, _ := cmd.Flags().GetBool("full")
-			return a.ExportJSON(context.Background(), cmd.OutOrStdout(), showFull)
+			scoped, _ := cmd.Flags().GetBool("scoped")
+			withScore, _ := cmd.Flags().GetBool("score")
+			adoptionDates, _ := cmd.Flags().GetBool("adoption-dates")
+			f, err := filterFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+			weights, err := a.scoreWeights(cmd)
+			if err != nil {
+				return err
+			}
+			var tools []compare.Tool
+			if withCompare, _ := cmd.Flags().GetBool("compare"); withCompare {
+				if tools, err = a.compareTools(); err != nil {
+					return err
+				}
+			}
+			ctx, cancel := a.commandContext(cmd)
+			defer cancel()
+			p := a.progressReporter(cmd)
+			defer p.Done()
+			opts := ExportOptions{Scoped: scoped, Filter: f, AdoptionDates: adoptionDates, Compare: tools, Progress: p}
+			if withScore {
+				opts.ScoreWeights = weights
+			}
+			return a.ExportJSON(ctx, cmd.OutOrStdout(), opts)
 		},
 	}
+	cmd.Flags().Bool("scoped", false, "Only export external and hand-added repositories")
 	cmd.Flags().BoolP("full", "f", false, "Include repositories from excluded organizations")
+	_ = cmd.Flags().MarkDeprecated("full", "every repository is exported with its classification; use --scoped for the external view")
+	cmd.Flags().Bool("adoption-dates", false, "Include when each repository adopted flox, from its commit history")
+	cmd.Flags().Bool("score", false, "Include the adoption score and per-repository contributions")
+	cmd.Flags().Bool("compare", false, "Include the comparison with other environment managers, as reported by compare")
+	cmd.Flags().StringSlice("weight", nil, "Override a score signal weight, e.g. --weight stars=0.5 (repeatable)")
+	addFilterFlags(cmd)
 	return cmd
 }
 
-// ExportJSON runs the export logic, writing JSON to w.
-func (a *App) ExportJSON(ctx context.Context, w io.Writer, showFull bool) error {
+// ExportOptions controls what ExportJSON writes.
+type ExportOptions struct {
+	// Scoped exports only repositories in scope. Otherwise every
+	// repository is exported with its classification, so both views come
+	// from one run.
+	Scoped bool
+	// Filter selects the exported repositories and is recorded in the output.
+	Filter filter.Filter
+	// AdoptionDates adds each repository's adoption date.
+	AdoptionDates bool
+	// ScoreWeights, if non-nil, adds the adoption score computed with these
+	// weights.
+	ScoreWeights score.Weights
+	// Compare, if non-nil, adds the comparison with these tools.
+	Compare []compare.Tool
+	// Progress receives progress events. Nil disables reporting.
+	Progress progress.Reporter
+}
+
+// ExportJSON runs the export logic, writing JSON to w. If ctx is canceled,
+// the repositories found so far are written with the incomplete flag set and
+// an error is returned.
+func (a *App) ExportJSON(ctx context.Context, w io.Writer, eo ExportOptions) error {
 	if err := a.ensureClient(); err != nil {
 		return err
 	}
 
-	var allRepos []ghub.RepoInfo
-	date := time.Now().Format("2006-Jan-02")
+	now := time.Now()
+	date := now.Format("2006-Jan-02")
+	export := ghub.Export{
+		SchemaVersion: ghub.ExportSchemaVersion,
+		Date:          date,
+		GeneratedAt:   now.UTC(),
+		Scope:         ghub.ScopeAll,
+		Filter:        eo.Filter.String(),
+		Repositories:  []ghub.RepoInfo{},
+	}
+	if eo.Scoped {
+		export.Scope = ghub.ScopeExternal
+	}
 
-	opts := ghub.SearchOptions{
-		ShowFull:  showFull,
-		NoCache:   a.Config.NoCache,
-		DebugMode: a.Config.DebugMode,
+	d, err := a.loadDataset(ctx, !eo.Scoped, eo.Filter, eo.Progress, allRecordTypes...)
+	if err != nil && !isInterrupted(err) {
+		return fmt.Errorf("finding repositories: %w", err)
+	}
+	for _, r := range d.Records {
+		export.Repositories = append(export.Repositories, ghub.NewRepoInfo(date, r.Type, r.Repo))
 	}
 
-	// Get repos with .flox/env/manifest.toml
-	repos, err := ghub.FindManifestRepos(ctx, a.GHClient, a.Cache, a.MembershipCache, opts)
-	if err != nil {
-		return fmt.Errorf("finding manifest repositories: %w", err)
+	if err == nil && eo.AdoptionDates {
+		err = a.addExportAdoptionDates(ctx, &export, eo.Progress)
+		if err != nil && !isInterrupted(err) {
+			return fmt.Errorf("finding adoption dates: %w", err)
+		}
 	}
-	for _, repo := range repos {
-		allRepos = append(allRepos, ghub.RepoInfo{
-			Date:       date,
-			Repository: repo.FullName(),
-			Type:       "dotflox",
-			StarCount:  repo.Stars,
-		})
+	if err == nil && eo.ScoreWeights != nil {
+		err = a.addExportScore(ctx, &export, eo.ScoreWeights, now, eo.Progress)
+		if err != nil && !isInterrupted(err) {
+			return fmt.Errorf("calculating score: %w", err)
+		}
+	}
+
+	if err == nil && eo.Compare != nil {
+		export.Comparison, err = a.compareAdoption(ctx, d, eo.Compare, !eo.Scoped, eo.Filter, eo.Progress)
+		if err != nil && !isInterrupted(err) {
+			return fmt.Errorf("comparing tools: %w", err)
+		}
 	}
 
-	// Get repos with 'flox install' in README
-	readmeRepos, err := ghub.FindReadmeRepos(ctx, a.GHClient, a.Cache, a.MembershipCache, opts)
+	export.Incomplete = err != nil
+	if writeErr := format.WriteJSON(w, export, a.Config.SlackMode); writeErr != nil {
+		return writeErr
+	}
 	if err != nil {
-		return fmt.Errorf("finding readme repositories: %w", err)
+		if saveErr := a.SaveCache(); saveErr != nil {
+			slog.Error("saving cache failed", "err", saveErr)
+		}
+		return fmt.Errorf("incomplete export: %w", err)
 	}
-	for _, repo := range readmeRepos {
-		allRepos = append(allRepos, ghub.RepoInfo{
-			Date:       date,
-			Repository: repo.FullName(),
-			Type:       "readme",
-			StarCount:  repo.Stars,
+	return nil
+}
+
+// addExportScore scores the exported repositories and records each
+// repository's contribution on its first record, so that summing the score
+// field over all records gives the total.
+func (a *App) addExportScore(ctx context.Context, export *ghub.Export, weights score.Weights, now time.Time, p progress.Reporter) error {
+	seen := make(map[string]bool)
+	var repos []typedRepo
+	for _, info := range export.Repositories {
+		if seen[info.Repository] {
+			continue
+		}
+		seen[info.Repository] = true
+		owner, name, _ := strings.Cut(info.Repository, "/")
+		repos = append(repos, typedRepo{
+			Type: info.Type,
+			Repo: ghub.Repo{Owner: owner, Name: name, RepoMetadata: ghub.RepoMetadata{Stars: info.StarCount}},
 		})
 	}
 
-	return format.WriteJSON(w, allRepos, a.Config.SlackMode)
+	res, err := a.computeScore(ctx, repos, weights, now, p)
+	export.Score = &ghub.AdoptionScore{Total: res.Total, Weights: weights}
+	byRepo := make(map[string]score.Contribution, len(res.Contributions))
+	for _, c := range res.Contributions {
+		byRepo[c.Repo] = c
+	}
+	for i := range export.Repositories {
+		info := &export.Repositories[i]
+		if c, ok := byRepo[info.Repository]; ok && c.Type == info.Type {
+			info.Score = c.Score
+			info.ScoreComponents = c.Components
+		}
+	}
+	return err
+}
+
+// addExportAdoptionDates records each repository's adoption date on its
+// export records. Records whose date cannot be looked up are left without
+// one.
+func (a *App) addExportAdoptionDates(ctx context.Context, export *ghub.Export, p progress.Reporter) error {
+	p = progress.OrNop(p)
+	p.Report(progress.Event{Kind: progress.StageStarted, Stage: "finding adoption dates", Total: len(export.Repositories)})
+	for i := range export.Repositories {
+		info := &export.Repositories[i]
+		owner, name, _ := strings.Cut(info.Repository, "/")
+		date, err := a.adoptionDate(ctx, info.Type, owner, name, info.DefaultBranch)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			slog.Warn("finding adoption date failed", "repo", info.Repository, "err", err)
+		}
+		if !date.IsZero() {
+			info.AdoptedAt = date.UTC().Format(time.RFC3339)
+		}
+		p.Report(progress.Event{Kind: progress.RepoProcessed})
+	}
+	return nil
 }
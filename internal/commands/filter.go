@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/stahnma/gh-flox/internal/filter"
+)
+
+// addFilterFlags registers the repository filter flags shared by repos,
+// readmes, floxindex and export.
+func addFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("exclude-forks", false, "Exclude forked repositories")
+	cmd.Flags().Bool("exclude-archived", false, "Exclude archived repositories")
+	cmd.Flags().String("active-within", "", "Only include repositories pushed to within this long, e.g. 180d")
+	cmd.Flags().Int("min-stars", 0, "Only include repositories with at least this many stars")
+	cmd.Flags().String("owner-type", "", "Only include repositories owned by an org or a user")
+	cmd.Flags().String("filter", "", "Filter expression over repository fields, e.g. 'stars >= 10 && !fork'")
+}
+
+// filterFromFlags builds a filter.Filter from the flags added by addFilterFlags.
+func filterFromFlags(cmd *cobra.Command) (filter.Filter, error) {
+	var f filter.Filter
+	f.ExcludeForks, _ = cmd.Flags().GetBool("exclude-forks")
+	f.ExcludeArchived, _ = cmd.Flags().GetBool("exclude-archived")
+	f.MinStars, _ = cmd.Flags().GetInt("min-stars")
+
+	if s, _ := cmd.Flags().GetString("active-within"); s != "" {
+		d, err := filter.ParseDuration(s)
+		if err != nil {
+			return f, fmt.Errorf("--active-within: %w", err)
+		}
+		f.ActiveWithin = d
+	}
+
+	ownerType, _ := cmd.Flags().GetString("owner-type")
+	ot, err := filter.NormalizeOwnerType(ownerType)
+	if err != nil {
+		return f, fmt.Errorf("--owner-type: %w", err)
+	}
+	f.OwnerType = ot
+
+	if s, _ := cmd.Flags().GetString("filter"); s != "" {
+		expr, err := filter.Parse(s)
+		if err != nil {
+			return f, fmt.Errorf("--filter: %w", err)
+		}
+		f.Expr = expr
+	}
+	return f, nil
+}
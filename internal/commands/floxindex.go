@@ -3,10 +3,11 @@ package commands
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/stahnma/gh-flox/internal/filter"
 	ghub "github.com/stahnma/gh-flox/internal/github"
+	"github.com/stahnma/gh-flox/internal/progress"
 )
 
 func (a *App) newFloxIndexCommand() *cobra.Command {
@@ -18,6 +19,8 @@ func (a *App) newFloxIndexCommand() *cobra.Command {
 		},
 	}
 	cmd.Flags().BoolP("full", "f", false, "Include repositories from excluded organizations")
+	cmd.Flags().Bool("force", false, "Run even if the API rate limit budget looks insufficient")
+	addFilterFlags(cmd)
 	return cmd
 }
 
@@ -25,64 +28,57 @@ func (a *App) runFloxIndex(cmd *cobra.Command) error {
 	if err := a.ensureClient(); err != nil {
 		return err
 	}
-	ctx := context.Background()
+	ctx, cancel := a.commandContext(cmd)
+	defer cancel()
 	showFull, _ := cmd.Flags().GetBool("full")
+	force, _ := cmd.Flags().GetBool("force")
 	w := cmd.OutOrStdout()
-
-	totalStars, err := a.calculateFloxIndex(ctx, showFull)
+	f, err := filterFromFlags(cmd)
 	if err != nil {
-		return fmt.Errorf("calculating floxindex: %w", err)
+		return err
 	}
 
-	fmt.Fprintf(w, "Total floxindex (sum of stars): %d\n", totalStars)
-	return nil
-}
-
-func (a *App) calculateFloxIndex(ctx context.Context, showFull bool) (int, error) {
-	totalStars := 0
+	if err := a.checkBudget(ctx, cmd, a.estimateFloxIndexBudget(showFull), force); err != nil {
+		return err
+	}
 
-	// Stars for repos with .flox/env/manifest.toml
-	opts := ghub.SearchOptions{
-		ShowFull:  showFull,
-		NoCache:   a.Config.NoCache,
-		DebugMode: a.Config.DebugMode,
+	p := a.progressReporter(cmd)
+	defer p.Done()
+	totalStars, err := a.calculateFloxIndex(ctx, showFull, f, p)
+	if err != nil && !isInterrupted(err) {
+		return fmt.Errorf("calculating floxindex: %w", err)
 	}
-	repos, err := ghub.FindManifestRepos(ctx, a.GHClient, a.Cache, a.MembershipCache, opts)
+
+	fmt.Fprintf(w, "Total floxindex (sum of stars): %d\n", totalStars)
 	if err != nil {
-		return 0, err
-	}
-	for _, repo := range repos {
-		stars, err := ghub.GetStarCount(ctx, a.GHClient, a.Cache, repo.Owner, repo.Name, a.Config.NoCache, a.Config.DebugMode)
-		if err != nil {
-			return 0, err
-		}
-		totalStars += stars
+		return a.incomplete(w, err)
 	}
+	return nil
+}
 
-	// Stars for repos with 'flox install' in README
-	readmeRepos, err := ghub.FindReadmeRepos(ctx, a.GHClient, a.Cache, a.MembershipCache, opts)
-	if err != nil {
+// FloxIndex returns the total stars of all flox-related repositories
+// passing f. If ctx is done first, the partial sum is returned with the
+// context's error.
+func (a *App) FloxIndex(ctx context.Context, showFull bool, f filter.Filter) (int, error) {
+	if err := a.ensureClient(); err != nil {
 		return 0, err
 	}
-	for _, repo := range readmeRepos {
-		stars, err := ghub.GetStarCount(ctx, a.GHClient, a.Cache, repo.Owner, repo.Name, a.Config.NoCache, a.Config.DebugMode)
-		if err != nil {
-			return 0, err
-		}
-		totalStars += stars
-	}
+	return a.calculateFloxIndex(ctx, showFull, f, nil)
+}
 
-	// Stars from additional repositories
-	for _, repoName := range a.AdditionalRepos {
-		parts := strings.Split(repoName, "/")
-		if len(parts) == 2 {
-			stars, err := ghub.GetStarCount(ctx, a.GHClient, a.Cache, parts[0], parts[1], a.Config.NoCache, a.Config.DebugMode)
-			if err != nil {
-				return 0, err
-			}
-			totalStars += stars
-		}
-	}
+// estimateFloxIndexBudget estimates the API calls calculateFloxIndex needs.
+func (a *App) estimateFloxIndexBudget(showFull bool) ghub.Budget {
+	b := ghub.EstimateSearchBudget(a.Cache, a.MembershipCache, ghub.SearchOptions{
+		ShowFull: showFull,
+		NoCache:  a.Config.NoCache,
+	}, ghub.ManifestSignature, ghub.ReadmeSignature)
+	b.Core += len(a.AdditionalRepos)
+	return b
+}
 
-	return totalStars, nil
+// calculateFloxIndex sums the stars of every dataset record passing f. If
+// ctx is canceled, the partial sum is returned along with the context's error.
+func (a *App) calculateFloxIndex(ctx context.Context, showFull bool, f filter.Filter, p progress.Reporter) (int, error) {
+	d, err := a.loadDataset(ctx, showFull, f, p, allRecordTypes...)
+	return d.stars(), err
 }
@@ -0,0 +1,250 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/stahnma/gh-flox/internal/format"
+	"github.com/stahnma/gh-flox/internal/history"
+	"github.com/stahnma/gh-flox/internal/s3client"
+)
+
+// newHistoryS3Client creates the client for s3:// imports.
+var newHistoryS3Client = func(ctx context.Context) (history.S3Client, error) {
+	return s3client.New(ctx, s3client.SettingsFromEnvironment())
+}
+
+func (a *App) newHistoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Import and show past results",
+		Long: `Import and show past results kept in the history store, a JSON file at
+$GH_FLOX_HISTORY or history.json in the gh-flox config directory.`,
+	}
+	cmd.PersistentFlags().String("store", "", "History store file (default $GH_FLOX_HISTORY or history.json in the config directory)")
+	cmd.AddCommand(a.newHistoryImportCommand())
+	cmd.AddCommand(a.newHistoryShowCommand())
+	return cmd
+}
+
+func (a *App) newHistoryImportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <file|dir|s3://bucket/prefix>...",
+		Short: "Import results into the history store",
+		Long: `Import results into the history store.
+
+Sources are export JSON files of any schema version, floxindex artifacts
+uploaded by the Lambda, and the text printed by repos, readmes, floxindex and
+stars (including repos -v lines such as owner/repo,stars,environments),
+gzipped or not. Directories are imported recursively and s3://bucket/prefix
+imports every JSON object under the prefix.
+
+Exports carry their date. Other sources are dated by a date in their name,
+such as 2024-Mar-01, 2024-03-01 or 2024/03/01, or by --date. Each import
+replaces what is stored for its date and types, so importing a source twice
+changes nothing. Incomplete results are rejected.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.runHistoryImport(cmd, args)
+		},
+	}
+	cmd.Flags().String("date", "", "Date of the imported results, YYYY-MM-DD, overriding the date found in them")
+	return cmd
+}
+
+// importResult is the outcome of importing one source.
+type importResult struct {
+	Source  string
+	Date    string
+	Outcome string
+	Reason  string
+}
+
+const (
+	importSkipped = "skipped"
+	importFailed  = "failed"
+)
+
+func (a *App) runHistoryImport(cmd *cobra.Command, args []string) error {
+	date, _ := cmd.Flags().GetString("date")
+	path := a.historyPath(cmd)
+	w := cmd.OutOrStdout()
+	ctx, cancel := a.commandContext(cmd)
+	defer cancel()
+
+	store, err := history.Load(path)
+	if err != nil {
+		return fmt.Errorf("loading history: %w", err)
+	}
+
+	var results []importResult
+	visit := func(name string, data []byte, err error) error {
+		res := importResult{Source: name}
+		if err == nil {
+			var imp *history.Import
+			if imp, err = history.Parse(name, data, date); err == nil {
+				res.Date = imp.Date
+				res.Outcome = string(store.Apply(imp))
+			}
+		}
+		switch {
+		case errors.Is(err, history.ErrUnknownJSON):
+			res.Outcome, res.Reason = importSkipped, err.Error()
+		case err != nil:
+			slog.Warn("import failed", "source", name, "err", err)
+			res.Outcome, res.Reason = importFailed, err.Error()
+		}
+		results = append(results, res)
+		return nil
+	}
+
+	var s3 history.S3Client
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "s3://") {
+			if err := history.WalkFiles(arg, visit); err != nil {
+				return err
+			}
+			continue
+		}
+		bucket, prefix, err := history.ParseS3URL(arg)
+		if err != nil {
+			return err
+		}
+		if s3 == nil {
+			if s3, err = newHistoryS3Client(ctx); err != nil {
+				return err
+			}
+		}
+		if err := history.WalkS3(ctx, s3, bucket, prefix, visit); err != nil {
+			return err
+		}
+	}
+
+	failed, changed := 0, false
+	for _, r := range results {
+		switch r.Outcome {
+		case importFailed:
+			failed++
+		case string(history.OutcomeAdded), string(history.OutcomeUpdated):
+			changed = true
+		}
+	}
+	if changed {
+		if err := store.Save(path); err != nil {
+			return fmt.Errorf("saving history: %w", err)
+		}
+	}
+	writeImportResults(w, results)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d sources failed to import", failed, len(results))
+	}
+	return nil
+}
+
+// historyPath returns the store path from --store or the configuration.
+func (a *App) historyPath(cmd *cobra.Command) string {
+	if path, _ := cmd.Flags().GetString("store"); path != "" {
+		return path
+	}
+	return a.Config.HistoryFile
+}
+
+// writeImportResults prints a table of import outcomes and a summary line.
+func writeImportResults(w io.Writer, results []importResult) {
+	counts := make(map[string]int)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tDATE\tRESULT\tREASON")
+	for _, r := range results {
+		counts[r.Outcome]++
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Source, defaultString(r.Date, "-"), r.Outcome, r.Reason)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "Imported %d sources: %d added, %d updated, %d unchanged, %d skipped, %d failed\n",
+		len(results), counts[string(history.OutcomeAdded)], counts[string(history.OutcomeUpdated)],
+		counts[string(history.OutcomeUnchanged)], counts[importSkipped], counts[importFailed])
+}
+
+func (a *App) newHistoryShowCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show [owner/repo]",
+		Short: "Show stored totals by date, or one repository's star history",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.runHistoryShow(cmd, args)
+		},
+	}
+	cmd.Flags().Bool("json", false, "Write JSON instead of a table")
+	return cmd
+}
+
+func (a *App) runHistoryShow(cmd *cobra.Command, args []string) error {
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	w := cmd.OutOrStdout()
+	store, err := history.Load(a.historyPath(cmd))
+	if err != nil {
+		return fmt.Errorf("loading history: %w", err)
+	}
+
+	if len(args) == 1 {
+		repo := args[0]
+		points := store.RepoHistory(repo)
+		if jsonOut {
+			return format.WriteJSON(w, struct {
+				Repository string              `json:"repository"`
+				FirstSeen  string              `json:"first_seen,omitempty"`
+				Stars      []history.StarPoint `json:"stars"`
+			}{repo, store.FirstSeen(repo), points}, a.Config.SlackMode)
+		}
+		fmt.Fprintf(w, "First seen: %s\n", defaultString(store.FirstSeen(repo), "never"))
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "DATE\tSTARS")
+		for _, p := range points {
+			fmt.Fprintf(tw, "%s\t%d\n", p.Date, p.Stars)
+		}
+		return tw.Flush()
+	}
+
+	if jsonOut {
+		return format.WriteJSON(w, store, a.Config.SlackMode)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATE\tDOTFLOX\tREADME\tADDITIONAL\tSTARS\tFLOXINDEX")
+	for _, snap := range store.Snapshots {
+		stars, known := 0, len(snap.Types) > 0
+		var counts []string
+		for _, t := range []string{history.TypeDotflox, history.TypeReadme, history.TypeAdditional} {
+			obs, ok := snap.Types[t]
+			if !ok {
+				counts = append(counts, "-")
+				continue
+			}
+			counts = append(counts, strconv.Itoa(obs.Count))
+			if obs.Stars == nil {
+				known = false
+			} else {
+				stars += *obs.Stars
+			}
+		}
+		floxIndex := "-"
+		if snap.FloxIndex != nil {
+			floxIndex = strconv.Itoa(*snap.FloxIndex)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", snap.Date, strings.Join(counts, "\t"), optionalInt(stars, known), floxIndex)
+	}
+	return tw.Flush()
+}
+
+// optionalInt formats n, or "-" if it is not known.
+func optionalInt(n int, known bool) string {
+	if !known {
+		return "-"
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+	"github.com/spf13/cobra"
+	ghub "github.com/stahnma/gh-flox/internal/github"
+)
+
+func (a *App) newRateLimitCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ratelimit",
+		Short: "Show the remaining GitHub API rate limits",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.runRateLimit(cmd)
+		},
+	}
+}
+
+func (a *App) runRateLimit(cmd *cobra.Command) error {
+	if err := a.ensureClient(); err != nil {
+		return err
+	}
+	ctx, cancel := a.commandContext(cmd)
+	defer cancel()
+	w := cmd.OutOrStdout()
+
+	limits, _, err := a.GHClient.GetRateLimits(ctx)
+	if err != nil {
+		return fmt.Errorf("retrieving rate limits: %w", err)
+	}
+
+	if a.Config.SlackMode {
+		fmt.Fprintln(w, "```")
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESOURCE\tLIMIT\tREMAINING\tRESET")
+	for _, r := range []struct {
+		name string
+		rate *gh.Rate
+	}{
+		{ghub.ResourceCore, limits.Core},
+		{ghub.ResourceSearch, limits.Search},
+		{ghub.ResourceCodeSearch, limits.CodeSearch},
+		{ghub.ResourceGraphQL, limits.GraphQL},
+	} {
+		if r.rate == nil {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", r.name, r.rate.Limit, r.rate.Remaining, r.rate.Reset.Format(time.TimeOnly))
+	}
+	tw.Flush()
+	if a.Config.SlackMode {
+		fmt.Fprintln(w, "```")
+	}
+	return nil
+}
+
+// checkBudget compares the estimated cost of an operation against the
+// current rate limits. Search shortfalls only warn since the search limits
+// reset every minute and repository searches wait for the reset; a core
+// shortfall is an error unless force is set.
+func (a *App) checkBudget(ctx context.Context, cmd *cobra.Command, need ghub.Budget, force bool) error {
+	limits, _, err := a.GHClient.GetRateLimits(ctx)
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: unable to check rate limits: %v\n", err)
+		return nil
+	}
+	for _, s := range ghub.CheckBudget(limits, need) {
+		if s.Resource == ghub.ResourceCore && !force {
+			return fmt.Errorf("insufficient API budget, %s (use --force to run anyway)", s)
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: API budget may be insufficient, %s\n", s)
+	}
+	return nil
+}
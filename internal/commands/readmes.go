@@ -1,13 +1,11 @@
 package commands
 
 import (
-	"context"
 	"fmt"
 	"sort"
-	"strings"
 
 	"github.com/spf13/cobra"
-	ghub "github.com/stahnma/gh-flox/internal/github"
+	"github.com/stahnma/gh-flox/internal/score"
 )
 
 func (a *App) newReadmesCommand() *cobra.Command {
@@ -20,6 +18,7 @@ func (a *App) newReadmesCommand() *cobra.Command {
 	}
 	cmd.Flags().BoolP("verbose", "v", false, "Verbose output")
 	cmd.Flags().BoolP("full", "f", false, "Include repositories from excluded organizations")
+	addFilterFlags(cmd)
 	return cmd
 }
 
@@ -27,56 +26,32 @@ func (a *App) runReadmes(cmd *cobra.Command) error {
 	if err := a.ensureClient(); err != nil {
 		return err
 	}
-	ctx := context.Background()
+	ctx, cancel := a.commandContext(cmd)
+	defer cancel()
+	p := a.progressReporter(cmd)
+	defer p.Done()
 	showFull, _ := cmd.Flags().GetBool("full")
 	verbose, _ := cmd.Flags().GetBool("verbose")
 	w := cmd.OutOrStdout()
-
-	repos, err := ghub.FindReadmeRepos(ctx, a.GHClient, a.Cache, a.MembershipCache, ghub.SearchOptions{
-		ShowFull:  showFull,
-		NoCache:   a.Config.NoCache,
-		DebugMode: a.Config.DebugMode,
-	})
+	f, err := filterFromFlags(cmd)
 	if err != nil {
-		return fmt.Errorf("finding repositories: %w", err)
-	}
-
-	// Merge with additional repos, deduplicating by full name
-	repoMap := make(map[string]ghub.Repo)
-	for _, r := range repos {
-		repoMap[r.FullName()] = r
-	}
-	for _, name := range a.AdditionalRepos {
-		parts := strings.Split(name, "/")
-		if len(parts) == 2 {
-			if _, exists := repoMap[name]; !exists {
-				repoMap[name] = ghub.Repo{Owner: parts[0], Name: parts[1]}
-			}
-		}
-	}
-
-	// Fetch star counts if verbose for all unique repos (including additional)
-	totalStars := 0
-	if verbose {
-		for name, repo := range repoMap {
-			stars, err := ghub.GetStarCount(ctx, a.GHClient, a.Cache, repo.Owner, repo.Name, a.Config.NoCache, a.Config.DebugMode)
-			if err == nil {
-				repo.Stars = stars
-				repoMap[name] = repo
-				totalStars += stars
-			}
-		}
+		return err
 	}
 
-	// Convert to sorted list
-	repoList := make([]ghub.Repo, 0, len(repoMap))
-	for _, r := range repoMap {
-		repoList = append(repoList, r)
+	d, err := a.loadDataset(ctx, showFull, f, p, score.TypeReadme, score.TypeAdditional)
+	if err != nil && !isInterrupted(err) {
+		return fmt.Errorf("finding repositories: %w", err)
 	}
+	repoList := d.ofType(score.TypeReadme, score.TypeAdditional)
 	sort.Slice(repoList, func(i, j int) bool {
 		return repoList[i].FullName() < repoList[j].FullName()
 	})
 
+	totalStars := 0
+	for _, r := range repoList {
+		totalStars += r.Stars
+	}
+
 	// Output summary
 	if verbose {
 		if a.Config.SlackMode {
@@ -103,5 +78,11 @@ func (a *App) runReadmes(cmd *cobra.Command) error {
 		}
 	}
 
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return a.incomplete(w, err)
+	}
 	return nil
 }
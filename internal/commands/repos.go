@@ -1,23 +1,30 @@
 package commands
 
 import (
-	"context"
 	"fmt"
+	"log/slog"
+	"strconv"
 
 	"github.com/spf13/cobra"
 	ghub "github.com/stahnma/gh-flox/internal/github"
+	"github.com/stahnma/gh-flox/internal/score"
 )
 
 func (a *App) newReposCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "repos [flags]",
 		Short: "List repositories with .flox/env/manifest.toml",
+		Long: `List repositories with .flox/env/manifest.toml.
+
+With -v, each repository is listed as owner/repo,stars,environments, where
+environments counts every .flox/env/manifest.toml in the repository.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return a.runRepos(cmd)
 		},
 	}
 	cmd.Flags().BoolP("verbose", "v", false, "Verbose output")
 	cmd.Flags().BoolP("full", "f", false, "Show full list including those made by flox and employees")
+	addFilterFlags(cmd)
 	return cmd
 }
 
@@ -25,31 +32,56 @@ func (a *App) runRepos(cmd *cobra.Command) error {
 	if err := a.ensureClient(); err != nil {
 		return err
 	}
-	ctx := context.Background()
+	ctx, cancel := a.commandContext(cmd)
+	defer cancel()
+	p := a.progressReporter(cmd)
+	defer p.Done()
 	showFull, _ := cmd.Flags().GetBool("full")
 	verbose, _ := cmd.Flags().GetBool("verbose")
 	w := cmd.OutOrStdout()
-
-	repos, err := ghub.FindManifestRepos(ctx, a.GHClient, a.Cache, a.MembershipCache, ghub.SearchOptions{
-		ShowFull:  showFull,
-		NoCache:   a.Config.NoCache,
-		DebugMode: a.Config.DebugMode,
-	})
+	f, err := filterFromFlags(cmd)
 	if err != nil {
+		return err
+	}
+
+	d, err := a.loadDataset(ctx, showFull, f, p, score.TypeDotflox)
+	if err != nil && !isInterrupted(err) {
 		return fmt.Errorf("finding repositories: %w", err)
 	}
+	repos := d.ofType(score.TypeDotflox)
 
 	if verbose {
-		totalStars := 0
+		// Count environments per repository; "?" marks counts that could
+		// not be determined.
+		envCounts := make(map[string]int, len(repos))
+		totalStars, totalEnvs := 0, 0
 		for _, repo := range repos {
 			totalStars += repo.Stars
+			if err != nil {
+				continue
+			}
+			envs, envErr := ghub.FindEnvironments(ctx, a.GHClient, a.Cache, repo.Owner, repo.Name, repo.DefaultBranch, a.Config.NoCache)
+			if isInterrupted(envErr) {
+				err = envErr
+				continue
+			}
+			if envErr != nil {
+				slog.Warn("listing environments failed", "repo", repo.FullName(), "err", envErr)
+				continue
+			}
+			envCounts[repo.FullName()] = len(envs)
+			totalEnvs += len(envs)
 		}
-		fmt.Fprintf(w, "Total unique repositories found: %d, Total stars: %d\n", len(repos), totalStars)
+		fmt.Fprintf(w, "Total unique repositories found: %d, Total stars: %d, Total environments: %d\n", len(repos), totalStars, totalEnvs)
 		if a.Config.SlackMode {
 			fmt.Fprintln(w, "```")
 		}
 		for _, repo := range repos {
-			fmt.Fprintf(w, "%s,%d\n", repo.FullName(), repo.Stars)
+			envs := "?"
+			if n, ok := envCounts[repo.FullName()]; ok {
+				envs = strconv.Itoa(n)
+			}
+			fmt.Fprintf(w, "%s,%d,%s\n", repo.FullName(), repo.Stars, envs)
 		}
 		if a.Config.SlackMode {
 			fmt.Fprintln(w, "```")
@@ -58,5 +90,8 @@ func (a *App) runRepos(cmd *cobra.Command) error {
 		fmt.Fprintf(w, "Total unique repositories found: %d\n", len(repos))
 	}
 
+	if err != nil {
+		return a.incomplete(w, err)
+	}
 	return nil
 }
@@ -2,23 +2,33 @@ package commands
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/stahnma/gh-flox/internal/cache"
 	"github.com/stahnma/gh-flox/internal/config"
 	ghub "github.com/stahnma/gh-flox/internal/github"
+	"github.com/stahnma/gh-flox/internal/logging"
+	"github.com/stahnma/gh-flox/internal/progress"
 )
 
 // App holds shared application state.
 type App struct {
 	Config          config.Config
+	File            config.File
 	Cache           *cache.Cache
 	GHClient        ghub.Client
 	AdditionalRepos []string
 	MembershipCache *ghub.MembershipCache
+	Stats           *ghub.CallStats
 	GitSHA          string
 	GitDirty        string
+
+	showStats  bool
+	noProgress bool
+	timeout    time.Duration
 }
 
 // NewApp creates a new App from the given configuration.
@@ -27,26 +37,42 @@ func NewApp(cfg config.Config, additionalRepos []string, gitSHA, gitDirty string
 	if err != nil {
 		return nil, fmt.Errorf("loading cache: %w", err)
 	}
+	file, err := config.LoadFile(cfg.ConfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading config file: %w", err)
+	}
 
 	return &App{
 		Config:          cfg,
+		File:            file,
 		Cache:           c,
 		AdditionalRepos: additionalRepos,
 		MembershipCache: ghub.NewMembershipCache(),
+		Stats:           ghub.NewCallStats(),
 		GitSHA:          gitSHA,
 		GitDirty:        gitDirty,
 	}, nil
 }
 
-// ensureClient creates the GitHub client if it doesn't exist.
+// ensureClient creates the GitHub client if it doesn't exist and wraps it so
+// that API calls are counted in a.Stats.
 func (a *App) ensureClient() error {
-	if a.GHClient != nil {
-		return nil
+	if a.GHClient == nil {
+		if a.Config.GitHubToken == "" {
+			return fmt.Errorf("GITHUB_TOKEN must be set")
+		}
+		var conditional *cache.Cache
+		if !a.Config.NoCache {
+			conditional = a.Cache
+		}
+		a.GHClient = ghub.NewClient(a.Config.GitHubToken, conditional)
+	}
+	if a.Stats == nil {
+		a.Stats = ghub.NewCallStats()
 	}
-	if a.Config.GitHubToken == "" {
-		return fmt.Errorf("GITHUB_TOKEN must be set")
+	if _, ok := a.GHClient.(*ghub.InstrumentedClient); !ok {
+		a.GHClient = ghub.Instrument(a.GHClient, a.Stats)
 	}
-	a.GHClient = ghub.NewClient(a.Config.GitHubToken)
 	return nil
 }
 
@@ -58,6 +84,31 @@ func (a *App) SaveCache() error {
 	return nil
 }
 
+// WriteStats writes the API call summary to w if --stats was given. It is
+// called once the command has returned, so failed and interrupted runs
+// report their calls too.
+func (a *App) WriteStats(w io.Writer) {
+	if a.showStats && a.Stats != nil {
+		a.Stats.WriteSummary(w)
+	}
+}
+
+// progressReporter returns a reporter writing to the command's stderr, or a
+// no-op reporter in Slack mode, Lambda mode or with --no-progress.
+func (a *App) progressReporter(cmd *cobra.Command) progress.Reporter {
+	if a.noProgress || a.Config.SlackMode || a.Config.LambdaMode {
+		return progress.Nop{}
+	}
+	return progress.New(cmd.ErrOrStderr())
+}
+
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
 // NewRootCommand creates the root cobra command with all subcommands.
 func (a *App) NewRootCommand() *cobra.Command {
 	rootCmd := &cobra.Command{
@@ -68,15 +119,35 @@ func (a *App) NewRootCommand() *cobra.Command {
 	rootCmd.SilenceErrors = true
 	rootCmd.SilenceUsage = true
 	rootCmd.PersistentFlags().BoolVar(&a.Config.NoCache, "no-cache", false, "Disable caching")
+	rootCmd.PersistentFlags().StringVar(&a.Config.LogLevel, "log-level", defaultString(a.Config.LogLevel, "info"), "Log level: debug, info, warn or error")
+	rootCmd.PersistentFlags().StringVar(&a.Config.LogFormat, "log-format", defaultString(a.Config.LogFormat, logging.FormatText), "Log format: text or json")
+	rootCmd.PersistentFlags().DurationVar(&a.timeout, "timeout", 0, "Abort the command after this long, e.g. 10m (0 disables)")
+	rootCmd.PersistentFlags().BoolVar(&a.noProgress, "no-progress", false, "Disable progress reporting on stderr")
+	rootCmd.PersistentFlags().BoolVar(&a.showStats, "stats", false, "Print API call statistics to stderr when done")
+	// Logs always go to stderr so they never mix with command output, which
+	// hubot relays to chat.
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return logging.Setup(cmd.ErrOrStderr(), a.Config.LogLevel, a.Config.LogFormat)
+	}
 
 	rootCmd.AddCommand(a.newReposCommand())
 	rootCmd.AddCommand(a.newStarsCommand())
+	rootCmd.AddCommand(a.newStargazersCommand())
 	rootCmd.AddCommand(a.newReadmesCommand())
 	rootCmd.AddCommand(a.newFloxIndexCommand())
 	rootCmd.AddCommand(a.newVersionCommand())
 	rootCmd.AddCommand(a.newClearCacheCommand())
 	rootCmd.AddCommand(a.newExportCommand())
 	rootCmd.AddCommand(a.newDownloadManifestsCommand())
+	rootCmd.AddCommand(a.newRateLimitCommand())
+	rootCmd.AddCommand(a.newScoreCommand())
+	rootCmd.AddCommand(a.newCompareCommand())
+	rootCmd.AddCommand(a.newAdoptionDateCommand())
+	rootCmd.AddCommand(a.newDiffCommand())
+	rootCmd.AddCommand(a.newHistoryCommand())
+	rootCmd.AddCommand(a.newTrendCommand())
+	rootCmd.AddCommand(a.newDigestCommand())
+	rootCmd.AddCommand(a.newAlertsCommand())
 
 	return rootCmd
 }
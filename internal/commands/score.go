@@ -0,0 +1,207 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	ghub "github.com/stahnma/gh-flox/internal/github"
+	"github.com/stahnma/gh-flox/internal/progress"
+	"github.com/stahnma/gh-flox/internal/score"
+)
+
+func (a *App) newScoreCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "score [flags]",
+		Short: "Calculate the activity-weighted adoption score",
+		Long: `Calculate the activity-weighted adoption score.
+
+Unlike floxindex, which sums stars, each repository contributes a weighted
+sum of log-scaled stars, recent commit activity and contributor count, how
+prominently flox is used, and how recently flox was adopted. Weights default
+to ` + score.DefaultWeights().String() + ` and can be changed in the
+config file or with --weight.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.runScore(cmd)
+		},
+	}
+	cmd.Flags().BoolP("full", "f", false, "Include repositories from excluded organizations")
+	cmd.Flags().Bool("force", false, "Run even if the API rate limit budget looks insufficient")
+	cmd.Flags().StringSlice("weight", nil, "Override a signal weight, e.g. --weight stars=0.5 (repeatable)")
+	cmd.Flags().Int("top", 0, "Only list the N highest-scoring repositories (0 lists all)")
+	addFilterFlags(cmd)
+	return cmd
+}
+
+func (a *App) runScore(cmd *cobra.Command) error {
+	if err := a.ensureClient(); err != nil {
+		return err
+	}
+	ctx, cancel := a.commandContext(cmd)
+	defer cancel()
+	showFull, _ := cmd.Flags().GetBool("full")
+	force, _ := cmd.Flags().GetBool("force")
+	top, _ := cmd.Flags().GetInt("top")
+	w := cmd.OutOrStdout()
+	f, err := filterFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	weights, err := a.scoreWeights(cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := a.checkBudget(ctx, cmd, a.estimateFloxIndexBudget(showFull), force); err != nil {
+		return err
+	}
+
+	p := a.progressReporter(cmd)
+	defer p.Done()
+	now := time.Now()
+	d, err := a.loadDataset(ctx, showFull, f, p, allRecordTypes...)
+	if err != nil && !isInterrupted(err) {
+		return fmt.Errorf("finding repositories: %w", err)
+	}
+	repos := d.unique()
+	res := score.Result{Weights: weights}
+	if err == nil {
+		// Scoring costs several calls per repository, known only now.
+		if budgetErr := a.checkBudget(ctx, cmd, a.estimateScoreBudget(repos), force); budgetErr != nil {
+			return budgetErr
+		}
+		res, err = a.computeScore(ctx, repos, weights, now, p)
+		if err != nil && !isInterrupted(err) {
+			return fmt.Errorf("calculating score: %w", err)
+		}
+	}
+
+	writeScore(w, res, top, a.Config.SlackMode)
+	if err != nil {
+		return a.incomplete(w, err)
+	}
+	return nil
+}
+
+// scoreWeights returns the default weights, overridden by the config file and
+// then by --weight flags.
+func (a *App) scoreWeights(cmd *cobra.Command) (score.Weights, error) {
+	weights := score.DefaultWeights()
+	for name, v := range a.File.Score.Weights {
+		if err := weights.Set(name, v); err != nil {
+			return nil, fmt.Errorf("config file: %w", err)
+		}
+	}
+	overrides, _ := cmd.Flags().GetStringSlice("weight")
+	for _, o := range overrides {
+		name, val, ok := strings.Cut(o, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --weight %q: expected name=value", o)
+		}
+		v, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --weight %q: %w", o, err)
+		}
+		if err := weights.Set(name, v); err != nil {
+			return nil, err
+		}
+	}
+	return weights, nil
+}
+
+// estimateScoreBudget estimates the core API calls computeScore needs for
+// repos, leaving out cached activity and adoption dates. Hand-added
+// repositories may need both a manifest and a README date.
+func (a *App) estimateScoreBudget(repos []typedRepo) ghub.Budget {
+	var b ghub.Budget
+	for _, r := range repos {
+		b.Core += ghub.EstimateActivityCalls(a.Cache, r.Owner, r.Name, a.Config.NoCache)
+		if r.Type != score.TypeReadme {
+			b.Core += ghub.EstimateManifestAdoptionCalls(a.Cache, r.Owner, r.Name, a.Config.NoCache)
+		}
+		if r.Type != score.TypeDotflox {
+			b.Core += ghub.EstimateReadmeAdoptionCalls(a.Cache, r.Owner, r.Name, a.Config.NoCache)
+		}
+	}
+	return b
+}
+
+// computeScore fetches commit activity and adoption dates for repos and
+// scores them. A repository whose activity or adoption date cannot be
+// fetched, such as a deleted or blocked one, is scored without it. If ctx is
+// canceled, the repositories fetched so far are scored and returned with the
+// context's error.
+func (a *App) computeScore(ctx context.Context, repos []typedRepo, weights score.Weights, now time.Time, p progress.Reporter) (score.Result, error) {
+	p = progress.OrNop(p)
+	p.Report(progress.Event{Kind: progress.StageStarted, Stage: "fetching activity", Total: len(repos)})
+	inputs := make([]score.Input, 0, len(repos))
+	for _, r := range repos {
+		activity, err := ghub.GetRepoActivity(ctx, a.GHClient, a.Cache, r.Owner, r.Name, a.Config.NoCache)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return score.Compute(inputs, weights, now), ctxErr
+			}
+			slog.Warn("fetching activity failed", "repo", r.FullName(), "err", err)
+		}
+		adopted, err := a.adoptionDate(ctx, r.Type, r.Owner, r.Name, r.DefaultBranch)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return score.Compute(inputs, weights, now), ctxErr
+			}
+			slog.Warn("finding adoption date failed", "repo", r.FullName(), "err", err)
+		}
+		inputs = append(inputs, score.Input{
+			Repo:          r.FullName(),
+			Type:          r.Type,
+			Stars:         r.Stars,
+			RecentCommits: activity.RecentCommits,
+			Contributors:  activity.Contributors,
+			AdoptedAt:     adopted,
+		})
+		p.Report(progress.Event{Kind: progress.RepoProcessed})
+	}
+	return score.Compute(inputs, weights, now), nil
+}
+
+// writeScore prints the total and the per-repository contribution table,
+// limited to the top entries if top is positive.
+func writeScore(w io.Writer, res score.Result, top int, slackMode bool) {
+	fmt.Fprintf(w, "Adoption score: %.2f (%d repositories)\n", res.Total, len(res.Contributions))
+	fmt.Fprintf(w, "Weights: %s\n", res.Weights)
+	rows := res.Contributions
+	if top > 0 && top < len(rows) {
+		rows = rows[:top]
+	}
+
+	if slackMode {
+		fmt.Fprintln(w, "```")
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := []string{"REPO", "TYPE", "STARS"}
+	for _, s := range score.Signals {
+		header = append(header, strings.ToUpper(s))
+	}
+	fmt.Fprintln(tw, strings.Join(append(header, "SCORE", "SHARE"), "\t"))
+	for _, c := range rows {
+		cells := []string{c.Repo, c.Type, strconv.Itoa(c.Stars)}
+		for _, s := range score.Signals {
+			cells = append(cells, fmt.Sprintf("%.2f", c.Components[s]))
+		}
+		share := 0.0
+		if res.Total > 0 {
+			share = 100 * c.Score / res.Total
+		}
+		cells = append(cells, fmt.Sprintf("%.2f", c.Score), fmt.Sprintf("%.1f%%", share))
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	tw.Flush()
+	if slackMode {
+		fmt.Fprintln(w, "```")
+	}
+}
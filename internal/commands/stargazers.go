@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stahnma/gh-flox/internal/format"
+	ghub "github.com/stahnma/gh-flox/internal/github"
+	"github.com/stahnma/gh-flox/internal/stargazers"
+	"github.com/stahnma/gh-flox/internal/trend"
+)
+
+func (a *App) newStargazersCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stargazers [owner/repo]",
+		Short: "Report new stars per day, week or month and the cumulative star curve",
+		Long: `Report when a repository, by default flox/flox, gained its stars: new stars
+and the cumulative total per day, week or month, from the stargazers API.
+
+Stargazers are cached and only new pages are fetched on later runs, so
+regular runs cost a request or two. With --adopters, the repositories found
+by repos are searched as well and new stargazers owning one of them are
+counted and listed.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.runStargazers(cmd, args)
+		},
+	}
+	cmd.Flags().String("since", "", "First date to report, YYYY-MM-DD (default the first star)")
+	cmd.Flags().String("until", "", "Last date to report, YYYY-MM-DD (default the last star)")
+	cmd.Flags().String("bucket", string(trend.Weekly), "Bucket size: day, week or month")
+	cmd.Flags().Bool("adopters", false, "Break down new stargazers by whether they own a flox-adopting repository")
+	cmd.Flags().BoolP("full", "f", false, "With --adopters, include repositories made by flox and employees")
+	cmd.Flags().String("svg", "", "Also write the new and total stars as an SVG chart to this file")
+	cmd.Flags().Bool("json", false, "Output as JSON")
+	return cmd
+}
+
+func (a *App) runStargazers(cmd *cobra.Command, args []string) error {
+	bucketFlag, _ := cmd.Flags().GetString("bucket")
+	withAdopters, _ := cmd.Flags().GetBool("adopters")
+	showFull, _ := cmd.Flags().GetBool("full")
+	svgPath, _ := cmd.Flags().GetString("svg")
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	w := cmd.OutOrStdout()
+
+	repo := "flox/flox"
+	if len(args) == 1 {
+		repo = args[0]
+	}
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" {
+		return fmt.Errorf("invalid repository %q: expected owner/repo", repo)
+	}
+	bucket, err := trend.ParseBucketing(bucketFlag)
+	if err != nil {
+		return err
+	}
+	since, err := dateFlag(cmd, "since")
+	if err != nil {
+		return err
+	}
+	until, err := dateFlag(cmd, "until")
+	if err != nil {
+		return err
+	}
+	if err := a.ensureClient(); err != nil {
+		return err
+	}
+	ctx, cancel := a.commandContext(cmd)
+	defer cancel()
+	p := a.progressReporter(cmd)
+	defer p.Done()
+
+	stars, err := ghub.GetStargazers(ctx, a.GHClient, a.Cache, owner, name, a.Config.NoCache, p)
+	if err != nil {
+		return err
+	}
+	opts := stargazers.Options{Since: since, Until: until, Bucket: bucket}
+	if withAdopters {
+		repos, err := ghub.FindManifestRepos(ctx, a.GHClient, a.Cache, a.MembershipCache, ghub.SearchOptions{
+			ShowFull: showFull,
+			NoCache:  a.Config.NoCache,
+			Progress: p,
+		})
+		if err != nil {
+			return fmt.Errorf("finding repositories: %w", err)
+		}
+		opts.Adopters = make(map[string]bool, len(repos))
+		for _, r := range repos {
+			opts.Adopters[strings.ToLower(r.Owner)] = true
+		}
+	}
+	report := stargazers.Build(repo, stars, opts)
+
+	if svgPath != "" {
+		f, err := os.Create(svgPath)
+		if err != nil {
+			return err
+		}
+		if err := trend.WriteSVG(f, report.Chart(), repo+" stargazers"); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	if jsonOut {
+		return format.WriteJSON(w, report, a.Config.SlackMode)
+	}
+	stargazers.WriteText(w, report, a.Config.SlackMode)
+	return nil
+}
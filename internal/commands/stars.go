@@ -3,37 +3,157 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/stahnma/gh-flox/internal/format"
 	ghub "github.com/stahnma/gh-flox/internal/github"
+	"github.com/stahnma/gh-flox/internal/history"
 )
 
 func (a *App) newStarsCommand() *cobra.Command {
-	return &cobra.Command{
-		Use:   "stars",
-		Short: "Show star count for the flox/flox repository",
+	cmd := &cobra.Command{
+		Use:   "stars [owner/repo...]",
+		Short: "Show star counts for flox/flox or other repositories",
+		Long: `Show stars, forks and watchers of the given repositories, or of the config
+file's stars.watchlist, or of flox/flox, with the star change over the last
+7 and 30 days from the history store.
+
+In Slack mode a single repository is reported as one line. With --record,
+today's star counts are saved to the history store, so later runs have
+deltas to show.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return a.runStars(cmd)
+			return a.runStars(cmd, args)
 		},
 	}
+	cmd.Flags().String("store", "", "History store file (default $GH_FLOX_HISTORY or history.json in the config directory)")
+	cmd.Flags().Bool("record", false, "Save today's star counts to the history store")
+	cmd.Flags().Bool("json", false, "Output as JSON")
+	return cmd
+}
+
+// starsRow is one repository in the stars output. The deltas are nil when
+// the history store has no star count from that long ago.
+type starsRow struct {
+	Repository string `json:"repository"`
+	Stars      int    `json:"stars"`
+	Forks      int    `json:"forks"`
+	Watchers   int    `json:"watchers"`
+	Delta7     *int   `json:"delta_7d"`
+	Delta30    *int   `json:"delta_30d"`
 }
 
-func (a *App) runStars(cmd *cobra.Command) error {
+func (a *App) runStars(cmd *cobra.Command, args []string) error {
+	record, _ := cmd.Flags().GetBool("record")
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	w := cmd.OutOrStdout()
+
+	repos := args
+	if len(repos) == 0 {
+		repos = a.File.Stars.Watchlist
+	}
+	if len(repos) == 0 {
+		repos = []string{"flox/flox"}
+	}
+	for _, repo := range repos {
+		if owner, name, ok := strings.Cut(repo, "/"); !ok || owner == "" || name == "" {
+			return fmt.Errorf("invalid repository %q: expected owner/repo", repo)
+		}
+	}
 	if err := a.ensureClient(); err != nil {
 		return err
 	}
-	ctx := context.Background()
-	w := cmd.OutOrStdout()
-
-	stars, err := ghub.GetStarCount(ctx, a.GHClient, a.Cache, "flox", "flox", a.Config.NoCache, a.Config.DebugMode)
+	path := a.historyPath(cmd)
+	store, err := history.Load(path)
 	if err != nil {
-		return fmt.Errorf("retrieving star count: %w", err)
+		return fmt.Errorf("loading history: %w", err)
+	}
+	ctx, cancel := a.commandContext(cmd)
+	defer cancel()
+
+	now := time.Now()
+	rows := make([]starsRow, 0, len(repos))
+	for _, repo := range repos {
+		owner, name, _ := strings.Cut(repo, "/")
+		meta, err := ghub.GetRepoMetadata(ctx, a.GHClient, a.Cache, owner, name, a.Config.NoCache)
+		if err != nil {
+			return fmt.Errorf("retrieving star count of %s: %w", repo, err)
+		}
+		rows = append(rows, starsRow{
+			Repository: repo,
+			Stars:      meta.Stars,
+			Forks:      meta.Forks,
+			Watchers:   meta.Watchers,
+			Delta7:     starsDelta(store, repo, meta.Stars, now, 7),
+			Delta30:    starsDelta(store, repo, meta.Stars, now, 30),
+		})
 	}
 
-	if a.Config.SlackMode {
-		fmt.Fprintf(w, "The repository :star2: `flox/flox` has %d stars :star2:.\n", stars)
-	} else {
-		fmt.Fprintf(w, "The repository flox/flox has %d stars \n", stars)
+	if record {
+		imp := &history.Import{Date: now.Format(history.DateLayout), Source: "gh-flox stars", Stars: make(map[string]int)}
+		for _, r := range rows {
+			imp.Stars[r.Repository] = r.Stars
+		}
+		store.Apply(imp)
+		if err := store.Save(path); err != nil {
+			return fmt.Errorf("saving history: %w", err)
+		}
+	}
+
+	switch {
+	case jsonOut:
+		return format.WriteJSON(w, rows, a.Config.SlackMode)
+	case a.Config.SlackMode && len(rows) == 1:
+		fmt.Fprintf(w, "The repository :star2: `%s` has %d stars :star2:.\n", rows[0].Repository, rows[0].Stars)
+	case a.Config.SlackMode:
+		fmt.Fprintln(w, "```")
+		writeStarsTable(w, rows)
+		fmt.Fprintln(w, "```")
+	default:
+		writeStarsTable(w, rows)
 	}
 	return nil
 }
+
+// starsDelta returns the change from repo's latest star count recorded at
+// least days before now, or nil if there is none.
+func starsDelta(store *history.Store, repo string, stars int, now time.Time, days int) *int {
+	before, ok := store.StarsOn(repo, now.AddDate(0, 0, -days).Format(history.DateLayout))
+	if !ok {
+		return nil
+	}
+	delta := stars - before
+	return &delta
+}
+
+func writeStarsTable(w io.Writer, rows []starsRow) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPOSITORY\tSTARS\tFORKS\tWATCHERS\t7D\t30D")
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\t%s\n", r.Repository, r.Stars, r.Forks, r.Watchers, deltaText(r.Delta7), deltaText(r.Delta30))
+	}
+	tw.Flush()
+}
+
+// deltaText formats a delta with its sign, or "-" if unknown.
+func deltaText(d *int) string {
+	if d == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%+d", *d)
+}
+
+// StarCount returns the star count of owner/repo.
+func (a *App) StarCount(ctx context.Context, owner, repo string) (int, error) {
+	if err := a.ensureClient(); err != nil {
+		return 0, err
+	}
+	stars, err := ghub.GetStarCount(ctx, a.GHClient, a.Cache, owner, repo, a.Config.NoCache)
+	if err != nil {
+		return 0, fmt.Errorf("retrieving star count: %w", err)
+	}
+	return stars, nil
+}
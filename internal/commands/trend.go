@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stahnma/gh-flox/internal/format"
+	"github.com/stahnma/gh-flox/internal/history"
+	"github.com/stahnma/gh-flox/internal/trend"
+)
+
+func (a *App) newTrendCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trend",
+		Short: "Chart repository counts, floxindex and stars over time",
+		Long: `Chart repository counts per type, the floxindex and flox/flox stars over
+time from the history store (see history import).
+
+The chart is printed as sparklines, one per series, and with --svg also
+written as a standalone SVG file. Snapshots are grouped into day, week or
+month buckets, keeping the latest value in each. Dates where a type's
+counting changed, such as from legacy text output to exports or between
+export scopes, are marked with ^, as are the annotations in the config
+file's trend section.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.runTrend(cmd)
+		},
+	}
+	cmd.Flags().String("store", "", "History store file (default $GH_FLOX_HISTORY or history.json in the config directory)")
+	cmd.Flags().String("since", "", "First date to chart, YYYY-MM-DD")
+	cmd.Flags().String("until", "", "Last date to chart, YYYY-MM-DD")
+	cmd.Flags().String("bucket", string(trend.Daily), "Bucket size: day, week or month")
+	cmd.Flags().StringSlice("series", trend.DefaultSeries, "Series to chart: dotflox, readme, additional, floxindex or owner/repo for its stars")
+	cmd.Flags().String("svg", "", "Also write the chart as SVG to this file")
+	cmd.Flags().Bool("json", false, "Write the chart data as JSON instead of sparklines")
+	return cmd
+}
+
+func (a *App) runTrend(cmd *cobra.Command) error {
+	bucketFlag, _ := cmd.Flags().GetString("bucket")
+	series, _ := cmd.Flags().GetStringSlice("series")
+	svgPath, _ := cmd.Flags().GetString("svg")
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	w := cmd.OutOrStdout()
+
+	bucket, err := trend.ParseBucketing(bucketFlag)
+	if err != nil {
+		return err
+	}
+	since, err := dateFlag(cmd, "since")
+	if err != nil {
+		return err
+	}
+	until, err := dateFlag(cmd, "until")
+	if err != nil {
+		return err
+	}
+	notes, err := a.trendNotes()
+	if err != nil {
+		return err
+	}
+	store, err := history.Load(a.historyPath(cmd))
+	if err != nil {
+		return fmt.Errorf("loading history: %w", err)
+	}
+
+	chart, err := trend.Build(store, trend.Options{Since: since, Until: until, Bucket: bucket, Series: series, Notes: notes})
+	if err != nil {
+		return err
+	}
+	if svgPath != "" {
+		f, err := os.Create(svgPath)
+		if err != nil {
+			return err
+		}
+		if err := trend.WriteSVG(f, chart, "gh-flox trends"); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	if jsonOut {
+		return format.WriteJSON(w, chart, a.Config.SlackMode)
+	}
+	trend.WriteText(w, chart, a.Config.SlackMode)
+	return nil
+}
+
+// dateFlag parses a YYYY-MM-DD flag; empty yields the zero time.
+func dateFlag(cmd *cobra.Command, name string) (time.Time, error) {
+	s, _ := cmd.Flags().GetString(name)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(history.DateLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --%s %q: use YYYY-MM-DD", name, s)
+	}
+	return t, nil
+}
+
+// trendNotes returns the annotations from the config file.
+func (a *App) trendNotes() ([]trend.Annotation, error) {
+	var notes []trend.Annotation
+	for _, n := range a.File.Trend.Annotations {
+		date, err := time.Parse(history.DateLayout, n.Date)
+		if err != nil {
+			return nil, fmt.Errorf("config file: invalid trend annotation date %q: use YYYY-MM-DD", n.Date)
+		}
+		notes = append(notes, trend.Annotation{Date: date, Note: n.Note})
+	}
+	return notes, nil
+}
@@ -0,0 +1,131 @@
+// Package compare sets flox adoption next to other environment managers',
+// found with equivalent code searches: repository counts, star sums and the
+// repositories using both.
+package compare
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	ghub "github.com/stahnma/gh-flox/internal/github"
+)
+
+// Flox is the name of flox's row.
+const Flox = "flox"
+
+// Tool is an environment manager compared with flox. A repository uses it if
+// any of its signatures finds the repository.
+type Tool struct {
+	Name       string
+	Signatures []ghub.Signature
+}
+
+// DefaultTools returns the tools compared when none are configured: devbox,
+// devenv and nix flakes. A repository counts for nix flakes if it has a
+// flake.nix anywhere, whether or not it defines a development shell, or a
+// README mentioning nix develop.
+func DefaultTools() []Tool {
+	return []Tool{
+		{Name: "devbox", Signatures: []ghub.Signature{{Name: "devbox search", Path: "devbox.json"}}},
+		{Name: "devenv", Signatures: []ghub.Signature{{Name: "devenv search", Path: "devenv.nix"}}},
+		{Name: "nix flakes", Signatures: []ghub.Signature{
+			{Name: "flake search", Path: "flake.nix"},
+			{Name: "nix develop readme search", Phrase: "nix develop"},
+		}},
+	}
+}
+
+// Validate checks that t has a name and valid signatures.
+func (t Tool) Validate() error {
+	if t.Name == "" {
+		return errors.New("tool without a name")
+	}
+	if len(t.Signatures) == 0 {
+		return fmt.Errorf("tool %s: no searches", t.Name)
+	}
+	for _, s := range t.Signatures {
+		if err := s.Validate(); err != nil {
+			return fmt.Errorf("tool %s: %w", t.Name, err)
+		}
+	}
+	return nil
+}
+
+// Found is the repositories a tool's searches found, duplicates included.
+type Found struct {
+	Tool  string
+	Repos []ghub.Repo
+}
+
+// Build summarizes flox's repositories and each tool's, flox first. Every
+// repository counts once per tool, however many searches found it.
+func Build(flox []ghub.Repo, tools []Found) []ghub.ToolAdoption {
+	floxRepos := unique(flox)
+	usesFlox := make(map[string]bool, len(floxRepos))
+	for _, r := range floxRepos {
+		usesFlox[r.FullName()] = true
+	}
+	rows := []ghub.ToolAdoption{summarize(Flox, floxRepos, nil)}
+	for _, t := range tools {
+		rows = append(rows, summarize(t.Tool, unique(t.Repos), usesFlox))
+	}
+	return rows
+}
+
+func summarize(tool string, repos []ghub.Repo, usesFlox map[string]bool) ghub.ToolAdoption {
+	row := ghub.ToolAdoption{Tool: tool, Repositories: len(repos)}
+	for _, r := range repos {
+		row.Stars += r.Stars
+		if usesFlox[r.FullName()] {
+			row.Overlap++
+			row.OverlapRepositories = append(row.OverlapRepositories, r.FullName())
+		}
+	}
+	sort.Strings(row.OverlapRepositories)
+	return row
+}
+
+func unique(repos []ghub.Repo) []ghub.Repo {
+	seen := make(map[string]bool, len(repos))
+	var out []ghub.Repo
+	for _, r := range repos {
+		if !seen[r.FullName()] {
+			seen[r.FullName()] = true
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// WriteText prints the rows as a table. With verbose, the repositories using
+// both flox and another tool are listed after it. In Slack mode the table is
+// wrapped in a code block so it stays aligned.
+func WriteText(w io.Writer, rows []ghub.ToolAdoption, verbose, slack bool) {
+	if slack {
+		fmt.Fprintln(w, "```")
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TOOL\tREPOS\tSTARS\tALSO FLOX")
+	for _, r := range rows {
+		overlap := "-"
+		if r.Tool != Flox {
+			overlap = fmt.Sprint(r.Overlap)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", r.Tool, r.Repositories, r.Stars, overlap)
+	}
+	tw.Flush()
+	if slack {
+		fmt.Fprintln(w, "```")
+	}
+	if !verbose {
+		return
+	}
+	for _, r := range rows {
+		for _, repo := range r.OverlapRepositories {
+			fmt.Fprintf(w, "%s,%s\n", r.Tool, repo)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package compare
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	ghub "github.com/stahnma/gh-flox/internal/github"
+)
+
+func repo(fullName string, stars int) ghub.Repo {
+	owner, name, _ := strings.Cut(fullName, "/")
+	return ghub.Repo{Owner: owner, Name: name, RepoMetadata: ghub.RepoMetadata{Stars: stars}}
+}
+
+func TestBuild(t *testing.T) {
+	flox := []ghub.Repo{repo("alice/app", 10), repo("bob/tool", 5), repo("alice/app", 10)}
+	rows := Build(flox, []Found{
+		{Tool: "devbox", Repos: []ghub.Repo{repo("carol/web", 100), repo("bob/tool", 5)}},
+		{Tool: "nix flakes", Repos: []ghub.Repo{repo("alice/app", 10), repo("dave/os", 7), repo("alice/app", 10)}},
+	})
+	want := []ghub.ToolAdoption{
+		{Tool: Flox, Repositories: 2, Stars: 15},
+		{Tool: "devbox", Repositories: 2, Stars: 105, Overlap: 1, OverlapRepositories: []string{"bob/tool"}},
+		{Tool: "nix flakes", Repositories: 2, Stars: 17, Overlap: 1, OverlapRepositories: []string{"alice/app"}},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got %+v\nwant %+v", rows, want)
+	}
+
+	var buf bytes.Buffer
+	WriteText(&buf, rows, true, false)
+	wantText := `TOOL        REPOS  STARS  ALSO FLOX
+flox        2      15     -
+devbox      2      105    1
+nix flakes  2      17     1
+devbox,bob/tool
+nix flakes,alice/app
+`
+	if buf.String() != wantText {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), wantText)
+	}
+}
+
+func TestDefaultTools(t *testing.T) {
+	for _, tool := range DefaultTools() {
+		if err := tool.Validate(); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := (Tool{Name: "empty"}).Validate(); err == nil {
+		t.Error("expected a tool without searches to be invalid")
+	}
+}
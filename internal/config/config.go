@@ -10,9 +10,15 @@ import (
 type Config struct {
 	GitHubToken string
 	SlackMode   bool
-	DebugMode   bool
+	LogLevel    string
+	LogFormat   string
 	CacheFile   string
 	NoCache     bool
+	LambdaMode  bool
+	// ConfigFile is the path of the optional JSON config file.
+	ConfigFile string
+	// HistoryFile is the path of the history store.
+	HistoryFile string
 }
 
 // FromEnvironment creates a Config from environment variables.
@@ -20,18 +26,48 @@ func FromEnvironment() Config {
 	slack := os.Getenv("SLACK_MODE")
 	slackMode := slack != "" && strings.ToLower(slack) != "false" && slack != "0"
 
-	debug := os.Getenv("DEBUG")
-	debugMode := debug != "" && debug != "0" && strings.ToLower(debug) != "false"
+	lambdaMode := os.Getenv("LAMBDA_TASK_ROOT") != ""
+
+	logLevel := os.Getenv("LOG_LEVEL")
+	if logLevel == "" {
+		logLevel = "info"
+		debug := os.Getenv("DEBUG")
+		if debug != "" && debug != "0" && strings.ToLower(debug) != "false" {
+			logLevel = "debug"
+		}
+	}
+
+	logFormat := os.Getenv("LOG_FORMAT")
+	if logFormat == "" {
+		logFormat = "text"
+		if lambdaMode {
+			logFormat = "json"
+		}
+	}
 
 	cacheFile := "/tmp/cache.gob"
 	if dir, err := os.UserCacheDir(); err == nil {
 		cacheFile = filepath.Join(dir, "gh-flox", "cache.gob")
 	}
 
+	configFile := os.Getenv("GH_FLOX_CONFIG")
+	if configFile == "" {
+		configFile = DefaultConfigFile()
+	}
+
+	historyFile := os.Getenv("GH_FLOX_HISTORY")
+	if historyFile == "" {
+		historyFile = DefaultHistoryFile()
+	}
+
 	return Config{
 		GitHubToken: os.Getenv("GITHUB_TOKEN"),
 		SlackMode:   slackMode,
-		DebugMode:   debugMode,
+		LogLevel:    logLevel,
+		LogFormat:   logFormat,
 		CacheFile:   cacheFile,
+		LambdaMode:  lambdaMode,
+		ConfigFile:  configFile,
+		HistoryFile: historyFile,
 	}
 }
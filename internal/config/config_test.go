@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -9,6 +10,9 @@ func TestFromEnvironment_Defaults(t *testing.T) {
 	os.Unsetenv("GITHUB_TOKEN")
 	os.Unsetenv("SLACK_MODE")
 	os.Unsetenv("DEBUG")
+	os.Unsetenv("LOG_LEVEL")
+	os.Unsetenv("LOG_FORMAT")
+	os.Unsetenv("LAMBDA_TASK_ROOT")
 
 	cfg := FromEnvironment()
 	if cfg.GitHubToken != "" {
@@ -17,8 +21,11 @@ func TestFromEnvironment_Defaults(t *testing.T) {
 	if cfg.SlackMode {
 		t.Error("expected SlackMode false by default")
 	}
-	if cfg.DebugMode {
-		t.Error("expected DebugMode false by default")
+	if cfg.LogLevel != "info" {
+		t.Errorf("expected LogLevel info by default, got %q", cfg.LogLevel)
+	}
+	if cfg.LogFormat != "text" {
+		t.Errorf("expected LogFormat text by default, got %q", cfg.LogFormat)
 	}
 	if cfg.CacheFile == "" {
 		t.Error("expected non-empty CacheFile")
@@ -59,25 +66,88 @@ func TestFromEnvironment_SlackMode(t *testing.T) {
 	}
 }
 
-func TestFromEnvironment_DebugMode(t *testing.T) {
+func TestFromEnvironment_DebugLogLevel(t *testing.T) {
 	tests := []struct {
 		val  string
-		want bool
+		want string
 	}{
-		{"true", true},
-		{"1", true},
-		{"yes", true},
-		{"false", false},
-		{"0", false},
-		{"", false},
+		{"true", "debug"},
+		{"1", "debug"},
+		{"yes", "debug"},
+		{"false", "info"},
+		{"0", "info"},
+		{"", "info"},
 	}
 	for _, tt := range tests {
 		t.Run("DEBUG="+tt.val, func(t *testing.T) {
+			t.Setenv("LOG_LEVEL", "")
 			t.Setenv("DEBUG", tt.val)
 			cfg := FromEnvironment()
-			if cfg.DebugMode != tt.want {
-				t.Errorf("DEBUG=%q → DebugMode=%v, want %v", tt.val, cfg.DebugMode, tt.want)
+			if cfg.LogLevel != tt.want {
+				t.Errorf("DEBUG=%q → LogLevel=%q, want %q", tt.val, cfg.LogLevel, tt.want)
 			}
 		})
 	}
 }
+
+func TestFromEnvironment_LogSettings(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("DEBUG", "1")
+	t.Setenv("LOG_FORMAT", "")
+	t.Setenv("LAMBDA_TASK_ROOT", "/var/task")
+
+	cfg := FromEnvironment()
+	if cfg.LogLevel != "warn" {
+		t.Errorf("LOG_LEVEL should override DEBUG, got %q", cfg.LogLevel)
+	}
+	if cfg.LogFormat != "json" {
+		t.Errorf("expected json logs in Lambda mode, got %q", cfg.LogFormat)
+	}
+	if !cfg.LambdaMode {
+		t.Error("expected LambdaMode when LAMBDA_TASK_ROOT is set")
+	}
+}
+
+func TestFromEnvironment_ConfigFile(t *testing.T) {
+	t.Setenv("GH_FLOX_CONFIG", "/etc/gh-flox.json")
+
+	cfg := FromEnvironment()
+	if cfg.ConfigFile != "/etc/gh-flox.json" {
+		t.Errorf("got %q, want /etc/gh-flox.json", cfg.ConfigFile)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"score": {"weights": {"stars": 0.5}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Score.Weights["stars"] != 0.5 {
+		t.Errorf("stars weight = %v, want 0.5", f.Score.Weights["stars"])
+	}
+}
+
+func TestLoadFile_Missing(t *testing.T) {
+	f, err := LoadFile(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("missing file should not be an error: %v", err)
+	}
+	if f.Score.Weights != nil {
+		t.Errorf("expected zero File, got %+v", f)
+	}
+}
+
+func TestLoadFile_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
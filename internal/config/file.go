@@ -0,0 +1,145 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// File holds settings read from the optional JSON config file, for options
+// that are too structured for environment variables.
+type File struct {
+	Score   ScoreConfig   `json:"score"`
+	Trend   TrendConfig   `json:"trend"`
+	Alerts  AlertsConfig  `json:"alerts"`
+	Stars   StarsConfig   `json:"stars"`
+	Compare CompareConfig `json:"compare"`
+}
+
+// ScoreConfig configures the adoption score.
+type ScoreConfig struct {
+	// Weights overrides the default weight of each named signal.
+	Weights map[string]float64 `json:"weights,omitempty"`
+}
+
+// TrendConfig configures trend charts.
+type TrendConfig struct {
+	// Annotations mark dates on every chart, such as changes in how
+	// repositories were counted that the history does not show.
+	Annotations []TrendAnnotation `json:"annotations,omitempty"`
+}
+
+// TrendAnnotation is a note on a date, YYYY-MM-DD.
+type TrendAnnotation struct {
+	Date string `json:"date"`
+	Note string `json:"note"`
+}
+
+// StarsConfig configures the stars command.
+type StarsConfig struct {
+	// Watchlist is the owner/repo list shown when no repository is given.
+	Watchlist []string `json:"watchlist,omitempty"`
+}
+
+// CompareConfig configures the compare command.
+type CompareConfig struct {
+	// Tools replaces the default devbox, devenv and nix flakes comparison.
+	Tools []CompareTool `json:"tools,omitempty"`
+}
+
+// CompareTool is an environment manager compared with flox. A repository
+// uses it if any of its searches finds the repository.
+type CompareTool struct {
+	Name     string          `json:"name"`
+	Searches []CompareSearch `json:"searches"`
+}
+
+// CompareSearch is one code search: a raw query, a file path or a README
+// phrase.
+type CompareSearch struct {
+	Query  string `json:"query,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Phrase string `json:"phrase,omitempty"`
+}
+
+// AlertsConfig configures the alerts command.
+type AlertsConfig struct {
+	// State is the file recording the alerts already sent, by default
+	// DefaultAlertStateFile.
+	State        string             `json:"state,omitempty"`
+	Rules        []AlertRule        `json:"rules,omitempty"`
+	Destinations []AlertDestination `json:"destinations,omitempty"`
+}
+
+// AlertRule is one alert condition; which fields apply depends on Type.
+type AlertRule struct {
+	Name       string   `json:"name,omitempty"`
+	Type       string   `json:"type"`
+	MinStars   int      `json:"min_stars,omitempty"`
+	Repo       string   `json:"repo,omitempty"`
+	Every      int      `json:"every,omitempty"`
+	Milestones []int    `json:"milestones,omitempty"`
+	Percent    float64  `json:"percent,omitempty"`
+	Series     string   `json:"series,omitempty"`
+	Repos      []string `json:"repos,omitempty"`
+}
+
+// AlertDestination is where alerts are sent: "slack" or "webhook" with a
+// URL, or "stdout".
+type AlertDestination struct {
+	Type string `json:"type"`
+	URL  string `json:"url,omitempty"`
+}
+
+// DefaultConfigFile returns the config file path used when GH_FLOX_CONFIG is
+// not set.
+func DefaultConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gh-flox", "config.json")
+}
+
+// DefaultHistoryFile returns the history store path used when
+// GH_FLOX_HISTORY is not set: history.json next to the config file.
+func DefaultHistoryFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "history.json"
+	}
+	return filepath.Join(dir, "gh-flox", "history.json")
+}
+
+// DefaultAlertStateFile returns the alert state path used when the config
+// file does not set one: alerts-state.json next to the config file.
+func DefaultAlertStateFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "alerts-state.json"
+	}
+	return filepath.Join(dir, "gh-flox", "alerts-state.json")
+}
+
+// LoadFile reads the config file at path. A missing file or empty path yields
+// a zero File.
+func LoadFile(path string) (File, error) {
+	var f File
+	if path == "" {
+		return f, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return f, err
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return f, nil
+}
@@ -0,0 +1,202 @@
+// Package digest summarizes a period of the history store into a report of
+// star, floxindex and adopter changes, rendered as Markdown, Slack or HTML.
+package digest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/stahnma/gh-flox/internal/history"
+)
+
+// Options configures Build.
+type Options struct {
+	// Date is the end of the period: the latest snapshot on or before it is
+	// the current one. Zero means the latest snapshot.
+	Date time.Time
+	// Days is the length of the period, 7 if zero. The previous snapshot is
+	// the latest one at least Days before the current one.
+	Days int
+	// Top is the number of repositories in the top lists, 5 if zero.
+	Top int
+	// Repo is the repository whose stars are reported, flox/flox if empty.
+	Repo string
+}
+
+// Change is a value at the start and end of the period.
+type Change struct {
+	Before int `json:"before"`
+	After  int `json:"after"`
+	Delta  int `json:"delta"`
+}
+
+func newChange(before, after int) *Change {
+	return &Change{Before: before, After: after, Delta: after - before}
+}
+
+// TypeChange is the change in repositories of one type. Count and Stars are
+// nil if the two snapshots counted repositories differently, such as text
+// output against an export.
+type TypeChange struct {
+	Type  string  `json:"type"`
+	Count *Change `json:"count"`
+	Stars *Change `json:"stars,omitempty"`
+}
+
+// Adopter is a repository that uses flox.
+type Adopter struct {
+	Repository string `json:"repository"`
+	Type       string `json:"type"`
+	Stars      int    `json:"stars"`
+	// Delta is the star change over the period, for star gainers.
+	Delta int    `json:"delta,omitempty"`
+	URL   string `json:"url"`
+}
+
+// Digest is the summary of one period.
+type Digest struct {
+	From      string       `json:"from"`
+	To        string       `json:"to"`
+	Repo      string       `json:"repo"`
+	Stars     *Change      `json:"stars,omitempty"`
+	FloxIndex *Change      `json:"floxindex,omitempty"`
+	Types     []TypeChange `json:"types"`
+	// AdoptersKnown is false when either snapshot lacks repository lists,
+	// as legacy outputs without -v do; the adopter lists are then empty.
+	AdoptersKnown bool      `json:"adopters_known"`
+	NewAdopters   []Adopter `json:"new_adopters"`
+	LostAdopters  []Adopter `json:"lost_adopters"`
+	// TopNew are the new adopters with the most stars.
+	TopNew []Adopter `json:"top_new"`
+	// Gainers are the manifest repositories that gained the most stars.
+	Gainers []Adopter `json:"gainers"`
+}
+
+// Build summarizes the period of s selected by o.
+func Build(s *history.Store, o Options) (*Digest, error) {
+	days := o.Days
+	if days == 0 {
+		days = 7
+	}
+	top := o.Top
+	if top == 0 {
+		top = 5
+	}
+	repo := o.Repo
+	if repo == "" {
+		repo = "flox/flox"
+	}
+
+	cur := latest(s, o.Date)
+	if cur == nil {
+		return nil, errors.New("no history to summarize, import some with history import")
+	}
+	curDate, err := time.Parse(history.DateLayout, cur.Date)
+	if err != nil {
+		return nil, err
+	}
+	prev := latest(s, curDate.AddDate(0, 0, -days))
+	if prev == nil {
+		return nil, fmt.Errorf("no history from %d days before %s", days, cur.Date)
+	}
+
+	d := &Digest{From: prev.Date, To: cur.Date, Repo: repo}
+	if after, ok := cur.RepoStars(repo); ok {
+		if before, ok := prev.RepoStars(repo); ok {
+			d.Stars = newChange(before, after)
+		}
+	}
+	if cur.FloxIndex != nil && prev.FloxIndex != nil {
+		d.FloxIndex = newChange(*prev.FloxIndex, *cur.FloxIndex)
+	}
+	for _, t := range history.AdopterTypes {
+		before, okBefore := prev.Types[t]
+		after, okAfter := cur.Types[t]
+		if !okBefore || !okAfter {
+			continue
+		}
+		tc := TypeChange{Type: t}
+		if before.Origin != after.Origin || before.Scope != after.Scope {
+			d.Types = append(d.Types, tc)
+			continue
+		}
+		tc.Count = newChange(before.Count, after.Count)
+		if before.Stars != nil && after.Stars != nil {
+			tc.Stars = newChange(*before.Stars, *after.Stars)
+		}
+		d.Types = append(d.Types, tc)
+	}
+
+	prevAdopters, okPrev := adopters(prev)
+	curAdopters, okCur := adopters(cur)
+	d.AdoptersKnown = okPrev && okCur
+	d.NewAdopters, d.LostAdopters, d.TopNew, d.Gainers = []Adopter{}, []Adopter{}, []Adopter{}, []Adopter{}
+	if !d.AdoptersKnown {
+		return d, nil
+	}
+	for name, a := range curAdopters {
+		p, ok := prevAdopters[name]
+		if !ok {
+			d.NewAdopters = append(d.NewAdopters, a)
+			continue
+		}
+		if a.Type == history.TypeDotflox && a.Stars > p.Stars {
+			a.Delta = a.Stars - p.Stars
+			d.Gainers = append(d.Gainers, a)
+		}
+	}
+	for name, a := range prevAdopters {
+		if _, ok := curAdopters[name]; !ok {
+			d.LostAdopters = append(d.LostAdopters, a)
+		}
+	}
+	byName(d.NewAdopters)
+	byName(d.LostAdopters)
+
+	d.TopNew = append(d.TopNew, d.NewAdopters...)
+	sort.SliceStable(d.TopNew, func(i, j int) bool { return d.TopNew[i].Stars > d.TopNew[j].Stars })
+	d.TopNew = d.TopNew[:min(top, len(d.TopNew))]
+	byName(d.Gainers)
+	sort.SliceStable(d.Gainers, func(i, j int) bool { return d.Gainers[i].Delta > d.Gainers[j].Delta })
+	d.Gainers = d.Gainers[:min(top, len(d.Gainers))]
+	return d, nil
+}
+
+// latest returns the latest snapshot with repository data on or before
+// date, or the latest one if date is zero.
+func latest(s *history.Store, date time.Time) *history.Snapshot {
+	for i := len(s.Snapshots) - 1; i >= 0; i-- {
+		snap := s.Snapshots[i]
+		if len(snap.Types) == 0 {
+			continue
+		}
+		if date.IsZero() || snap.Date <= date.Format(history.DateLayout) {
+			return snap
+		}
+	}
+	return nil
+}
+
+// adopters returns the in-scope repositories of snap by name, or false if
+// their lists are not known.
+func adopters(snap *history.Snapshot) (map[string]Adopter, bool) {
+	repos, ok := snap.Adopters()
+	if !ok {
+		return nil, false
+	}
+	out := make(map[string]Adopter, len(repos))
+	for name, r := range repos {
+		a := Adopter{Repository: name, Type: r.Type, URL: "https://github.com/" + name}
+		if r.Stars != nil {
+			a.Stars = *r.Stars
+		}
+		out[name] = a
+	}
+	return out, true
+}
+
+func byName(adopters []Adopter) {
+	sort.Slice(adopters, func(i, j int) bool { return adopters[i].Repository < adopters[j].Repository })
+}
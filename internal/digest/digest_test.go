@@ -0,0 +1,157 @@
+package digest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stahnma/gh-flox/internal/history"
+)
+
+func testStore(t *testing.T) *history.Store {
+	t.Helper()
+	s := &history.Store{Version: history.StoreVersion}
+	sources := map[string]string{
+		"2024-03-01": `{"schema_version":3,"generated_at":"2024-03-01T00:00:00Z","scope":"all","repositories":[
+			{"repository":"a/keep","type":"dotflox","starcount":10,"classification":"external"},
+			{"repository":"a/gone","type":"dotflox","starcount":5,"classification":"external"},
+			{"repository":"flox/own","type":"dotflox","starcount":50,"classification":"flox-org"}]}`,
+		"2024-03-01 stars":     "The repository flox/flox has 100 stars\nTotal floxindex (sum of stars): 15\n",
+		"2024-03-05 text only": "Total unique repositories found: 4\n",
+		"2024-03-08": `{"schema_version":3,"generated_at":"2024-03-08T00:00:00Z","scope":"all","repositories":[
+			{"repository":"a/keep","type":"dotflox","starcount":14,"classification":"external"},
+			{"repository":"b/new","type":"dotflox","starcount":3,"classification":"external"},
+			{"repository":"c/big","type":"readme","starcount":30,"classification":"external"},
+			{"repository":"flox/new","type":"dotflox","starcount":1,"classification":"flox-org"}]}`,
+		"2024-03-08 stars": "The repository flox/flox has 120 stars\nTotal floxindex (sum of stars): 47\n",
+	}
+	for name, text := range sources {
+		imp, err := history.Parse(name, []byte(text), name[:10])
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Apply(imp)
+	}
+	return s
+}
+
+func TestBuild(t *testing.T) {
+	d, err := Build(testStore(t), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.From != "2024-03-01" || d.To != "2024-03-08" {
+		t.Errorf("period = %s to %s", d.From, d.To)
+	}
+	if *d.Stars != (Change{Before: 100, After: 120, Delta: 20}) || d.FloxIndex.Delta != 32 {
+		t.Errorf("stars = %+v, floxindex = %+v", d.Stars, d.FloxIndex)
+	}
+	if len(d.Types) != 1 || d.Types[0].Type != history.TypeDotflox || d.Types[0].Count.Delta != 0 {
+		t.Errorf("types = %+v", d.Types)
+	}
+
+	names := func(adopters []Adopter) string {
+		var s []string
+		for _, a := range adopters {
+			s = append(s, a.Repository)
+		}
+		return strings.Join(s, ",")
+	}
+	if !d.AdoptersKnown {
+		t.Fatal("expected adopter lists")
+	}
+	if got := names(d.NewAdopters); got != "b/new,c/big" {
+		t.Errorf("new adopters = %s; flox-owned repositories are left out", got)
+	}
+	if got := names(d.LostAdopters); got != "a/gone" {
+		t.Errorf("lost adopters = %s", got)
+	}
+	if got := names(d.TopNew); got != "c/big,b/new" {
+		t.Errorf("top new = %s, want by stars", got)
+	}
+	if len(d.Gainers) != 1 || d.Gainers[0].Delta != 4 || d.Gainers[0].URL != "https://github.com/a/keep" {
+		t.Errorf("gainers = %+v", d.Gainers)
+	}
+}
+
+func TestBuild_DifferentOrigins(t *testing.T) {
+	s := testStore(t)
+	imp, err := history.Parse("2024-03-09", []byte("Total unique repositories found: 9\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Apply(imp)
+	d, err := Build(s, Options{Days: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Types) != 1 || d.Types[0].Count != nil || d.Types[0].Stars != nil {
+		t.Fatalf("types = %+v, want no change between an export and text output", d.Types)
+	}
+	var buf bytes.Buffer
+	if err := Render(&buf, d, FormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	if want := "- **dotflox:** n/a repositories, n/a stars"; !strings.Contains(buf.String(), want) {
+		t.Errorf("missing %q in:\n%s", want, buf.String())
+	}
+}
+
+func TestBuild_Errors(t *testing.T) {
+	if _, err := Build(&history.Store{}, Options{}); err == nil {
+		t.Error("expected an error for an empty store")
+	}
+	if _, err := Build(testStore(t), Options{Days: 30}); err == nil || !strings.Contains(err.Error(), "30 days before 2024-03-08") {
+		t.Errorf("expected missing previous snapshot error, got %v", err)
+	}
+}
+
+func TestBuild_WithoutRepositoryLists(t *testing.T) {
+	date := time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC)
+	d, err := Build(testStore(t), Options{Date: date, Days: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.From != "2024-03-05" || d.AdoptersKnown || d.Stars != nil {
+		t.Errorf("digest = %+v", d)
+	}
+	var buf bytes.Buffer
+	if err := Render(&buf, d, FormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "flox/flox stars:** n/a") || !strings.Contains(buf.String(), "not available") {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestRender(t *testing.T) {
+	d, err := Build(testStore(t), Options{Top: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{FormatMarkdown, []string{"# flox digest: 2024-03-01 to 2024-03-08", "- **flox/flox stars:** 120 (+20)", "## New adopters (2)", "- [b/new](https://github.com/b/new) (dotflox, 3 stars)", "## Top new repositories by stars (1)\n- [c/big]"}},
+		{FormatSlack, []string{"*flox digest: 2024-03-01 to 2024-03-08*", "• *floxindex:* 47 (+32)", "• <https://github.com/a/gone|a/gone> (dotflox, 5 stars)", "<https://github.com/a/keep|a/keep> +4, now 14"}},
+		{FormatHTML, []string{"<h1>flox digest: 2024-03-01 to 2024-03-08</h1>", "<li><b>dotflox:</b> 2 (&#43;0) repositories", `<a href="https://github.com/c/big">c/big</a> (readme, 30 stars)`, "<h2>Lost adopters (1)</h2>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Render(&buf, d, tt.format); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("missing %q in:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+	if err := Render(&bytes.Buffer{}, d, "pdf"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,139 @@
+package digest
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// Formats accepted by Render.
+const (
+	FormatMarkdown = "markdown"
+	FormatSlack    = "slack"
+	FormatHTML     = "html"
+)
+
+// Render writes d in format.
+func Render(w io.Writer, d *Digest, format string) error {
+	switch format {
+	case FormatMarkdown:
+		return writeText(w, d, markdown)
+	case FormatSlack:
+		return writeText(w, d, slack)
+	case FormatHTML:
+		return htmlTemplate.Execute(w, d)
+	}
+	return fmt.Errorf("invalid format %q: use markdown, slack or html", format)
+}
+
+// markup is the syntax of a text format.
+type markup struct {
+	title   func(string) string
+	heading func(string) string
+	bold    func(string) string
+	link    func(text, url string) string
+	bullet  string
+}
+
+var markdown = markup{
+	title:   func(s string) string { return "# " + s },
+	heading: func(s string) string { return "## " + s },
+	bold:    func(s string) string { return "**" + s + "**" },
+	link:    func(text, url string) string { return "[" + text + "](" + url + ")" },
+	bullet:  "-",
+}
+
+var slack = markup{
+	title:   func(s string) string { return "*" + s + "*" },
+	heading: func(s string) string { return "*" + s + "*" },
+	bold:    func(s string) string { return "*" + s + "*" },
+	link:    func(text, url string) string { return "<" + url + "|" + text + ">" },
+	bullet:  "•",
+}
+
+// writeText writes d as Markdown or Slack mrkdwn.
+func writeText(w io.Writer, d *Digest, m markup) error {
+	var b strings.Builder
+	line := func(format string, args ...any) { fmt.Fprintf(&b, format+"\n", args...) }
+	item := func(format string, args ...any) { line(m.bullet+" "+format, args...) }
+
+	line("%s", m.title(fmt.Sprintf("flox digest: %s to %s", d.From, d.To)))
+	line("")
+	item("%s %s", m.bold(d.Repo+" stars:"), changeText(d.Stars))
+	item("%s %s", m.bold("floxindex:"), changeText(d.FloxIndex))
+	for _, t := range d.Types {
+		item("%s %s repositories, %s stars", m.bold(t.Type+":"), changeText(t.Count), changeText(t.Stars))
+	}
+
+	if !d.AdoptersKnown {
+		line("")
+		line("Adopter lists are not available for this period.")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	adopterList := func(title string, adopters []Adopter, describe func(Adopter) string) {
+		line("")
+		line("%s", m.heading(fmt.Sprintf("%s (%d)", title, len(adopters))))
+		if len(adopters) == 0 {
+			line("None.")
+		}
+		for _, a := range adopters {
+			item("%s %s", m.link(a.Repository, a.URL), describe(a))
+		}
+	}
+	describe := func(a Adopter) string { return fmt.Sprintf("(%s, %d stars)", a.Type, a.Stars) }
+	adopterList("New adopters", d.NewAdopters, describe)
+	adopterList("Lost adopters", d.LostAdopters, describe)
+	adopterList("Top new repositories by stars", d.TopNew, describe)
+	adopterList("Manifest repositories gaining the most stars", d.Gainers, func(a Adopter) string {
+		return fmt.Sprintf("%+d, now %d", a.Delta, a.Stars)
+	})
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// changeText formats a change as "after (+delta)", or "n/a".
+func changeText(c *Change) string {
+	if c == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%d (%+d)", c.After, c.Delta)
+}
+
+var htmlTemplate = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"change": changeText,
+	"list":   func(v ...any) []any { return v },
+}).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif">
+<h1>flox digest: {{.From}} to {{.To}}</h1>
+<ul>
+<li><b>{{.Repo}} stars:</b> {{change .Stars}}</li>
+<li><b>floxindex:</b> {{change .FloxIndex}}</li>
+{{- range .Types}}
+<li><b>{{.Type}}:</b> {{change .Count}} repositories, {{change .Stars}} stars</li>
+{{- end}}
+</ul>
+{{- if not .AdoptersKnown}}
+<p>Adopter lists are not available for this period.</p>
+{{- else}}
+{{template "list" (list "New adopters" .NewAdopters false)}}
+{{template "list" (list "Lost adopters" .LostAdopters false)}}
+{{template "list" (list "Top new repositories by stars" .TopNew false)}}
+{{template "list" (list "Manifest repositories gaining the most stars" .Gainers true)}}
+{{- end}}
+</body>
+</html>
+{{define "list"}}<h2>{{index . 0}} ({{len (index . 1)}})</h2>
+{{- if index . 1}}
+<ul>
+{{- range index . 1}}
+<li><a href="{{.URL}}">{{.Repository}}</a> {{if index $ 2}}{{printf "%+d" .Delta}}, now {{.Stars}}{{else}}({{.Type}}, {{.Stars}} stars){{end}}</li>
+{{- end}}
+</ul>
+{{- else}}
+<p>None.</p>
+{{- end}}{{end}}
+`))
@@ -0,0 +1,322 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+	"github.com/stahnma/gh-flox/internal/cache"
+	ghub "github.com/stahnma/gh-flox/internal/github"
+	"github.com/stahnma/gh-flox/internal/progress"
+)
+
+// Status is the outcome of one file or repository in a download run.
+type Status string
+
+const (
+	StatusDownloaded Status = "downloaded"
+	StatusUnchanged  Status = "unchanged"
+	StatusMissing    Status = "missing"
+	StatusFailed     Status = "failed"
+)
+
+// Result is the outcome for one environment file. Repository-level problems,
+// such as an unresolvable default branch, have an empty Path.
+type Result struct {
+	Owner     string `json:"owner"`
+	Repo      string `json:"repo"`
+	Path      string `json:"path,omitempty"`
+	Status    Status `json:"status"`
+	CommitSHA string `json:"commit_sha,omitempty"`
+	BlobSHA   string `json:"blob_sha,omitempty"`
+	LocalPath string `json:"local_path,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Summary counts results by status.
+type Summary struct {
+	Downloaded int `json:"downloaded"`
+	Unchanged  int `json:"unchanged"`
+	Missing    int `json:"missing"`
+	Failed     int `json:"failed"`
+}
+
+// Report is the outcome of a download run.
+type Report struct {
+	Summary    Summary  `json:"summary"`
+	Incomplete bool     `json:"incomplete,omitempty"`
+	Results    []Result `json:"results"`
+}
+
+func (r *Report) add(res Result) {
+	switch res.Status {
+	case StatusDownloaded:
+		r.Summary.Downloaded++
+	case StatusUnchanged:
+		r.Summary.Unchanged++
+	case StatusMissing:
+		r.Summary.Missing++
+	case StatusFailed:
+		r.Summary.Failed++
+	}
+	r.Results = append(r.Results, res)
+}
+
+// Downloader fetches flox environment files into OutputDir, pinned to each
+// repository's default branch head, and keeps the index in OutputDir
+// up to date. Files already present with the right blob are skipped, so an
+// interrupted run can simply be repeated.
+type Downloader struct {
+	Client  ghub.Client
+	Cache   *cache.Cache
+	NoCache bool
+	// OutputDir receives <owner>/<repo>/<path> files and the index.
+	OutputDir string
+	// Files selects environment files from ghub.EnvironmentFiles; empty
+	// means all of them.
+	Files []string
+	// Concurrency is the number of repositories processed at once.
+	Concurrency int
+	// Retries is how many times a transient failure is retried.
+	Retries int
+	// RetryDelay is the initial backoff, doubled after each retry.
+	RetryDelay time.Duration
+	// Progress receives a ManifestDownloaded event per repository. Nil
+	// disables reporting.
+	Progress progress.Reporter
+}
+
+// Run downloads the environments of repos. The report lists every file; an
+// error is returned only for failures outside individual files, such as an
+// unreadable index or a canceled ctx, in which case the report is still
+// returned with Incomplete set.
+func (d *Downloader) Run(ctx context.Context, repos []ghub.Repo) (*Report, error) {
+	if err := os.MkdirAll(d.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("creating output directory: %w", err)
+	}
+	indexPath := filepath.Join(d.OutputDir, IndexFile)
+	index, err := LoadIndex(indexPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading download index: %w", err)
+	}
+
+	p := progress.OrNop(d.Progress)
+	p.Report(progress.Event{Kind: progress.StageStarted, Stage: "manifest download", Total: len(repos)})
+
+	var (
+		mu     sync.Mutex
+		report = &Report{Results: []Result{}}
+		wg     sync.WaitGroup
+		sem    = make(chan struct{}, max(d.Concurrency, 1))
+	)
+	for _, repo := range repos {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			results, entries, ok := d.downloadRepo(ctx, repo, index, &mu)
+			mu.Lock()
+			for _, r := range results {
+				report.add(r)
+			}
+			if ok {
+				index.ReplaceRepo(repo.Owner, repo.Name, entries)
+			}
+			mu.Unlock()
+			p.Report(progress.Event{Kind: progress.ManifestDownloaded})
+		}()
+	}
+	wg.Wait()
+
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		a, b := report.Results[i], report.Results[j]
+		if a.Owner+"/"+a.Repo != b.Owner+"/"+b.Repo {
+			return a.Owner+"/"+a.Repo < b.Owner+"/"+b.Repo
+		}
+		return a.Path < b.Path
+	})
+	if err := index.Save(indexPath); err != nil {
+		return report, fmt.Errorf("saving download index: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		report.Incomplete = true
+		return report, err
+	}
+	return report, nil
+}
+
+// downloadRepo handles one repository. It returns the results, the index
+// entries for the repository and whether those entries should replace the
+// existing ones. mu guards index.
+func (d *Downloader) downloadRepo(ctx context.Context, repo ghub.Repo, index *Index, mu *sync.Mutex) ([]Result, []Entry, bool) {
+	fail := func(format string, err error) ([]Result, []Entry, bool) {
+		slog.Warn(format, "repo", repo.FullName(), "err", err)
+		return []Result{{Owner: repo.Owner, Repo: repo.Name, Status: StatusFailed, Reason: fmt.Sprintf("%s: %v", format, err)}}, nil, false
+	}
+
+	branch := repo.DefaultBranch
+	if branch == "" {
+		branch = "HEAD"
+	}
+	var commitSHA string
+	err := d.retry(ctx, func() error {
+		var err error
+		commitSHA, _, err = d.Client.GetCommitSHA1(ctx, repo.Owner, repo.Name, branch, "")
+		return err
+	})
+	if err != nil {
+		return fail("resolving default branch failed", err)
+	}
+
+	var envs []ghub.Environment
+	err = d.retry(ctx, func() error {
+		var err error
+		envs, err = ghub.FindEnvironments(ctx, d.Client, d.Cache, repo.Owner, repo.Name, commitSHA, d.NoCache)
+		return err
+	})
+	if err != nil {
+		return fail("listing environments failed", err)
+	}
+	if len(envs) == 0 {
+		slog.Info("no manifest.toml found", "repo", repo.FullName(), "commit", commitSHA)
+		return []Result{{Owner: repo.Owner, Repo: repo.Name, Status: StatusMissing, CommitSHA: commitSHA,
+			Reason: "no " + ghub.FileManifest + " at commit " + commitSHA}}, nil, true
+	}
+
+	var results []Result
+	var entries []Entry
+	for _, env := range envs {
+		present := make(map[string]ghub.EnvironmentFile, len(env.Files))
+		for _, f := range env.Files {
+			present[f.Name] = f
+		}
+		for _, name := range d.files() {
+			file, ok := present[name]
+			if !ok {
+				results = append(results, Result{Owner: repo.Owner, Repo: repo.Name, Path: filepath.ToSlash(filepath.Join(env.Dir, name)),
+					Status: StatusMissing, CommitSHA: commitSHA, Reason: "not in repository"})
+				continue
+			}
+			entry := Entry{Owner: repo.Owner, Repo: repo.Name, Path: file.Path, CommitSHA: commitSHA, BlobSHA: file.SHA}
+			res := Result{Owner: repo.Owner, Repo: repo.Name, Path: file.Path, CommitSHA: commitSHA, BlobSHA: file.SHA,
+				LocalPath: filepath.Join(d.OutputDir, repo.Owner, repo.Name, filepath.FromSlash(file.Path))}
+
+			mu.Lock()
+			prev, hasPrev := index.Lookup(repo.Owner, repo.Name, file.Path)
+			mu.Unlock()
+			if blobUnchanged(prev, hasPrev, entry, res.LocalPath) {
+				slog.Debug("file unchanged", "repo", repo.FullName(), "path", file.Path)
+				res.Status = StatusUnchanged
+				results = append(results, res)
+				entries = append(entries, entry)
+				continue
+			}
+
+			err := d.retry(ctx, func() error { return d.downloadBlob(ctx, repo, file.SHA, res.LocalPath) })
+			if err != nil {
+				slog.Warn("downloading file failed", "repo", repo.FullName(), "path", file.Path, "err", err)
+				res.Status = StatusFailed
+				res.Reason = err.Error()
+				results = append(results, res)
+				if hasPrev {
+					entries = append(entries, prev)
+				}
+				continue
+			}
+			res.Status = StatusDownloaded
+			results = append(results, res)
+			entries = append(entries, entry)
+		}
+	}
+	return results, entries, true
+}
+
+func (d *Downloader) files() []string {
+	if len(d.Files) == 0 {
+		return ghub.EnvironmentFiles
+	}
+	return d.Files
+}
+
+// blobUnchanged reports whether the local copy of entry's file already holds
+// its blob, trusting the index when the file exists and falling back to
+// hashing the file when the index has no matching record.
+func blobUnchanged(prev Entry, hasPrev bool, entry Entry, localFilePath string) bool {
+	if _, err := os.Stat(localFilePath); err != nil {
+		return false
+	}
+	if hasPrev && prev.BlobSHA == entry.BlobSHA {
+		return true
+	}
+	data, err := os.ReadFile(localFilePath)
+	return err == nil && ghub.BlobSHA(data) == entry.BlobSHA
+}
+
+// errChecksum marks a blob whose content did not match its SHA.
+var errChecksum = errors.New("checksum mismatch")
+
+// downloadBlob fetches a git blob by SHA and writes it to localFilePath,
+// verifying its checksum.
+func (d *Downloader) downloadBlob(ctx context.Context, repo ghub.Repo, blobSHA, localFilePath string) error {
+	data, _, err := d.Client.GetBlobRaw(ctx, repo.Owner, repo.Name, blobSHA)
+	if err != nil {
+		return err
+	}
+	if got := ghub.BlobSHA(data); got != blobSHA {
+		return fmt.Errorf("%w: got blob %s, want %s", errChecksum, got, blobSHA)
+	}
+	return WriteFile(localFilePath, data)
+}
+
+// retry runs fn, retrying transient failures up to d.Retries times with
+// exponential backoff.
+func (d *Downloader) retry(ctx context.Context, fn func() error) error {
+	delay := d.RetryDelay
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= d.Retries || !retryable(err) {
+			return err
+		}
+		slog.Debug("retrying after transient error", "attempt", attempt+1, "delay", delay, "err", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// retryable reports whether err may succeed on a later attempt: network
+// errors, server errors, secondary rate limits and corrupted transfers. The
+// primary rate limit is not retried since it resets far in the future.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var rateErr *gh.RateLimitError
+	if errors.As(err, &rateErr) {
+		return false
+	}
+	var abuseErr *gh.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		return true
+	}
+	var respErr *gh.ErrorResponse
+	if errors.As(err, &respErr) && respErr.Response != nil {
+		code := respErr.Response.StatusCode
+		return code >= http.StatusInternalServerError || code == http.StatusTooManyRequests
+	}
+	return true
+}
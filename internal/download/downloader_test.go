@@ -0,0 +1,50 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	gh "github.com/google/go-github/v68/github"
+)
+
+func TestRetryable(t *testing.T) {
+	status := func(code int) error {
+		return &gh.ErrorResponse{Response: &http.Response{StatusCode: code}}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server error", status(http.StatusBadGateway), true},
+		{"too many requests", status(http.StatusTooManyRequests), true},
+		{"not found", status(http.StatusNotFound), false},
+		{"secondary rate limit", &gh.AbuseRateLimitError{}, true},
+		{"primary rate limit", &gh.RateLimitError{}, false},
+		{"checksum", fmt.Errorf("%w: bad", errChecksum), true},
+		{"canceled", fmt.Errorf("get: %w", context.Canceled), false},
+		{"network", errors.New("connection reset by peer"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err); got != tt.want {
+				t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloader_Retry(t *testing.T) {
+	d := &Downloader{Retries: 2}
+	calls := 0
+	err := d.retry(context.Background(), func() error {
+		calls++
+		return errors.New("connection reset by peer")
+	})
+	if err == nil || calls != 3 {
+		t.Errorf("got %v after %d calls, want an error after 3", err, calls)
+	}
+}
@@ -0,0 +1,112 @@
+// Package download fetches flox environment files and records what was
+// fetched, so downloads are reproducible and reruns can skip unchanged files.
+package download
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// IndexFile is the name of the sidecar index in the output directory.
+const IndexFile = "index.json"
+
+// Entry records one downloaded file and the commit it was taken from.
+type Entry struct {
+	Owner     string `json:"owner"`
+	Repo      string `json:"repo"`
+	Path      string `json:"path"`
+	CommitSHA string `json:"commit_sha"`
+	BlobSHA   string `json:"blob_sha"`
+}
+
+// Index is the sidecar index of downloaded files.
+type Index struct {
+	Entries []Entry `json:"entries"`
+}
+
+// LoadIndex reads the index at path. A missing file yields an empty index.
+func LoadIndex(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Index{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ix Index
+	if err := json.Unmarshal(data, &ix); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &ix, nil
+}
+
+// Save writes the index to path, sorted by repository and path.
+func (ix *Index) Save(path string) error {
+	sort.Slice(ix.Entries, func(i, j int) bool {
+		a, b := ix.Entries[i], ix.Entries[j]
+		if a.Owner != b.Owner {
+			return a.Owner < b.Owner
+		}
+		if a.Repo != b.Repo {
+			return a.Repo < b.Repo
+		}
+		return a.Path < b.Path
+	})
+	data, err := json.MarshalIndent(ix, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFile(path, append(data, '\n'))
+}
+
+// Lookup returns the entry for a file, if recorded.
+func (ix *Index) Lookup(owner, repo, path string) (Entry, bool) {
+	for _, e := range ix.Entries {
+		if e.Owner == owner && e.Repo == repo && e.Path == path {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// ReplaceRepo replaces all entries of a repository with entries, dropping
+// files that no longer exist there.
+func (ix *Index) ReplaceRepo(owner, repo string, entries []Entry) {
+	kept := ix.Entries[:0]
+	for _, e := range ix.Entries {
+		if e.Owner != owner || e.Repo != repo {
+			kept = append(kept, e)
+		}
+	}
+	ix.Entries = append(kept, entries...)
+}
+
+// WriteFile writes data to path atomically, creating parent directories, so
+// an interrupted run never leaves a partial file behind.
+func WriteFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,74 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIndex_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), IndexFile)
+	ix := &Index{Entries: []Entry{
+		{Owner: "bob", Repo: "b", Path: ".flox/env/manifest.toml", CommitSHA: "c2", BlobSHA: "b2"},
+		{Owner: "alice", Repo: "a", Path: ".flox/env/manifest.toml", CommitSHA: "c1", BlobSHA: "b1"},
+	}}
+	if err := ix.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Entries, ix.Entries) || got.Entries[0].Owner != "alice" {
+		t.Errorf("got %+v, want sorted %+v", got.Entries, ix.Entries)
+	}
+	if e, ok := got.Lookup("bob", "b", ".flox/env/manifest.toml"); !ok || e.BlobSHA != "b2" {
+		t.Errorf("Lookup = %+v, %v", e, ok)
+	}
+}
+
+func TestLoadIndex_Missing(t *testing.T) {
+	ix, err := LoadIndex(filepath.Join(t.TempDir(), IndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ix.Entries) != 0 {
+		t.Errorf("expected empty index, got %+v", ix.Entries)
+	}
+}
+
+func TestIndex_ReplaceRepo(t *testing.T) {
+	ix := &Index{Entries: []Entry{
+		{Owner: "alice", Repo: "a", Path: "old/.flox/env/manifest.toml"},
+		{Owner: "bob", Repo: "b", Path: ".flox/env/manifest.toml"},
+	}}
+	ix.ReplaceRepo("alice", "a", []Entry{{Owner: "alice", Repo: "a", Path: "new/.flox/env/manifest.toml"}})
+
+	if _, ok := ix.Lookup("alice", "a", "old/.flox/env/manifest.toml"); ok {
+		t.Error("expected removed file to be dropped")
+	}
+	if _, ok := ix.Lookup("alice", "a", "new/.flox/env/manifest.toml"); !ok {
+		t.Error("expected new file to be recorded")
+	}
+	if _, ok := ix.Lookup("bob", "b", ".flox/env/manifest.toml"); !ok {
+		t.Error("expected other repositories to be kept")
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a", "b", "manifest.toml")
+	if err := WriteFile(path, []byte("version = 1\n")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "version = 1\n" {
+		t.Errorf("got %q, %v", data, err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files left, got %d entries", len(entries))
+	}
+}
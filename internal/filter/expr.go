@@ -0,0 +1,409 @@
+package filter
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	ghub "github.com/stahnma/gh-flox/internal/github"
+)
+
+// Expr is a compiled filter expression such as
+//
+//	stars >= 10 && !fork && (language == "Go" || topics == "nix")
+//
+// Supported operators are ==, !=, <, <=, >, >=, =~ (regular expression match),
+// &&, || and !, with parentheses for grouping. Boolean fields may be used on
+// their own as predicates. See Fields for the available fields.
+type Expr struct {
+	src  string
+	pred predicate
+}
+
+type predicate func(r ghub.Repo, now time.Time) bool
+
+// Match reports whether r satisfies the expression at time now.
+func (e *Expr) Match(r ghub.Repo, now time.Time) bool {
+	return e.pred(r, now)
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+type fieldKind int
+
+const (
+	kindNumber fieldKind = iota
+	kindString
+	kindBool
+	kindList
+	kindAge
+)
+
+func (k fieldKind) String() string {
+	return [...]string{"number", "string", "bool", "list", "age"}[k]
+}
+
+type field struct {
+	kind fieldKind
+	get  func(r ghub.Repo, now time.Time) any
+}
+
+func age(t, now time.Time) any {
+	if t.IsZero() {
+		return time.Duration(math.MaxInt64)
+	}
+	return now.Sub(t)
+}
+
+var fields = map[string]field{
+	"owner":          {kindString, func(r ghub.Repo, _ time.Time) any { return r.Owner }},
+	"name":           {kindString, func(r ghub.Repo, _ time.Time) any { return r.Name }},
+	"repo":           {kindString, func(r ghub.Repo, _ time.Time) any { return r.FullName() }},
+	"stars":          {kindNumber, func(r ghub.Repo, _ time.Time) any { return float64(r.Stars) }},
+	"forks":          {kindNumber, func(r ghub.Repo, _ time.Time) any { return float64(r.Forks) }},
+	"watchers":       {kindNumber, func(r ghub.Repo, _ time.Time) any { return float64(r.Watchers) }},
+	"language":       {kindString, func(r ghub.Repo, _ time.Time) any { return r.Language }},
+	"license":        {kindString, func(r ghub.Repo, _ time.Time) any { return r.License }},
+	"owner_type":     {kindString, func(r ghub.Repo, _ time.Time) any { return r.OwnerType }},
+	"classification": {kindString, func(r ghub.Repo, _ time.Time) any { return r.Classification }},
+	"default_branch": {kindString, func(r ghub.Repo, _ time.Time) any { return r.DefaultBranch }},
+	"description":    {kindString, func(r ghub.Repo, _ time.Time) any { return r.Description }},
+	"topics":         {kindList, func(r ghub.Repo, _ time.Time) any { return r.Topics }},
+	"archived":       {kindBool, func(r ghub.Repo, _ time.Time) any { return r.Archived }},
+	"fork":           {kindBool, func(r ghub.Repo, _ time.Time) any { return r.Fork }},
+	"template":       {kindBool, func(r ghub.Repo, _ time.Time) any { return r.Template }},
+	"pushed_age":     {kindAge, func(r ghub.Repo, now time.Time) any { return age(r.PushedAt, now) }},
+	"created_age":    {kindAge, func(r ghub.Repo, now time.Time) any { return age(r.CreatedAt, now) }},
+}
+
+// Fields returns the names of the fields usable in expressions.
+func Fields() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse compiles a filter expression.
+func Parse(src string) (*Expr, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	pred, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+	}
+	return &Expr{src: strings.TrimSpace(src), pred: pred}, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokDuration
+	tokString
+	tokOp
+	tokNot
+	tokAnd
+	tokOr
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case strings.HasPrefix(src[i:], "&&"):
+			toks = append(toks, token{tokAnd, "&&", i})
+			i += 2
+		case strings.HasPrefix(src[i:], "||"):
+			toks = append(toks, token{tokOr, "||", i})
+			i += 2
+		case strings.HasPrefix(src[i:], "=="), strings.HasPrefix(src[i:], "!="),
+			strings.HasPrefix(src[i:], "=~"), strings.HasPrefix(src[i:], "<="),
+			strings.HasPrefix(src[i:], ">="):
+			toks = append(toks, token{tokOp, src[i : i+2], i})
+			i += 2
+		case c == '<' || c == '>':
+			toks = append(toks, token{tokOp, string(c), i})
+			i++
+		case c == '!':
+			toks = append(toks, token{tokNot, "!", i})
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			s, err := strconv.Unquote(src[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %w", i, err)
+			}
+			toks = append(toks, token{tokString, s, i})
+			i = j + 1
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			k := j
+			for k < len(src) && unicode.IsLetter(rune(src[k])) {
+				k++
+			}
+			if k > j {
+				toks = append(toks, token{tokDuration, src[i:k], i})
+			} else {
+				toks = append(toks, token{tokNumber, src[i:j], i})
+			}
+			i = k
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i
+			for j < len(src) && (src[j] == '_' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			toks = append(toks, token{tokIdent, src[i:j], i})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	return append(toks, token{tokEOF, "end of expression", len(src)}), nil
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (predicate, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(r ghub.Repo, now time.Time) bool { return l(r, now) || right(r, now) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (predicate, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(r ghub.Repo, now time.Time) bool { return l(r, now) && right(r, now) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (predicate, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(r ghub.Repo, now time.Time) bool { return !inner(r, now) }, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (predicate, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, fmt.Errorf("expected ) at position %d, got %q", closing.pos, closing.text)
+		}
+		return inner, nil
+	case tokIdent:
+		f, ok := fields[t.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q at position %d (fields: %s)", t.text, t.pos, strings.Join(Fields(), ", "))
+		}
+		if p.peek().kind != tokOp {
+			if f.kind != kindBool {
+				return nil, fmt.Errorf("field %q is a %s and needs a comparison", t.text, f.kind)
+			}
+			return func(r ghub.Repo, now time.Time) bool { return f.get(r, now).(bool) }, nil
+		}
+		op := p.next()
+		return p.parseComparison(t.text, f, op)
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+	}
+}
+
+func (p *parser) parseComparison(name string, f field, op token) (predicate, error) {
+	lit := p.next()
+	bad := func() error {
+		return fmt.Errorf("cannot compare %s field %q with %q using %s", f.kind, name, lit.text, op.text)
+	}
+
+	switch f.kind {
+	case kindNumber:
+		if lit.kind != tokNumber || op.text == "=~" {
+			return nil, bad()
+		}
+		want, err := strconv.ParseFloat(lit.text, 64)
+		if err != nil {
+			return nil, bad()
+		}
+		return func(r ghub.Repo, now time.Time) bool {
+			return compareOrdered(f.get(r, now).(float64), want, op.text)
+		}, nil
+
+	case kindAge:
+		var want time.Duration
+		switch lit.kind {
+		case tokDuration:
+			d, err := ParseDuration(lit.text)
+			if err != nil {
+				return nil, err
+			}
+			want = d
+		case tokNumber:
+			days, err := strconv.ParseFloat(lit.text, 64)
+			if err != nil {
+				return nil, bad()
+			}
+			want = time.Duration(days * float64(24*time.Hour))
+		default:
+			return nil, bad()
+		}
+		if op.text == "=~" {
+			return nil, bad()
+		}
+		return func(r ghub.Repo, now time.Time) bool {
+			return compareOrdered(f.get(r, now).(time.Duration), want, op.text)
+		}, nil
+
+	case kindString:
+		if lit.kind != tokString {
+			return nil, bad()
+		}
+		switch op.text {
+		case "==":
+			return func(r ghub.Repo, now time.Time) bool { return strings.EqualFold(f.get(r, now).(string), lit.text) }, nil
+		case "!=":
+			return func(r ghub.Repo, now time.Time) bool { return !strings.EqualFold(f.get(r, now).(string), lit.text) }, nil
+		case "=~":
+			re, err := regexp.Compile(lit.text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %q: %w", lit.text, err)
+			}
+			return func(r ghub.Repo, now time.Time) bool { return re.MatchString(f.get(r, now).(string)) }, nil
+		}
+		return nil, bad()
+
+	case kindBool:
+		if lit.kind != tokIdent || (lit.text != "true" && lit.text != "false") || (op.text != "==" && op.text != "!=") {
+			return nil, bad()
+		}
+		want := lit.text == "true"
+		if op.text == "!=" {
+			want = !want
+		}
+		return func(r ghub.Repo, now time.Time) bool { return f.get(r, now).(bool) == want }, nil
+
+	case kindList:
+		if lit.kind != tokString || (op.text != "==" && op.text != "!=") {
+			return nil, bad()
+		}
+		negate := op.text == "!="
+		return func(r ghub.Repo, now time.Time) bool {
+			for _, v := range f.get(r, now).([]string) {
+				if strings.EqualFold(v, lit.text) {
+					return !negate
+				}
+			}
+			return negate
+		}, nil
+	}
+	return nil, bad()
+}
+
+func compareOrdered[T float64 | time.Duration](got, want T, op string) bool {
+	switch op {
+	case "==":
+		return got == want
+	case "!=":
+		return got != want
+	case "<":
+		return got < want
+	case "<=":
+		return got <= want
+	case ">":
+		return got > want
+	case ">=":
+		return got >= want
+	}
+	return false
+}
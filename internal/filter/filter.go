@@ -0,0 +1,133 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	ghub "github.com/stahnma/gh-flox/internal/github"
+)
+
+// Filter selects repositories by their enrichment metadata. The zero value
+// matches everything.
+type Filter struct {
+	ExcludeForks    bool
+	ExcludeArchived bool
+	// ActiveWithin keeps repositories pushed to within this duration.
+	ActiveWithin time.Duration
+	MinStars     int
+	// OwnerType is "User" or "Organization"; empty matches both.
+	OwnerType string
+	Expr      *Expr
+}
+
+// IsZero reports whether f matches every repository.
+func (f Filter) IsZero() bool {
+	return !f.ExcludeForks && !f.ExcludeArchived && f.ActiveWithin == 0 &&
+		f.MinStars == 0 && f.OwnerType == "" && f.Expr == nil
+}
+
+// Match reports whether r passes the filter at time now.
+func (f Filter) Match(r ghub.Repo, now time.Time) bool {
+	if f.ExcludeForks && r.Fork {
+		return false
+	}
+	if f.ExcludeArchived && r.Archived {
+		return false
+	}
+	if f.ActiveWithin > 0 && (r.PushedAt.IsZero() || now.Sub(r.PushedAt) > f.ActiveWithin) {
+		return false
+	}
+	if r.Stars < f.MinStars {
+		return false
+	}
+	if f.OwnerType != "" && !strings.EqualFold(r.OwnerType, f.OwnerType) {
+		return false
+	}
+	if f.Expr != nil && !f.Expr.Match(r, now) {
+		return false
+	}
+	return true
+}
+
+// Apply returns the repositories in repos that pass the filter at time now.
+func (f Filter) Apply(repos []ghub.Repo, now time.Time) []ghub.Repo {
+	if f.IsZero() {
+		return repos
+	}
+	var kept []ghub.Repo
+	for _, r := range repos {
+		if f.Match(r, now) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// String returns the filter as an expression in the filter language, suitable
+// for recording alongside results. The zero filter returns "".
+func (f Filter) String() string {
+	var parts []string
+	if f.ExcludeForks {
+		parts = append(parts, "!fork")
+	}
+	if f.ExcludeArchived {
+		parts = append(parts, "!archived")
+	}
+	if f.ActiveWithin > 0 {
+		parts = append(parts, "pushed_age <= "+FormatDuration(f.ActiveWithin))
+	}
+	if f.MinStars > 0 {
+		parts = append(parts, fmt.Sprintf("stars >= %d", f.MinStars))
+	}
+	if f.OwnerType != "" {
+		parts = append(parts, fmt.Sprintf("owner_type == %q", f.OwnerType))
+	}
+	if f.Expr != nil {
+		parts = append(parts, "("+f.Expr.String()+")")
+	}
+	return strings.Join(parts, " && ")
+}
+
+// NormalizeOwnerType maps user-friendly owner types such as "org" to the
+// values GitHub reports.
+func NormalizeOwnerType(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case "":
+		return "", nil
+	case "org", "orgs", "organization":
+		return "Organization", nil
+	case "user", "users":
+		return "User", nil
+	}
+	return "", fmt.Errorf("invalid owner type %q: use org or user", s)
+}
+
+// ParseDuration parses durations like time.ParseDuration, and additionally
+// accepts whole days ("180d") and weeks ("4w").
+func ParseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(v * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// FormatDuration formats d in days when it is a whole number of days.
+func FormatDuration(d time.Duration) string {
+	day := 24 * time.Hour
+	if d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	return d.String()
+}
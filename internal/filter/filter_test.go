@@ -0,0 +1,162 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	ghub "github.com/stahnma/gh-flox/internal/github"
+)
+
+var now = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func repo(name string, meta ghub.RepoMetadata) ghub.Repo {
+	return ghub.Repo{Owner: "alice", Name: name, RepoMetadata: meta}
+}
+
+func TestFilter_Flags(t *testing.T) {
+	repos := []ghub.Repo{
+		repo("fork", ghub.RepoMetadata{Fork: true, Stars: 50, PushedAt: now}),
+		repo("archived", ghub.RepoMetadata{Archived: true, Stars: 50, PushedAt: now}),
+		repo("stale", ghub.RepoMetadata{Stars: 50, PushedAt: now.AddDate(-2, 0, 0)}),
+		repo("small", ghub.RepoMetadata{Stars: 1, PushedAt: now}),
+		repo("user", ghub.RepoMetadata{Stars: 50, PushedAt: now, OwnerType: "User"}),
+		repo("keep", ghub.RepoMetadata{Stars: 50, PushedAt: now, OwnerType: "Organization"}),
+	}
+	f := Filter{
+		ExcludeForks:    true,
+		ExcludeArchived: true,
+		ActiveWithin:    180 * 24 * time.Hour,
+		MinStars:        10,
+		OwnerType:       "Organization",
+	}
+
+	got := f.Apply(repos, now)
+	if len(got) != 1 || got[0].Name != "keep" {
+		t.Errorf("got %v, want only keep", got)
+	}
+}
+
+func TestFilter_ZeroMatchesAll(t *testing.T) {
+	repos := []ghub.Repo{repo("a", ghub.RepoMetadata{}), repo("b", ghub.RepoMetadata{Fork: true})}
+	if got := (Filter{}).Apply(repos, now); len(got) != 2 {
+		t.Errorf("zero filter dropped repos: %v", got)
+	}
+	if (Filter{}).String() != "" {
+		t.Error("zero filter should have empty description")
+	}
+}
+
+func TestFilter_String(t *testing.T) {
+	expr, err := Parse(`language == "Go"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := Filter{ExcludeForks: true, ActiveWithin: 180 * 24 * time.Hour, MinStars: 5, Expr: expr}
+	want := `!fork && pushed_age <= 180d && stars >= 5 && (language == "Go")`
+	if got := f.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	// The description must itself be a valid expression with the same meaning.
+	reparsed, err := Parse(f.String())
+	if err != nil {
+		t.Fatalf("description does not parse: %v", err)
+	}
+	r := repo("x", ghub.RepoMetadata{Stars: 6, PushedAt: now, Language: "go"})
+	if !reparsed.Match(r, now) || !f.Match(r, now) {
+		t.Error("expected repo to match both filter and reparsed description")
+	}
+}
+
+func TestParse_Expressions(t *testing.T) {
+	r := repo("flox-demo", ghub.RepoMetadata{
+		Stars:     25,
+		Language:  "Go",
+		Topics:    []string{"nix", "devenv"},
+		Archived:  false,
+		Fork:      true,
+		PushedAt:  now.AddDate(0, 0, -10),
+		OwnerType: "User",
+	})
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`stars >= 10`, true},
+		{`stars > 25`, false},
+		{`language == "go"`, true},
+		{`language != "Go"`, false},
+		{`topics == "nix"`, true},
+		{`topics != "nix"`, false},
+		{`fork`, true},
+		{`!archived`, true},
+		{`archived == false`, true},
+		{`name =~ "^flox-"`, true},
+		{`repo == "alice/flox-demo"`, true},
+		{`pushed_age < 30d`, true},
+		{`pushed_age < 7`, false},
+		{`stars > 100 || (fork && owner_type == "User")`, true},
+		{`stars > 100 || fork && owner_type == "Organization"`, false},
+		{`!(stars > 10 && fork)`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := e.Match(r, now); got != tt.want {
+				t.Errorf("Match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`bogus > 1`,
+		`stars`,
+		`stars > "ten"`,
+		`language > 3`,
+		`archived == maybe`,
+		`(stars > 1`,
+		`stars > 1 extra`,
+		`name =~ "("`,
+		`language == "unterminated`,
+		`stars @ 3`,
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("expected error for %q", expr)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"180d": 180 * 24 * time.Hour,
+		"2w":   14 * 24 * time.Hour,
+		"36h":  36 * time.Hour,
+	}
+	for in, want := range tests {
+		got, err := ParseDuration(in)
+		if err != nil || got != want {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseDuration("soon"); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+}
+
+func TestNormalizeOwnerType(t *testing.T) {
+	if got, _ := NormalizeOwnerType("org"); got != "Organization" {
+		t.Errorf("org -> %q", got)
+	}
+	if got, _ := NormalizeOwnerType("User"); got != "User" {
+		t.Errorf("User -> %q", got)
+	}
+	if _, err := NormalizeOwnerType("team"); err == nil {
+		t.Error("expected error for invalid owner type")
+	}
+}
@@ -0,0 +1,117 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+	"github.com/stahnma/gh-flox/internal/cache"
+)
+
+// ActivityWindow is how far back RepoActivity.RecentCommits counts.
+const ActivityWindow = 90 * 24 * time.Hour
+
+// RepoActivity holds the commit history signals used by the adoption score.
+type RepoActivity struct {
+	// RecentCommits is the number of commits on the default branch within
+	// ActivityWindow.
+	RecentCommits int
+	// Contributors is the number of contributors, including anonymous ones.
+	Contributors int
+}
+
+// GetRepoActivity retrieves commit activity for a repository, using the cache.
+// It costs two core API calls per uncached repository.
+func GetRepoActivity(ctx context.Context, client Client, c *cache.Cache, owner, repo string, noCache bool) (RepoActivity, error) {
+	cacheKey := repoActivityCacheKey(owner, repo)
+	if !noCache {
+		if val, found := c.Get(cacheKey); found {
+			slog.Debug("cache hit", "key", cacheKey)
+			if activity, ok := val.(RepoActivity); ok {
+				return activity, nil
+			}
+		}
+		slog.Debug("cache miss", "key", cacheKey)
+	}
+
+	var activity RepoActivity
+	commits, resp, err := client.ListCommits(ctx, owner, repo, &gh.CommitsListOptions{
+		Since:       time.Now().Add(-ActivityWindow),
+		ListOptions: gh.ListOptions{PerPage: 1},
+	})
+	if err != nil && !isEmptyRepo(resp) {
+		return RepoActivity{}, fmt.Errorf("listing commits for %s/%s: %w", owner, repo, err)
+	}
+	activity.RecentCommits = countFromFirstPage(len(commits), resp)
+
+	contributors, resp, err := client.ListContributors(ctx, owner, repo, &gh.ListContributorsOptions{
+		Anon:        "true",
+		ListOptions: gh.ListOptions{PerPage: 1},
+	})
+	if err != nil && !isEmptyRepo(resp) {
+		return RepoActivity{}, fmt.Errorf("listing contributors for %s/%s: %w", owner, repo, err)
+	}
+	activity.Contributors = countFromFirstPage(len(contributors), resp)
+
+	if !noCache {
+		c.Set(cacheKey, activity)
+	}
+	return activity, nil
+}
+
+// EstimateActivityCalls returns the core API calls GetRepoActivity needs for
+// a repository: none if its activity is cached, otherwise two.
+func EstimateActivityCalls(c *cache.Cache, owner, repo string, noCache bool) int {
+	if _, hit := c.Get(repoActivityCacheKey(owner, repo)); hit && !noCache {
+		return 0
+	}
+	return 2
+}
+
+// firstCommitDate returns the author date of the oldest commit touching path,
+// or zero if there is none. Commits are listed newest first, so with one
+// commit per page the oldest is on the last page.
+func firstCommitDate(ctx context.Context, client Client, owner, repo, path string) (time.Time, error) {
+	opts := &gh.CommitsListOptions{Path: path, ListOptions: gh.ListOptions{PerPage: 1}}
+	commits, resp, err := client.ListCommits(ctx, owner, repo, opts)
+	if err != nil {
+		if isEmptyRepo(resp) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("listing %s commits for %s/%s: %w", path, owner, repo, err)
+	}
+	if resp != nil && resp.LastPage > 1 {
+		opts.Page = resp.LastPage
+		commits, _, err = client.ListCommits(ctx, owner, repo, opts)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("listing %s commits for %s/%s: %w", path, owner, repo, err)
+		}
+	}
+	if len(commits) == 0 {
+		return time.Time{}, nil
+	}
+	return commits[len(commits)-1].GetCommit().GetAuthor().GetDate().Time, nil
+}
+
+// countFromFirstPage returns the total number of items in a listing fetched
+// with one item per page: the last page number if there are several pages,
+// otherwise the number of items returned.
+func countFromFirstPage(n int, resp *gh.Response) int {
+	if resp != nil && resp.LastPage > 0 {
+		return resp.LastPage
+	}
+	return n
+}
+
+// isEmptyRepo reports whether resp is GitHub's 409 Conflict for a repository
+// with no commits.
+func isEmptyRepo(resp *gh.Response) bool {
+	return resp != nil && resp.Response != nil && resp.StatusCode == http.StatusConflict
+}
+
+func repoActivityCacheKey(owner, repo string) string {
+	return fmt.Sprintf("repoActivity:%s/%s", owner, repo)
+}
@@ -0,0 +1,70 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+	"github.com/stahnma/gh-flox/internal/cache"
+)
+
+func commitAt(t time.Time) *gh.RepositoryCommit {
+	return &gh.RepositoryCommit{Commit: &gh.Commit{Author: &gh.CommitAuthor{Date: &gh.Timestamp{Time: t}}}}
+}
+
+func TestGetRepoActivity(t *testing.T) {
+	client := &mockClient{
+		listCommitsFn: func(_ context.Context, _, _ string, opts *gh.CommitsListOptions) ([]*gh.RepositoryCommit, *gh.Response, error) {
+			if opts.Since.IsZero() {
+				t.Error("expected commits to be limited to the activity window")
+			}
+			resp := emptyResponse()
+			resp.LastPage = 37
+			return []*gh.RepositoryCommit{commitAt(time.Now())}, resp, nil
+		},
+		listContributorsFn: func(_ context.Context, _, _ string, opts *gh.ListContributorsOptions) ([]*gh.Contributor, *gh.Response, error) {
+			if opts.Anon != "true" {
+				t.Error("expected anonymous contributors to be counted")
+			}
+			return []*gh.Contributor{{}}, emptyResponse(), nil
+		},
+	}
+	c := cache.New()
+
+	got, err := GetRepoActivity(context.Background(), client, c, "alice", "project", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RepoActivity{RecentCommits: 37, Contributors: 1}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	client.listCommitsFn = nil
+	client.listContributorsFn = nil
+	if cached, err := GetRepoActivity(context.Background(), client, c, "alice", "project", false); err != nil || cached != want {
+		t.Errorf("expected cached activity, got %+v, %v", cached, err)
+	}
+}
+
+func TestGetRepoActivity_EmptyRepository(t *testing.T) {
+	conflict := &gh.Response{Response: &http.Response{StatusCode: http.StatusConflict}}
+	client := &mockClient{
+		listCommitsFn: func(_ context.Context, _, _ string, _ *gh.CommitsListOptions) ([]*gh.RepositoryCommit, *gh.Response, error) {
+			return nil, conflict, &gh.ErrorResponse{Response: conflict.Response, Message: "Git Repository is empty."}
+		},
+		listContributorsFn: func(_ context.Context, _, _ string, _ *gh.ListContributorsOptions) ([]*gh.Contributor, *gh.Response, error) {
+			return nil, emptyResponse(), nil
+		},
+	}
+
+	got, err := GetRepoActivity(context.Background(), client, cache.New(), "alice", "empty", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != (RepoActivity{}) {
+		t.Errorf("got %+v, want zero activity", got)
+	}
+}
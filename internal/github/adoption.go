@@ -0,0 +1,186 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+	"github.com/stahnma/gh-flox/internal/cache"
+)
+
+// readmePhrase is the text whose introduction marks a README repository's
+// adoption of flox, matching the README search.
+const readmePhrase = "flox install"
+
+// maxReadmeCommitPages bounds how much README history is scanned. Older
+// history is ignored, so very busy READMEs may report a later date.
+const maxReadmeCommitPages = 10
+
+// Core API calls of an uncached adoption date lookup. A manifest date lists
+// the repository tree, then the first and last page of each manifest's
+// commits; a README date lists the README's commits and fetches the README
+// at about log2(n) of them.
+const (
+	manifestAdoptionCalls = 3
+	readmeAdoptionCalls   = 10
+)
+
+// EstimateManifestAdoptionCalls returns the core API calls
+// GetManifestAdoptionDate needs for a repository, none if its date is cached.
+func EstimateManifestAdoptionCalls(c *cache.Cache, owner, repo string, noCache bool) int {
+	if _, hit := c.Get(adoptionCacheKey("manifest", owner, repo)); hit && !noCache {
+		return 0
+	}
+	return manifestAdoptionCalls
+}
+
+// EstimateReadmeAdoptionCalls returns the core API calls
+// GetReadmeAdoptionDate needs for a repository, none if its date is cached.
+func EstimateReadmeAdoptionCalls(c *cache.Cache, owner, repo string, noCache bool) int {
+	if _, hit := c.Get(adoptionCacheKey("readme", owner, repo)); hit && !noCache {
+		return 0
+	}
+	return readmeAdoptionCalls
+}
+
+// GetManifestAdoptionDate returns the date of the earliest commit touching
+// any environment manifest in the repository at ref, as found by
+// FindEnvironments, or zero if there is none. Without environments, the root
+// .flox/env/manifest.toml is tried. Dates never change, so found dates are
+// cached permanently.
+func GetManifestAdoptionDate(ctx context.Context, client Client, c *cache.Cache, owner, repo, ref string, noCache bool) (time.Time, error) {
+	return cachedAdoptionDate(c, adoptionCacheKey("manifest", owner, repo), noCache, func() (time.Time, error) {
+		envs, err := FindEnvironments(ctx, client, c, owner, repo, ref, noCache)
+		if err != nil {
+			return time.Time{}, err
+		}
+		paths := []string{FileManifest}
+		if len(envs) > 0 {
+			paths = paths[:0]
+			for _, env := range envs {
+				paths = append(paths, path.Join(env.Dir, FileManifest))
+			}
+		}
+		var earliest time.Time
+		for _, p := range paths {
+			date, err := firstCommitDate(ctx, client, owner, repo, p)
+			if err != nil {
+				return time.Time{}, err
+			}
+			if !date.IsZero() && (earliest.IsZero() || date.Before(earliest)) {
+				earliest = date
+			}
+		}
+		return earliest, nil
+	})
+}
+
+// GetReadmeAdoptionDate returns the date of the commit that introduced
+// "flox install" into the repository's README, or zero if it cannot be
+// determined. Found dates are cached permanently.
+//
+// The README's commits are binary searched, assuming the phrase stayed once
+// added, so a README with n commits costs about log2(n) content fetches.
+func GetReadmeAdoptionDate(ctx context.Context, client Client, c *cache.Cache, owner, repo string, noCache bool) (time.Time, error) {
+	return cachedAdoptionDate(c, adoptionCacheKey("readme", owner, repo), noCache, func() (time.Time, error) {
+		return readmeAdoptionDate(ctx, client, owner, repo)
+	})
+}
+
+func cachedAdoptionDate(c *cache.Cache, cacheKey string, noCache bool, fetch func() (time.Time, error)) (time.Time, error) {
+	if !noCache {
+		if val, found := c.Get(cacheKey); found {
+			slog.Debug("cache hit", "key", cacheKey)
+			if t, ok := val.(time.Time); ok {
+				return t, nil
+			}
+		}
+		slog.Debug("cache miss", "key", cacheKey)
+	}
+
+	t, err := fetch()
+	if err != nil {
+		return time.Time{}, err
+	}
+	// A zero date means the history could not be found yet, which may change.
+	if !noCache && !t.IsZero() {
+		c.SetWithExpiration(cacheKey, t, cache.NoExpiration)
+	}
+	return t, nil
+}
+
+func readmeAdoptionDate(ctx context.Context, client Client, owner, repo string) (time.Time, error) {
+	readme, resp, err := client.GetReadme(ctx, owner, repo, nil)
+	if err != nil {
+		if isNotFound(resp) || isEmptyRepo(resp) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("getting README for %s/%s: %w", owner, repo, err)
+	}
+
+	var commits []*gh.RepositoryCommit
+	opts := &gh.CommitsListOptions{Path: readme.GetPath(), ListOptions: gh.ListOptions{PerPage: 100}}
+	for page := 0; page < maxReadmeCommitPages; page++ {
+		batch, resp, err := client.ListCommits(ctx, owner, repo, opts)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("listing README commits for %s/%s: %w", owner, repo, err)
+		}
+		commits = append(commits, batch...)
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	if len(commits) == 0 {
+		return time.Time{}, nil
+	}
+
+	contains := func(i int) (bool, error) {
+		rc, resp, err := client.GetReadme(ctx, owner, repo, &gh.RepositoryContentGetOptions{Ref: commits[i].GetSHA()})
+		if err != nil {
+			if isNotFound(resp) {
+				return false, nil
+			}
+			return false, fmt.Errorf("getting README for %s/%s at %s: %w", owner, repo, commits[i].GetSHA(), err)
+		}
+		content, err := rc.GetContent()
+		if err != nil {
+			return false, fmt.Errorf("decoding README for %s/%s: %w", owner, repo, err)
+		}
+		return strings.Contains(strings.ToLower(content), readmePhrase), nil
+	}
+
+	// Commits are newest first. Find the oldest one whose README still has
+	// the phrase; everything newer is assumed to have it too.
+	ok, err := contains(0)
+	if err != nil || !ok {
+		return time.Time{}, err
+	}
+	lo, hi := 0, len(commits)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		ok, err := contains(mid)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if ok {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return commits[lo].GetCommit().GetAuthor().GetDate().Time, nil
+}
+
+func isNotFound(resp *gh.Response) bool {
+	return resp != nil && resp.Response != nil && resp.StatusCode == http.StatusNotFound
+}
+
+func adoptionCacheKey(kind, owner, repo string) string {
+	return fmt.Sprintf("adoptionDate:%s:%s/%s", kind, owner, repo)
+}
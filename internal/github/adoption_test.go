@@ -0,0 +1,160 @@
+package github
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+	"github.com/stahnma/gh-flox/internal/cache"
+)
+
+func TestGetManifestAdoptionDate(t *testing.T) {
+	added := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	var pages []int
+	client := &mockClient{
+		getTreeFn: noTree,
+		listCommitsFn: func(_ context.Context, _, _ string, opts *gh.CommitsListOptions) ([]*gh.RepositoryCommit, *gh.Response, error) {
+			if opts.Path != ".flox/env/manifest.toml" {
+				t.Errorf("path = %q, want .flox/env/manifest.toml", opts.Path)
+			}
+			pages = append(pages, opts.Page)
+			resp := emptyResponse()
+			if opts.Page == 0 {
+				resp.LastPage = 5
+				return []*gh.RepositoryCommit{commitAt(time.Now())}, resp, nil
+			}
+			return []*gh.RepositoryCommit{commitAt(added)}, resp, nil
+		},
+	}
+	c := cache.New()
+
+	got, err := GetManifestAdoptionDate(context.Background(), client, c, "alice", "project", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(added) {
+		t.Errorf("got %v, want %v", got, added)
+	}
+	if len(pages) != 2 || pages[1] != 5 {
+		t.Errorf("expected the oldest commit to be read from the last page, fetched pages %v", pages)
+	}
+
+	client.listCommitsFn = nil
+	if cached, err := GetManifestAdoptionDate(context.Background(), client, c, "alice", "project", "", false); err != nil || !cached.Equal(added) {
+		t.Errorf("expected cached date, got %v, %v", cached, err)
+	}
+}
+
+func TestGetManifestAdoptionDate_NoHistoryNotCached(t *testing.T) {
+	calls := 0
+	client := &mockClient{
+		getTreeFn: noTree,
+		listCommitsFn: func(_ context.Context, _, _ string, _ *gh.CommitsListOptions) ([]*gh.RepositoryCommit, *gh.Response, error) {
+			calls++
+			return nil, emptyResponse(), nil
+		},
+	}
+	c := cache.New()
+
+	for range 2 {
+		got, err := GetManifestAdoptionDate(context.Background(), client, c, "alice", "project", "", false)
+		if err != nil || !got.IsZero() {
+			t.Fatalf("got %v, %v, want zero date", got, err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected unknown dates not to be cached, got %d calls", calls)
+	}
+}
+
+// noTree serves an empty tree, as for a repository whose manifest search hit
+// is no longer there.
+func noTree(_ context.Context, _, _, _ string, _ bool) (*gh.Tree, *gh.Response, error) {
+	return &gh.Tree{}, emptyResponse(), nil
+}
+
+func TestGetManifestAdoptionDate_Subdirectories(t *testing.T) {
+	dates := map[string]time.Time{
+		"web/.flox/env/manifest.toml": time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		"api/.flox/env/manifest.toml": time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	client := &mockClient{
+		getTreeFn: func(_ context.Context, _, _, ref string, recursive bool) (*gh.Tree, *gh.Response, error) {
+			if ref != "main" || !recursive {
+				t.Errorf("tree requested at %q, recursive %v", ref, recursive)
+			}
+			var entries []*gh.TreeEntry
+			for p := range dates {
+				entries = append(entries, &gh.TreeEntry{Path: gh.Ptr(p), Type: gh.Ptr("blob")})
+			}
+			return &gh.Tree{Entries: entries}, emptyResponse(), nil
+		},
+		listCommitsFn: func(_ context.Context, _, _ string, opts *gh.CommitsListOptions) ([]*gh.RepositoryCommit, *gh.Response, error) {
+			date, ok := dates[opts.Path]
+			if !ok {
+				t.Errorf("unexpected commits listing for %q", opts.Path)
+			}
+			return []*gh.RepositoryCommit{commitAt(date)}, emptyResponse(), nil
+		},
+	}
+
+	got, err := GetManifestAdoptionDate(context.Background(), client, cache.New(), "alice", "project", "main", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := dates["api/.flox/env/manifest.toml"]; !got.Equal(want) {
+		t.Errorf("got %v, want the earliest environment's %v", got, want)
+	}
+}
+
+func TestGetReadmeAdoptionDate(t *testing.T) {
+	// 20 README commits, newest first; "flox install" was added in commit
+	// index 13 and kept since.
+	const n, introduced = 20, 13
+	base := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	commits := make([]*gh.RepositoryCommit, n)
+	for i := range commits {
+		commits[i] = commitAt(base.AddDate(0, 0, n-i))
+		commits[i].SHA = gh.Ptr(fmt.Sprintf("sha%d", i))
+	}
+	var fetched []string
+	client := &mockClient{
+		getReadmeFn: func(_ context.Context, _, _ string, opts *gh.RepositoryContentGetOptions) (*gh.RepositoryContent, *gh.Response, error) {
+			text := "# project\n"
+			ref := ""
+			if opts != nil {
+				ref = opts.Ref
+				fetched = append(fetched, ref)
+			}
+			var idx int
+			if _, err := fmt.Sscanf(ref, "sha%d", &idx); ref == "" || (err == nil && idx <= introduced) {
+				text += "Run `Flox Install hello`.\n"
+			}
+			return &gh.RepositoryContent{
+				Path:     gh.Ptr("README.md"),
+				Encoding: gh.Ptr("base64"),
+				Content:  gh.Ptr(base64.StdEncoding.EncodeToString([]byte(text))),
+			}, emptyResponse(), nil
+		},
+		listCommitsFn: func(_ context.Context, _, _ string, opts *gh.CommitsListOptions) ([]*gh.RepositoryCommit, *gh.Response, error) {
+			if opts.Path != "README.md" {
+				t.Errorf("path = %q, want README.md", opts.Path)
+			}
+			return commits, emptyResponse(), nil
+		},
+	}
+
+	got, err := GetReadmeAdoptionDate(context.Background(), client, cache.New(), "alice", "project", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := commits[introduced].GetCommit().GetAuthor().GetDate().Time; !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if len(fetched) > 6 {
+		t.Errorf("expected a binary search, fetched %d README versions: %v", len(fetched), fetched)
+	}
+}
@@ -2,16 +2,27 @@ package github
 
 import (
 	"context"
+	"net/http"
 
 	gh "github.com/google/go-github/v68/github"
+	"github.com/stahnma/gh-flox/internal/cache"
 	"golang.org/x/oauth2"
 )
 
 // Client defines the GitHub API methods used by this application.
 type Client interface {
 	SearchCode(ctx context.Context, query string, opts *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error)
+	SearchRepositories(ctx context.Context, query string, opts *gh.SearchOptions) (*gh.RepositoriesSearchResult, *gh.Response, error)
 	GetRepository(ctx context.Context, owner, repo string) (*gh.Repository, *gh.Response, error)
 	IsOrgMember(ctx context.Context, org, user string) (bool, *gh.Response, error)
+	GetRateLimits(ctx context.Context) (*gh.RateLimits, *gh.Response, error)
+	ListCommits(ctx context.Context, owner, repo string, opts *gh.CommitsListOptions) ([]*gh.RepositoryCommit, *gh.Response, error)
+	ListContributors(ctx context.Context, owner, repo string, opts *gh.ListContributorsOptions) ([]*gh.Contributor, *gh.Response, error)
+	GetReadme(ctx context.Context, owner, repo string, opts *gh.RepositoryContentGetOptions) (*gh.RepositoryContent, *gh.Response, error)
+	GetTree(ctx context.Context, owner, repo, sha string, recursive bool) (*gh.Tree, *gh.Response, error)
+	GetCommitSHA1(ctx context.Context, owner, repo, ref, lastSHA string) (string, *gh.Response, error)
+	GetBlobRaw(ctx context.Context, owner, repo, sha string) ([]byte, *gh.Response, error)
+	ListStargazers(ctx context.Context, owner, repo string, opts *gh.ListOptions) ([]*gh.Stargazer, *gh.Response, error)
 }
 
 // realClient wraps the go-github client to implement Client.
@@ -20,9 +31,16 @@ type realClient struct {
 }
 
 // NewClient creates a new GitHub API client authenticated with the given token.
-func NewClient(token string) Client {
+// If c is non-nil, repository requests are revalidated with conditional
+// requests whose validators are persisted in c.
+func NewClient(token string, c *cache.Cache) Client {
 	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	httpClient := oauth2.NewClient(context.Background(), ts)
+	httpClient := &http.Client{
+		Transport: &oauth2.Transport{
+			Source: ts,
+			Base:   &ConditionalTransport{Cache: c},
+		},
+	}
 	return &realClient{inner: gh.NewClient(httpClient)}
 }
 
@@ -30,6 +48,10 @@ func (c *realClient) SearchCode(ctx context.Context, query string, opts *gh.Sear
 	return c.inner.Search.Code(ctx, query, opts)
 }
 
+func (c *realClient) SearchRepositories(ctx context.Context, query string, opts *gh.SearchOptions) (*gh.RepositoriesSearchResult, *gh.Response, error) {
+	return c.inner.Search.Repositories(ctx, query, opts)
+}
+
 func (c *realClient) GetRepository(ctx context.Context, owner, repo string) (*gh.Repository, *gh.Response, error) {
 	return c.inner.Repositories.Get(ctx, owner, repo)
 }
@@ -37,3 +59,37 @@ func (c *realClient) GetRepository(ctx context.Context, owner, repo string) (*gh
 func (c *realClient) IsOrgMember(ctx context.Context, org, user string) (bool, *gh.Response, error) {
 	return c.inner.Organizations.IsMember(ctx, org, user)
 }
+
+func (c *realClient) GetRateLimits(ctx context.Context) (*gh.RateLimits, *gh.Response, error) {
+	return c.inner.RateLimit.Get(ctx)
+}
+
+func (c *realClient) ListCommits(ctx context.Context, owner, repo string, opts *gh.CommitsListOptions) ([]*gh.RepositoryCommit, *gh.Response, error) {
+	return c.inner.Repositories.ListCommits(ctx, owner, repo, opts)
+}
+
+func (c *realClient) ListContributors(ctx context.Context, owner, repo string, opts *gh.ListContributorsOptions) ([]*gh.Contributor, *gh.Response, error) {
+	return c.inner.Repositories.ListContributors(ctx, owner, repo, opts)
+}
+
+func (c *realClient) GetReadme(ctx context.Context, owner, repo string, opts *gh.RepositoryContentGetOptions) (*gh.RepositoryContent, *gh.Response, error) {
+	return c.inner.Repositories.GetReadme(ctx, owner, repo, opts)
+}
+
+func (c *realClient) GetTree(ctx context.Context, owner, repo, sha string, recursive bool) (*gh.Tree, *gh.Response, error) {
+	return c.inner.Git.GetTree(ctx, owner, repo, sha, recursive)
+}
+
+func (c *realClient) GetCommitSHA1(ctx context.Context, owner, repo, ref, lastSHA string) (string, *gh.Response, error) {
+	return c.inner.Repositories.GetCommitSHA1(ctx, owner, repo, ref, lastSHA)
+}
+
+func (c *realClient) GetBlobRaw(ctx context.Context, owner, repo, sha string) ([]byte, *gh.Response, error) {
+	return c.inner.Git.GetBlobRaw(ctx, owner, repo, sha)
+}
+
+// ListStargazers lists stargazers with the star+json media type, so each
+// carries when it starred the repository.
+func (c *realClient) ListStargazers(ctx context.Context, owner, repo string, opts *gh.ListOptions) ([]*gh.Stargazer, *gh.Response, error) {
+	return c.inner.Activity.ListStargazers(ctx, owner, repo, opts)
+}
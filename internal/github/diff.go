@@ -0,0 +1,90 @@
+package github
+
+import "sort"
+
+// ExportDiff describes how one export changed relative to an earlier one.
+// Records are matched by repository and type.
+type ExportDiff struct {
+	FromDate string `json:"from_date"`
+	ToDate   string `json:"to_date"`
+	// StarsBefore and StarsAfter sum the stars of all records.
+	StarsBefore int          `json:"stars_before"`
+	StarsAfter  int          `json:"stars_after"`
+	Added       []RepoChange `json:"added"`
+	Removed     []RepoChange `json:"removed"`
+	Changed     []RepoChange `json:"changed"`
+}
+
+// RepoChange is one record's star count before and after.
+type RepoChange struct {
+	Repository string `json:"repository"`
+	Type       string `json:"type"`
+	Before     int    `json:"before"`
+	After      int    `json:"after"`
+	Delta      int    `json:"delta"`
+}
+
+// DiffExports compares two exports. If only one of them is scoped, just the
+// records in scope are compared, so flox organization and employee
+// repositories are not reported as added or removed. Added and removed
+// records are sorted by name, changed records by the size of their star
+// change, largest first.
+func DiffExports(from, to *Export) ExportDiff {
+	fromRepos, toRepos := from.Repositories, to.Repositories
+	if from.Scoped() != to.Scoped() {
+		fromRepos, toRepos = from.ScopedRepositories(), to.ScopedRepositories()
+	}
+
+	type key struct{ repo, typ string }
+	before := make(map[key]int, len(fromRepos))
+	d := ExportDiff{FromDate: from.Date, ToDate: to.Date, Added: []RepoChange{}, Removed: []RepoChange{}, Changed: []RepoChange{}}
+	for _, r := range fromRepos {
+		before[key{r.Repository, r.Type}] = r.StarCount
+		d.StarsBefore += r.StarCount
+	}
+
+	seen := make(map[key]bool, len(toRepos))
+	for _, r := range toRepos {
+		k := key{r.Repository, r.Type}
+		seen[k] = true
+		d.StarsAfter += r.StarCount
+		stars, ok := before[k]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, RepoChange{Repository: r.Repository, Type: r.Type, After: r.StarCount, Delta: r.StarCount})
+		case stars != r.StarCount:
+			d.Changed = append(d.Changed, RepoChange{Repository: r.Repository, Type: r.Type, Before: stars, After: r.StarCount, Delta: r.StarCount - stars})
+		}
+	}
+	for _, r := range fromRepos {
+		if !seen[key{r.Repository, r.Type}] {
+			d.Removed = append(d.Removed, RepoChange{Repository: r.Repository, Type: r.Type, Before: r.StarCount, Delta: -r.StarCount})
+		}
+	}
+
+	byName := func(c []RepoChange) func(i, j int) bool {
+		return func(i, j int) bool {
+			if c[i].Repository != c[j].Repository {
+				return c[i].Repository < c[j].Repository
+			}
+			return c[i].Type < c[j].Type
+		}
+	}
+	sort.Slice(d.Added, byName(d.Added))
+	sort.Slice(d.Removed, byName(d.Removed))
+	sort.SliceStable(d.Changed, func(i, j int) bool {
+		a, b := abs(d.Changed[i].Delta), abs(d.Changed[j].Delta)
+		if a != b {
+			return a > b
+		}
+		return byName(d.Changed)(i, j)
+	})
+	return d
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
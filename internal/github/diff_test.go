@@ -0,0 +1,54 @@
+package github
+
+import "testing"
+
+func TestDiffExports(t *testing.T) {
+	from := &Export{Date: "2025-Mar-01", Repositories: []RepoInfo{
+		{Repository: "alice/a", Type: "dotflox", StarCount: 10},
+		{Repository: "alice/a", Type: "readme", StarCount: 10},
+		{Repository: "bob/b", Type: "dotflox", StarCount: 5},
+		{Repository: "carol/c", Type: "readme", StarCount: 1},
+	}}
+	to := &Export{Date: "2025-Mar-02", Repositories: []RepoInfo{
+		{Repository: "alice/a", Type: "dotflox", StarCount: 12},
+		{Repository: "alice/a", Type: "readme", StarCount: 12},
+		{Repository: "bob/b", Type: "dotflox", StarCount: 1},
+		{Repository: "dave/d", Type: "dotflox", StarCount: 7},
+	}}
+
+	d := DiffExports(from, to)
+	if d.StarsBefore != 26 || d.StarsAfter != 32 {
+		t.Errorf("stars = %d -> %d, want 26 -> 32", d.StarsBefore, d.StarsAfter)
+	}
+	if len(d.Added) != 1 || d.Added[0].Repository != "dave/d" || d.Added[0].Delta != 7 {
+		t.Errorf("added = %+v", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].Repository != "carol/c" || d.Removed[0].Delta != -1 {
+		t.Errorf("removed = %+v", d.Removed)
+	}
+	if len(d.Changed) != 3 || d.Changed[0].Repository != "bob/b" || d.Changed[0].Delta != -4 ||
+		d.Changed[1].Type != "dotflox" || d.Changed[2].Type != "readme" {
+		t.Errorf("changed = %+v", d.Changed)
+	}
+}
+
+func TestDiffExports_Scope(t *testing.T) {
+	from := &Export{SchemaVersion: 2, Repositories: []RepoInfo{
+		{Repository: "alice/a", Type: "dotflox", StarCount: 10},
+	}}
+	to := &Export{SchemaVersion: 3, Scope: ScopeAll, Repositories: []RepoInfo{
+		{Repository: "alice/a", Type: "dotflox", StarCount: 10, Classification: ClassExternal},
+		{Repository: "flox/flox", Type: "dotflox", StarCount: 3000, Classification: ClassFloxOrg},
+		{Repository: "emp/dots", Type: "dotflox", StarCount: 2, Classification: ClassEmployee},
+		{Repository: "dave/d", Type: "dotflox", StarCount: 7, Classification: ClassExternal},
+	}}
+
+	d := DiffExports(from, to)
+	if len(d.Added) != 1 || d.Added[0].Repository != "dave/d" || d.StarsAfter != 17 {
+		t.Errorf("a scoped export against a full one should compare only repositories in scope, got %+v", d)
+	}
+	from.Scope = ScopeAll
+	if d := DiffExports(from, to); len(d.Added) != 3 {
+		t.Errorf("two full exports should compare every record, got %d added", len(d.Added))
+	}
+}
@@ -0,0 +1,133 @@
+package github
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/stahnma/gh-flox/internal/cache"
+)
+
+func init() {
+	gob.Register([]Environment{})
+}
+
+// Files making up a flox environment, relative to the directory containing
+// its .flox directory.
+const (
+	FileManifest = ".flox/env/manifest.toml"
+	FileLock     = ".flox/env/manifest.lock"
+	FileEnvJSON  = ".flox/env.json"
+)
+
+// EnvironmentFiles lists the environment files in the order they are
+// downloaded.
+var EnvironmentFiles = []string{FileManifest, FileLock, FileEnvJSON}
+
+// Environment is one flox environment in a repository.
+type Environment struct {
+	// Dir is the directory containing .flox, "." for the repository root.
+	Dir string
+	// Files holds the environment files present, in EnvironmentFiles order.
+	Files []EnvironmentFile
+}
+
+// EnvironmentFile is a file of an environment as recorded in the git tree.
+type EnvironmentFile struct {
+	// Name is one of EnvironmentFiles.
+	Name string
+	// Path is the file's path in the repository.
+	Path string
+	// SHA is the git blob SHA of the file's content.
+	SHA  string
+	Size int
+}
+
+// FindEnvironments lists every flox environment in a repository at ref,
+// identified by a .flox/env/manifest.toml, using the recursive git trees API.
+// An empty ref means the default branch. Environments are sorted by
+// directory and cached.
+func FindEnvironments(ctx context.Context, client Client, c *cache.Cache, owner, repo, ref string, noCache bool) ([]Environment, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	cacheKey := environmentsCacheKey(owner, repo, ref)
+	if !noCache {
+		if val, found := c.Get(cacheKey); found {
+			slog.Debug("cache hit", "key", cacheKey)
+			if envs, ok := val.([]Environment); ok {
+				return envs, nil
+			}
+		}
+		slog.Debug("cache miss", "key", cacheKey)
+	}
+
+	tree, resp, err := client.GetTree(ctx, owner, repo, ref, true)
+	if err != nil {
+		if isNotFound(resp) || isEmptyRepo(resp) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getting tree for %s/%s: %w", owner, repo, err)
+	}
+	if tree.GetTruncated() {
+		slog.Warn("tree listing truncated, some environments may be missed", "repo", owner+"/"+repo)
+	}
+
+	blobs := make(map[string]EnvironmentFile)
+	for _, entry := range tree.Entries {
+		if entry.GetType() == "blob" {
+			blobs[entry.GetPath()] = EnvironmentFile{Path: entry.GetPath(), SHA: entry.GetSHA(), Size: entry.GetSize()}
+		}
+	}
+
+	envs := []Environment{}
+	for p := range blobs {
+		dir, ok := environmentDir(p)
+		if !ok {
+			continue
+		}
+		env := Environment{Dir: dir}
+		for _, name := range EnvironmentFiles {
+			if f, ok := blobs[path.Join(dir, name)]; ok {
+				f.Name = name
+				env.Files = append(env.Files, f)
+			}
+		}
+		envs = append(envs, env)
+	}
+	sort.Slice(envs, func(i, j int) bool { return envs[i].Dir < envs[j].Dir })
+
+	if !noCache {
+		c.Set(cacheKey, envs)
+	}
+	return envs, nil
+}
+
+// environmentDir returns the directory containing the .flox directory if p is
+// an environment's manifest.toml.
+func environmentDir(p string) (string, bool) {
+	if p == FileManifest {
+		return ".", true
+	}
+	dir, ok := strings.CutSuffix(p, "/"+FileManifest)
+	return dir, ok
+}
+
+// BlobSHA returns the git blob SHA of data, as listed in git trees, so local
+// files can be compared with the repository without downloading them.
+func BlobSHA(data []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(data))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func environmentsCacheKey(owner, repo, ref string) string {
+	return fmt.Sprintf("environments:%s/%s@%s", owner, repo, ref)
+}
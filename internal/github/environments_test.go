@@ -0,0 +1,84 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	gh "github.com/google/go-github/v68/github"
+	"github.com/stahnma/gh-flox/internal/cache"
+)
+
+func treeEntry(path, typ, sha string) *gh.TreeEntry {
+	return &gh.TreeEntry{Path: gh.Ptr(path), Type: gh.Ptr(typ), SHA: gh.Ptr(sha), Size: gh.Ptr(len(sha))}
+}
+
+func TestFindEnvironments(t *testing.T) {
+	client := &mockClient{
+		getTreeFn: func(_ context.Context, _, _, sha string, recursive bool) (*gh.Tree, *gh.Response, error) {
+			if sha != "main" || !recursive {
+				t.Errorf("got sha %q recursive %v, want main true", sha, recursive)
+			}
+			return &gh.Tree{Entries: []*gh.TreeEntry{
+				treeEntry("README.md", "blob", "r"),
+				treeEntry(".flox/env/manifest.toml", "blob", "m0"),
+				treeEntry(".flox/env/manifest.lock", "blob", "l0"),
+				treeEntry(".flox/env.json", "blob", "e0"),
+				treeEntry("frontend/.flox/env/manifest.toml", "blob", "m1"),
+				treeEntry("backend/.flox/env/manifest.toml", "blob", "m2"),
+				treeEntry("backend/.flox/env.json", "blob", "e2"),
+				treeEntry("backend/.flox/env", "tree", "t"),
+				treeEntry("docs/not.flox/env/manifest.toml", "blob", "x"),
+				treeEntry("orphan/.flox/env/manifest.lock", "blob", "x"),
+			}}, emptyResponse(), nil
+		},
+	}
+	c := cache.New()
+
+	got, err := FindEnvironments(context.Background(), client, c, "owner", "repo", "main", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Environment{
+		{Dir: ".", Files: []EnvironmentFile{
+			{Name: FileManifest, Path: ".flox/env/manifest.toml", SHA: "m0", Size: 2},
+			{Name: FileLock, Path: ".flox/env/manifest.lock", SHA: "l0", Size: 2},
+			{Name: FileEnvJSON, Path: ".flox/env.json", SHA: "e0", Size: 2},
+		}},
+		{Dir: "backend", Files: []EnvironmentFile{
+			{Name: FileManifest, Path: "backend/.flox/env/manifest.toml", SHA: "m2", Size: 2},
+			{Name: FileEnvJSON, Path: "backend/.flox/env.json", SHA: "e2", Size: 2},
+		}},
+		{Dir: "frontend", Files: []EnvironmentFile{
+			{Name: FileManifest, Path: "frontend/.flox/env/manifest.toml", SHA: "m1", Size: 2},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	client.getTreeFn = nil
+	if cached, err := FindEnvironments(context.Background(), client, c, "owner", "repo", "main", false); err != nil || len(cached) != 3 {
+		t.Errorf("expected cached environments, got %v, %v", cached, err)
+	}
+}
+
+func TestFindEnvironments_Error(t *testing.T) {
+	client := &mockClient{
+		getTreeFn: func(_ context.Context, _, _, _ string, _ bool) (*gh.Tree, *gh.Response, error) {
+			return nil, nil, errors.New("tree error")
+		},
+	}
+
+	if _, err := FindEnvironments(context.Background(), client, cache.New(), "owner", "repo", "", true); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestBlobSHA(t *testing.T) {
+	// git hash-object of "hello\n"
+	if got := BlobSHA([]byte("hello\n")); got != "ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Errorf("got %s", got)
+	}
+}
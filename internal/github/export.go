@@ -0,0 +1,108 @@
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ExportSchemaVersion is the version of the export format written by Export.
+// Version 1 was a bare JSON array of RepoInfo records with only date,
+// repository, type and starcount. Version 2 exports held either the scoped
+// or the full set of repositories; version 3 holds every repository with its
+// classification, and Scope records whether it was narrowed.
+const ExportSchemaVersion = 3
+
+// Export scopes.
+const (
+	ScopeAll      = "all"
+	ScopeExternal = "external"
+)
+
+// Export is the versioned envelope written by the export command.
+type Export struct {
+	SchemaVersion int       `json:"schema_version"`
+	Date          string    `json:"date"`
+	GeneratedAt   time.Time `json:"generated_at"`
+	Incomplete    bool      `json:"incomplete,omitempty"`
+	// Scope is ScopeAll, or ScopeExternal when only repositories in scope
+	// were exported. Empty before version 3.
+	Scope string `json:"scope,omitempty"`
+	// Filter is the filter expression applied to Repositories, if any.
+	Filter string `json:"filter,omitempty"`
+	// Score is the adoption score of Repositories, if requested.
+	Score *AdoptionScore `json:"score,omitempty"`
+	// Comparison is flox's adoption next to other environment managers',
+	// if requested.
+	Comparison   []ToolAdoption `json:"comparison,omitempty"`
+	Repositories []RepoInfo     `json:"repositories"`
+}
+
+// AdoptionScore summarizes the activity-weighted adoption score in an export.
+// Per-repository contributions are recorded on each RepoInfo.
+type AdoptionScore struct {
+	Total   float64            `json:"total"`
+	Weights map[string]float64 `json:"weights"`
+}
+
+// Scoped reports whether e holds only repositories in scope. Exports before
+// version 3 do not record their scope and are taken to be scoped, as that was
+// the default.
+func (e *Export) Scoped() bool {
+	return e.Scope != ScopeAll
+}
+
+// ScopedRepositories returns the records of e in scope. Records without a
+// classification come from older, already scoped exports and are kept.
+func (e *Export) ScopedRepositories() []RepoInfo {
+	var out []RepoInfo
+	for _, r := range e.Repositories {
+		if r.Classification == "" || (Repo{Classification: r.Classification}).InScope() {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// ToolAdoption is how many repositories a tool's code searches found, their
+// stars and how many of them also use flox.
+type ToolAdoption struct {
+	Tool         string `json:"tool"`
+	Repositories int    `json:"repositories"`
+	Stars        int    `json:"stars"`
+	// Overlap counts the repositories that flox's searches found too. It is
+	// not set for flox itself.
+	Overlap             int      `json:"overlap"`
+	OverlapRepositories []string `json:"overlap_repositories,omitempty"`
+}
+
+// DecodeExport parses export JSON in any supported schema version. Version 1
+// arrays are wrapped in an Export with SchemaVersion 1.
+func DecodeExport(data []byte) (*Export, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("empty export")
+	}
+
+	if trimmed[0] == '[' {
+		var repos []RepoInfo
+		if err := json.Unmarshal(trimmed, &repos); err != nil {
+			return nil, fmt.Errorf("decoding v1 export: %w", err)
+		}
+		exp := &Export{SchemaVersion: 1, Repositories: repos}
+		if len(repos) > 0 {
+			exp.Date = repos[0].Date
+		}
+		return exp, nil
+	}
+
+	var exp Export
+	if err := json.Unmarshal(trimmed, &exp); err != nil {
+		return nil, fmt.Errorf("decoding export: %w", err)
+	}
+	if exp.SchemaVersion < 2 || exp.SchemaVersion > ExportSchemaVersion {
+		return nil, fmt.Errorf("unsupported export schema version %d", exp.SchemaVersion)
+	}
+	return &exp, nil
+}
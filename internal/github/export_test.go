@@ -0,0 +1,54 @@
+package github
+
+import (
+	"testing"
+)
+
+func TestDecodeExport_V1(t *testing.T) {
+	data := []byte(`[{"date":"2024-Jan-02","repository":"alice/p1","type":"dotflox","starcount":5}]`)
+	exp, err := DecodeExport(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp.SchemaVersion != 1 || exp.Date != "2024-Jan-02" {
+		t.Errorf("unexpected envelope: %+v", exp)
+	}
+	if len(exp.Repositories) != 1 || exp.Repositories[0].StarCount != 5 {
+		t.Errorf("unexpected repositories: %+v", exp.Repositories)
+	}
+}
+
+func TestDecodeExport_V2(t *testing.T) {
+	data := []byte(`{"schema_version":2,"date":"2024-Jan-02","repositories":[
+		{"date":"2024-Jan-02","repository":"alice/p1","type":"dotflox","starcount":5,"forks":2,"language":"Go","owner_type":"Organization","archived":true}
+	]}`)
+	exp, err := DecodeExport(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := exp.Repositories[0]
+	if r.Forks != 2 || r.Language != "Go" || r.OwnerType != "Organization" || !r.Archived {
+		t.Errorf("unexpected record: %+v", r)
+	}
+}
+
+func TestDecodeExport_V3(t *testing.T) {
+	data := []byte(`{"schema_version":3,"date":"2024-Jan-02","scope":"all","repositories":[
+		{"date":"2024-Jan-02","repository":"flox/flox","type":"dotflox","starcount":5,"classification":"flox-org"}
+	]}`)
+	exp, err := DecodeExport(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp.Scope != ScopeAll || exp.Repositories[0].Classification != ClassFloxOrg {
+		t.Errorf("unexpected export: %+v", exp)
+	}
+}
+
+func TestDecodeExport_Errors(t *testing.T) {
+	for _, data := range []string{"", "{", `{"schema_version":99,"repositories":[]}`} {
+		if _, err := DecodeExport([]byte(data)); err == nil {
+			t.Errorf("expected error for %q", data)
+		}
+	}
+}
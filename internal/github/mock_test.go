@@ -9,23 +9,71 @@ import (
 
 // mockClient implements Client for testing.
 type mockClient struct {
-	searchCodeFn    func(ctx context.Context, query string, opts *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error)
-	getRepositoryFn func(ctx context.Context, owner, repo string) (*gh.Repository, *gh.Response, error)
-	isOrgMemberFn   func(ctx context.Context, org, user string) (bool, *gh.Response, error)
+	searchCodeFn       func(ctx context.Context, query string, opts *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error)
+	searchReposFn      func(ctx context.Context, query string, opts *gh.SearchOptions) (*gh.RepositoriesSearchResult, *gh.Response, error)
+	getRepositoryFn    func(ctx context.Context, owner, repo string) (*gh.Repository, *gh.Response, error)
+	isOrgMemberFn      func(ctx context.Context, org, user string) (bool, *gh.Response, error)
+	rateLimitsFn       func(ctx context.Context) (*gh.RateLimits, *gh.Response, error)
+	listCommitsFn      func(ctx context.Context, owner, repo string, opts *gh.CommitsListOptions) ([]*gh.RepositoryCommit, *gh.Response, error)
+	listContributorsFn func(ctx context.Context, owner, repo string, opts *gh.ListContributorsOptions) ([]*gh.Contributor, *gh.Response, error)
+	getReadmeFn        func(ctx context.Context, owner, repo string, opts *gh.RepositoryContentGetOptions) (*gh.RepositoryContent, *gh.Response, error)
+	getTreeFn          func(ctx context.Context, owner, repo, sha string, recursive bool) (*gh.Tree, *gh.Response, error)
+	getCommitSHA1Fn    func(ctx context.Context, owner, repo, ref, lastSHA string) (string, *gh.Response, error)
+	getBlobRawFn       func(ctx context.Context, owner, repo, sha string) ([]byte, *gh.Response, error)
+	listStargazersFn   func(ctx context.Context, owner, repo string, opts *gh.ListOptions) ([]*gh.Stargazer, *gh.Response, error)
 }
 
 func (m *mockClient) SearchCode(ctx context.Context, query string, opts *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error) {
 	return m.searchCodeFn(ctx, query, opts)
 }
 
+func (m *mockClient) SearchRepositories(ctx context.Context, query string, opts *gh.SearchOptions) (*gh.RepositoriesSearchResult, *gh.Response, error) {
+	return m.searchReposFn(ctx, query, opts)
+}
+
 func (m *mockClient) GetRepository(ctx context.Context, owner, repo string) (*gh.Repository, *gh.Response, error) {
 	return m.getRepositoryFn(ctx, owner, repo)
 }
 
 func (m *mockClient) IsOrgMember(ctx context.Context, org, user string) (bool, *gh.Response, error) {
+	if m.isOrgMemberFn == nil {
+		return false, nil, nil
+	}
 	return m.isOrgMemberFn(ctx, org, user)
 }
 
+func (m *mockClient) GetRateLimits(ctx context.Context) (*gh.RateLimits, *gh.Response, error) {
+	return m.rateLimitsFn(ctx)
+}
+
+func (m *mockClient) ListCommits(ctx context.Context, owner, repo string, opts *gh.CommitsListOptions) ([]*gh.RepositoryCommit, *gh.Response, error) {
+	return m.listCommitsFn(ctx, owner, repo, opts)
+}
+
+func (m *mockClient) ListContributors(ctx context.Context, owner, repo string, opts *gh.ListContributorsOptions) ([]*gh.Contributor, *gh.Response, error) {
+	return m.listContributorsFn(ctx, owner, repo, opts)
+}
+
+func (m *mockClient) GetReadme(ctx context.Context, owner, repo string, opts *gh.RepositoryContentGetOptions) (*gh.RepositoryContent, *gh.Response, error) {
+	return m.getReadmeFn(ctx, owner, repo, opts)
+}
+
+func (m *mockClient) GetTree(ctx context.Context, owner, repo, sha string, recursive bool) (*gh.Tree, *gh.Response, error) {
+	return m.getTreeFn(ctx, owner, repo, sha, recursive)
+}
+
+func (m *mockClient) GetCommitSHA1(ctx context.Context, owner, repo, ref, lastSHA string) (string, *gh.Response, error) {
+	return m.getCommitSHA1Fn(ctx, owner, repo, ref, lastSHA)
+}
+
+func (m *mockClient) GetBlobRaw(ctx context.Context, owner, repo, sha string) ([]byte, *gh.Response, error) {
+	return m.getBlobRawFn(ctx, owner, repo, sha)
+}
+
+func (m *mockClient) ListStargazers(ctx context.Context, owner, repo string, opts *gh.ListOptions) ([]*gh.Stargazer, *gh.Response, error) {
+	return m.listStargazersFn(ctx, owner, repo, opts)
+}
+
 // emptyResponse returns a *gh.Response that signals no more pages.
 func emptyResponse() *gh.Response {
 	return &gh.Response{
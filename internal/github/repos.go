@@ -2,11 +2,15 @@ package github
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
+	"time"
 
 	gh "github.com/google/go-github/v68/github"
 	"github.com/stahnma/gh-flox/internal/cache"
+	"github.com/stahnma/gh-flox/internal/progress"
 )
 
 // excludedOrgs is the set of organizations excluded from non-full results.
@@ -30,51 +34,193 @@ func (mc *MembershipCache) Check(ctx context.Context, client Client, username, o
 	}
 	member, _, err := client.IsOrgMember(ctx, org, username)
 	if err != nil {
-		log.Printf("Error during membership check: %v", err)
 		return false, err
 	}
 	mc.entries[key] = member
 	return member, nil
 }
 
+// checked reports whether the membership of username in org is cached. A nil
+// cache has nothing checked.
+func (mc *MembershipCache) checked(username, org string) bool {
+	if mc == nil {
+		return false
+	}
+	_, ok := mc.entries[org+"/"+username]
+	return ok
+}
+
 // GetStarCount retrieves the star count for a repository, using the cache.
-func GetStarCount(ctx context.Context, client Client, c *cache.Cache, owner, repo string, noCache, debugMode bool) (int, error) {
-	cacheKey := fmt.Sprintf("starCount:%s/%s", owner, repo)
+func GetStarCount(ctx context.Context, client Client, c *cache.Cache, owner, repo string, noCache bool) (int, error) {
+	cacheKey := starCountCacheKey(owner, repo)
 	if !noCache {
 		if val, found := c.Get(cacheKey); found {
-			if debugMode {
-				log.Printf("Cache hit for key: %s", cacheKey)
-			}
+			slog.Debug("cache hit", "key", cacheKey)
 			if stars, ok := val.(int); ok {
 				return stars, nil
 			}
 		}
-		if debugMode {
-			log.Printf("Cache miss for key: %s", cacheKey)
-		}
+		slog.Debug("cache miss", "key", cacheKey)
 	}
 
-	repository, _, err := client.GetRepository(ctx, owner, repo)
+	meta, err := GetRepoMetadata(ctx, client, c, owner, repo, noCache)
 	if err != nil {
 		return 0, err
 	}
-	starCount := repository.GetStargazersCount()
 	if !noCache {
-		c.Set(cacheKey, starCount)
+		c.Set(cacheKey, meta.Stars)
 	}
-	return starCount, nil
+	return meta.Stars, nil
 }
 
-// FetchManifestPath searches for the manifest.toml path in a repository.
-func FetchManifestPath(ctx context.Context, client Client, owner, repo string) (string, error) {
-	query := fmt.Sprintf("manifest.toml repo:%s/%s path:.flox/env", owner, repo)
-	options := &gh.SearchOptions{ListOptions: gh.ListOptions{PerPage: 1}}
-	results, _, err := client.SearchCode(ctx, query, options)
+// GetRepoMetadata retrieves a repository's metadata, using the cache.
+func GetRepoMetadata(ctx context.Context, client Client, c *cache.Cache, owner, repo string, noCache bool) (RepoMetadata, error) {
+	cacheKey := repoMetadataCacheKey(owner, repo)
+	if !noCache {
+		if val, found := c.Get(cacheKey); found {
+			slog.Debug("cache hit", "key", cacheKey)
+			if meta, ok := val.(RepoMetadata); ok {
+				return meta, nil
+			}
+		}
+		slog.Debug("cache miss", "key", cacheKey)
+	}
+
+	start := time.Now()
+	repository, _, err := client.GetRepository(ctx, owner, repo)
 	if err != nil {
-		return "", err
+		return RepoMetadata{}, err
+	}
+	slog.Debug("fetched repository", "repo", owner+"/"+repo, "duration", time.Since(start))
+
+	meta := metadataFromRepository(repository)
+	if !noCache {
+		c.Set(cacheKey, meta)
+	}
+	return meta, nil
+}
+
+// repoSearchBatch is the number of repositories looked up per repository
+// search.
+const repoSearchBatch = 50
+
+// searchRepoMetadata fills in the metadata of repos from the cache or with
+// repository searches of up to repoSearchBatch repositories each, which count
+// against the search rate limit instead of the core one. Repositories the
+// search does not return keep empty metadata.
+func searchRepoMetadata(ctx context.Context, client Client, c *cache.Cache, repos []Repo, noCache bool, p progress.Reporter) error {
+	var pending []int
+	for i, r := range repos {
+		if !noCache {
+			if meta, ok := cachedSearchMetadata(c, r.Owner, r.Name); ok {
+				repos[i].RepoMetadata = meta
+				p.Report(progress.Event{Kind: progress.CacheHit})
+				continue
+			}
+		}
+		pending = append(pending, i)
+	}
+
+	for len(pending) > 0 {
+		batch := pending[:min(repoSearchBatch, len(pending))]
+		pending = pending[len(batch):]
+		terms := make([]string, len(batch))
+		for j, i := range batch {
+			terms[j] = "repo:" + repos[i].FullName()
+		}
+		start := time.Now()
+		result, err := searchRepositoriesPaced(ctx, client, strings.Join(terms, " "))
+		if err != nil {
+			return err
+		}
+		slog.Debug("repository search fetched", "repos", len(batch), "results", len(result.Repositories), "duration", time.Since(start))
+
+		found := make(map[string]*gh.Repository, len(result.Repositories))
+		for _, r := range result.Repositories {
+			found[strings.ToLower(r.GetFullName())] = r
+		}
+		for _, i := range batch {
+			p.Report(progress.Event{Kind: progress.StarsFetched})
+			r, ok := found[strings.ToLower(repos[i].FullName())]
+			if !ok {
+				slog.Warn("repository search did not return repository", "repo", repos[i].FullName())
+				continue
+			}
+			repos[i].RepoMetadata = metadataFromRepository(r)
+			if !noCache {
+				c.Set(repoSearchMetadataCacheKey(repos[i].Owner, repos[i].Name), repos[i].RepoMetadata)
+			}
+		}
 	}
-	if len(results.CodeResults) == 0 {
-		return "", nil
+	return nil
+}
+
+// searchRateRetries is the number of times a repository search waits for the
+// search rate limit to reset before giving up.
+const searchRateRetries = 3
+
+// searchRepositoriesPaced runs a repository search. The search rate limit
+// allows only 30 searches a minute, fewer than a cold compare needs, so when
+// it is exhausted the search waits for the reset and is retried.
+func searchRepositoriesPaced(ctx context.Context, client Client, query string) (*gh.RepositoriesSearchResult, error) {
+	for attempt := 0; ; attempt++ {
+		result, _, err := client.SearchRepositories(ctx, query, &gh.SearchOptions{ListOptions: gh.ListOptions{PerPage: repoSearchBatch}})
+		var rateErr *gh.RateLimitError
+		if !errors.As(err, &rateErr) || attempt == searchRateRetries {
+			return result, err
+		}
+		wait := time.Until(rateErr.Rate.Reset.Time)
+		slog.Info("search rate limit reached, waiting for reset", "wait", wait.Round(time.Second))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
 	}
-	return results.CodeResults[0].GetPath(), nil
+}
+
+// cachedSearchMetadata returns the cached metadata searchRepoMetadata would
+// fetch, preferring a full entry from GetRepoMetadata over a search result.
+func cachedSearchMetadata(c *cache.Cache, owner, repo string) (RepoMetadata, bool) {
+	for _, key := range []string{repoMetadataCacheKey(owner, repo), repoSearchMetadataCacheKey(owner, repo)} {
+		if val, found := c.Get(key); found {
+			if meta, ok := val.(RepoMetadata); ok {
+				return meta, true
+			}
+		}
+	}
+	return RepoMetadata{}, false
+}
+
+func metadataFromRepository(r *gh.Repository) RepoMetadata {
+	return RepoMetadata{
+		Stars:         r.GetStargazersCount(),
+		Forks:         r.GetForksCount(),
+		Watchers:      r.GetSubscribersCount(),
+		Language:      r.GetLanguage(),
+		Topics:        r.Topics,
+		License:       r.GetLicense().GetSPDXID(),
+		Archived:      r.GetArchived(),
+		Fork:          r.GetFork(),
+		Template:      r.GetIsTemplate(),
+		CreatedAt:     r.GetCreatedAt().Time,
+		PushedAt:      r.GetPushedAt().Time,
+		OwnerType:     r.GetOwner().GetType(),
+		DefaultBranch: r.GetDefaultBranch(),
+		Description:   r.GetDescription(),
+	}
+}
+
+func starCountCacheKey(owner, repo string) string {
+	return fmt.Sprintf("starCount:%s/%s", owner, repo)
+}
+
+func repoMetadataCacheKey(owner, repo string) string {
+	return fmt.Sprintf("repoMetadata:%s/%s", owner, repo)
+}
+
+// repoSearchMetadataCacheKey holds metadata from the repository search, which
+// lacks the watcher count, apart from the full entries of GetRepoMetadata.
+func repoSearchMetadataCacheKey(owner, repo string) string {
+	return fmt.Sprintf("repoMetadata:search:%s/%s", owner, repo)
 }
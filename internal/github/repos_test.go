@@ -87,7 +87,7 @@ func TestGetStarCount_CacheMiss(t *testing.T) {
 		},
 	}
 
-	got, err := GetStarCount(context.Background(), client, c, "owner", "repo", false, false)
+	got, err := GetStarCount(context.Background(), client, c, "owner", "repo", false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -116,7 +116,7 @@ func TestGetStarCount_CacheHit(t *testing.T) {
 		},
 	}
 
-	got, err := GetStarCount(context.Background(), client, c, "owner", "repo", false, false)
+	got, err := GetStarCount(context.Background(), client, c, "owner", "repo", false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -138,7 +138,7 @@ func TestGetStarCount_NoCache(t *testing.T) {
 		},
 	}
 
-	got, err := GetStarCount(context.Background(), client, c, "owner", "repo", true, false)
+	got, err := GetStarCount(context.Background(), client, c, "owner", "repo", true)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -155,60 +155,8 @@ func TestGetStarCount_Error(t *testing.T) {
 		},
 	}
 
-	_, err := GetStarCount(context.Background(), client, c, "owner", "repo", true, false)
+	_, err := GetStarCount(context.Background(), client, c, "owner", "repo", true)
 	if err == nil {
 		t.Error("expected error from GetStarCount")
 	}
 }
-
-// --- FetchManifestPath ---
-
-func TestFetchManifestPath_Found(t *testing.T) {
-	path := ".flox/env/manifest.toml"
-	client := &mockClient{
-		searchCodeFn: func(_ context.Context, _ string, _ *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error) {
-			return &gh.CodeSearchResult{
-				CodeResults: []*gh.CodeResult{
-					{Path: &path},
-				},
-			}, emptyResponse(), nil
-		},
-	}
-
-	got, err := FetchManifestPath(context.Background(), client, "owner", "repo")
-	if err != nil {
-		t.Fatal(err)
-	}
-	if got != path {
-		t.Errorf("got %q, want %q", got, path)
-	}
-}
-
-func TestFetchManifestPath_NotFound(t *testing.T) {
-	client := &mockClient{
-		searchCodeFn: func(_ context.Context, _ string, _ *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error) {
-			return &gh.CodeSearchResult{CodeResults: []*gh.CodeResult{}}, emptyResponse(), nil
-		},
-	}
-
-	got, err := FetchManifestPath(context.Background(), client, "owner", "repo")
-	if err != nil {
-		t.Fatal(err)
-	}
-	if got != "" {
-		t.Errorf("expected empty path, got %q", got)
-	}
-}
-
-func TestFetchManifestPath_Error(t *testing.T) {
-	client := &mockClient{
-		searchCodeFn: func(_ context.Context, _ string, _ *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error) {
-			return nil, nil, errors.New("search error")
-		},
-	}
-
-	_, err := FetchManifestPath(context.Background(), client, "owner", "repo")
-	if err == nil {
-		t.Error("expected error")
-	}
-}
@@ -3,78 +3,245 @@ package github
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"sort"
+	"time"
 
 	gh "github.com/google/go-github/v68/github"
 	"github.com/stahnma/gh-flox/internal/cache"
+	"github.com/stahnma/gh-flox/internal/progress"
 )
 
+// Search result limits imposed by the GitHub code search API.
+const (
+	searchPageSize = 100
+	maxSearchPages = 10
+)
+
+// Signature is what marks a repository as using a tool in code search: a
+// file at a path, a phrase in its README, or a raw code search query. Exactly
+// one of Query, Path and Phrase is set.
+type Signature struct {
+	// Name labels the search in progress output.
+	Name   string `json:"name,omitempty"`
+	Query  string `json:"query,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Phrase string `json:"phrase,omitempty"`
+}
+
+// The signatures of flox adoption.
+var (
+	ManifestSignature = Signature{Name: "manifest search", Path: ".flox/env/manifest.toml"}
+	ReadmeSignature   = Signature{Name: "readme search", Phrase: "flox install"}
+)
+
+// Validate checks that exactly one way of searching is set.
+func (s Signature) Validate() error {
+	set := 0
+	for _, v := range []string{s.Query, s.Path, s.Phrase} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("search signature %q: exactly one of query, path and phrase must be set", s.Name)
+	}
+	return nil
+}
+
+// SearchQuery returns the code search query for s.
+func (s Signature) SearchQuery() string {
+	switch {
+	case s.Query != "":
+		return s.Query
+	case s.Path != "":
+		return s.Path + " in:path"
+	default:
+		return fmt.Sprintf("%q in:file filename:README", s.Phrase)
+	}
+}
+
+func (s Signature) stage() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.SearchQuery()
+}
+
+// cacheKey is the key of the signature's classified results, shared by the
+// scoped and full views. Results classified by owner alone are kept apart.
+func (s Signature) cacheKey(opts SearchOptions) string {
+	key := "searchRepos:" + s.SearchQuery() + ":v4"
+	if opts.SkipClassification {
+		key += ":owner"
+	}
+	return key
+}
+
 // FindManifestRepos searches for repositories containing .flox/env/manifest.toml.
+// If ctx is canceled mid-search, the repositories found so far are returned
+// together with the context's error.
 func FindManifestRepos(ctx context.Context, client Client, c *cache.Cache, mc *MembershipCache, opts SearchOptions) ([]Repo, error) {
-	return findRepos(ctx, client, c, mc, ".flox/env/manifest.toml in:path", "floxManifestRepos", opts)
+	return FindRepos(ctx, client, c, mc, ManifestSignature, opts)
 }
 
 // FindReadmeRepos searches for repositories containing "flox install" in their README.
 func FindReadmeRepos(ctx context.Context, client Client, c *cache.Cache, mc *MembershipCache, opts SearchOptions) ([]Repo, error) {
-	return findRepos(ctx, client, c, mc, "\"flox install\" in:file filename:README", "floxReadmeRepos", opts)
+	return FindRepos(ctx, client, c, mc, ReadmeSignature, opts)
 }
 
-func findRepos(ctx context.Context, client Client, c *cache.Cache, mc *MembershipCache, query, cacheKeyPrefix string, opts SearchOptions) ([]Repo, error) {
-	cacheKey := fmt.Sprintf("%s:v2:%t", cacheKeyPrefix, opts.ShowFull)
+// EstimateSearchBudget estimates the API calls needed to run the searches of
+// sigs, taking cached results and the memberships already in mc into account.
+func EstimateSearchBudget(c *cache.Cache, mc *MembershipCache, opts SearchOptions, sigs ...Signature) Budget {
+	var b Budget
+	for _, sig := range sigs {
+		// Searches classify and enrich every repository, whatever the view.
+		repos, found := cachedRepos(c, sig, SearchOptions{ShowFull: true, NoCache: opts.NoCache, SkipClassification: opts.SkipClassification})
+		if !found {
+			// Unknown result size: assume the search API maximum, one
+			// membership check and one star lookup per result, or one
+			// repository search per batch without classification.
+			b.CodeSearch += maxSearchPages
+			if opts.SkipClassification {
+				b.Search += maxSearchPages * searchPageSize / repoSearchBatch
+			} else {
+				b.Core += 2 * maxSearchPages * searchPageSize
+			}
+			continue
+		}
+		owners := make(map[string]bool)
+		uncached := 0
+		for _, r := range repos {
+			if opts.SkipClassification {
+				if _, hit := cachedSearchMetadata(c, r.Owner, r.Name); !hit {
+					uncached++
+				}
+			} else if _, hit := c.Get(repoMetadataCacheKey(r.Owner, r.Name)); !hit {
+				uncached++
+			}
+			if !opts.SkipClassification && !excludedOrgs[r.Owner] && !mc.checked(r.Owner, "flox") {
+				owners[r.Owner] = true
+			}
+		}
+		if opts.SkipClassification {
+			b.Search += (uncached + repoSearchBatch - 1) / repoSearchBatch
+		} else {
+			b.Core += uncached + len(owners)
+		}
+	}
+	return b
+}
+
+func cachedRepos(c *cache.Cache, sig Signature, opts SearchOptions) ([]Repo, bool) {
+	if opts.NoCache {
+		return nil, false
+	}
+	val, found := c.Get(sig.cacheKey(opts))
+	if !found {
+		return nil, false
+	}
+	repos, ok := val.([]Repo)
+	return ScopeRepos(repos, opts.ShowFull), ok
+}
+
+// FindRepos runs the code search of sig and classifies every repository
+// found. The classified results are cached once; opts.ShowFull only selects
+// the view. If ctx is canceled mid-search, the repositories found so far are
+// returned together with the context's error.
+func FindRepos(ctx context.Context, client Client, c *cache.Cache, mc *MembershipCache, sig Signature, opts SearchOptions) ([]Repo, error) {
+	p := progress.OrNop(opts.Progress)
+	p.Report(progress.Event{Kind: progress.StageStarted, Stage: sig.stage()})
+
+	query := sig.SearchQuery()
+	cacheKey := sig.cacheKey(opts)
 	if !opts.NoCache {
 		if val, found := c.Get(cacheKey); found {
-			if opts.DebugMode {
-				log.Printf("Cache hit for key: %s", cacheKey)
-			}
+			slog.Debug("cache hit", "key", cacheKey)
 			if repos, ok := val.([]Repo); ok {
-				return repos, nil
+				p.Report(progress.Event{Kind: progress.CacheHit})
+				return ScopeRepos(repos, opts.ShowFull), nil
 			}
 		}
-		if opts.DebugMode {
-			log.Printf("Cache miss for key: %s", cacheKey)
-		}
+		slog.Debug("cache miss", "key", cacheKey)
+	}
+	partial := func(repos []Repo) []Repo {
+		return ScopeRepos(sortRepos(repos), opts.ShowFull)
 	}
 
 	seen := make(map[string]bool)
 	var repositories []Repo
+	// Results with unknown classifications are not cached, so the next
+	// search retries the membership checks.
+	unclassified := false
 
-	options := &gh.SearchOptions{ListOptions: gh.ListOptions{PerPage: 100}}
+	options := &gh.SearchOptions{ListOptions: gh.ListOptions{PerPage: searchPageSize}}
 
 	for {
+		start := time.Now()
 		results, response, err := client.SearchCode(ctx, query, options)
 		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return partial(repositories), ctxErr
+			}
 			return nil, err
 		}
+		slog.Debug("search page fetched", "query", query, "page", max(options.Page, 1), "results", len(results.CodeResults), "duration", time.Since(start))
+		p.Report(progress.Event{Kind: progress.PageFetched, Total: min(results.GetTotal(), maxSearchPages*searchPageSize)})
 
 		for _, item := range results.CodeResults {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return partial(repositories), ctxErr
+			}
+
 			owner := item.Repository.GetOwner().GetLogin()
 			name := item.Repository.GetName()
 			fullName := owner + "/" + name
 
+			p.Report(progress.Event{Kind: progress.RepoProcessed})
 			if seen[fullName] {
 				continue
 			}
 			seen[fullName] = true
 
-			if !opts.ShowFull {
+			repo := Repo{Owner: owner, Name: name, Classification: ClassFloxOrg}
+			switch {
+			case excludedOrgs[owner]:
+			case opts.SkipClassification:
+				repo.Classification = ClassExternal
+			default:
 				isMember, err := mc.Check(ctx, client, owner, "flox")
-				if err != nil {
-					log.Printf("Error checking membership: %v", err)
-					continue
-				}
-				if isMember {
-					continue
-				}
-				if excludedOrgs[owner] {
-					continue
+				switch {
+				case err != nil:
+					if ctxErr := ctx.Err(); ctxErr != nil {
+						return partial(repositories), ctxErr
+					}
+					slog.Warn("membership check failed", "repo", fullName, "err", err)
+					repo.Classification = ClassUnknown
+					unclassified = true
+				case isMember:
+					repo.Classification = ClassEmployee
+				default:
+					repo.Classification = ClassExternal
 				}
+				p.Report(progress.Event{Kind: progress.MembershipChecked})
+			}
+			if opts.SkipClassification {
+				repositories = append(repositories, repo)
+				continue
 			}
 
-			repo := Repo{Owner: owner, Name: name}
-			stars, err := GetStarCount(ctx, client, c, owner, name, opts.NoCache, opts.DebugMode)
+			if _, hit := c.Get(repoMetadataCacheKey(owner, name)); hit && !opts.NoCache {
+				p.Report(progress.Event{Kind: progress.CacheHit})
+			} else {
+				p.Report(progress.Event{Kind: progress.StarsFetched})
+			}
+			meta, err := GetRepoMetadata(ctx, client, c, owner, name, opts.NoCache)
 			if err == nil {
-				repo.Stars = stars
+				repo.RepoMetadata = meta
+			} else if ctxErr := ctx.Err(); ctxErr != nil {
+				return partial(repositories), ctxErr
+			} else {
+				slog.Warn("repository metadata lookup failed", "repo", fullName, "err", err)
 			}
 			repositories = append(repositories, repo)
 		}
@@ -85,13 +252,27 @@ func findRepos(ctx context.Context, client Client, c *cache.Cache, mc *Membershi
 		options.Page = response.NextPage
 	}
 
-	sort.Slice(repositories, func(i, j int) bool {
-		return repositories[i].FullName() < repositories[j].FullName()
-	})
-	if !opts.NoCache {
+	if opts.SkipClassification {
+		if err := searchRepoMetadata(ctx, client, c, repositories, opts.NoCache, p); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return partial(repositories), ctxErr
+			}
+			return nil, err
+		}
+	}
+	sortRepos(repositories)
+	if !opts.NoCache && !unclassified {
 		c.Set(cacheKey, repositories)
 	}
-	return repositories, nil
+	return ScopeRepos(repositories, opts.ShowFull), nil
+}
+
+// sortRepos sorts repos alphabetically by full name in place and returns it.
+func sortRepos(repos []Repo) []Repo {
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].FullName() < repos[j].FullName()
+	})
+	return repos
 }
 
 func sumStars(repos []Repo) int {
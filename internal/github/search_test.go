@@ -3,10 +3,14 @@ package github
 import (
 	"context"
 	"errors"
+	"fmt"
+	"reflect"
 	"testing"
+	"time"
 
 	gh "github.com/google/go-github/v68/github"
 	"github.com/stahnma/gh-flox/internal/cache"
+	"github.com/stahnma/gh-flox/internal/progress"
 )
 
 func newSearchClient(results []*gh.CodeResult) *mockClient {
@@ -141,6 +145,69 @@ func TestFindManifestRepos_ShowFull(t *testing.T) {
 	}
 }
 
+func TestFindManifestRepos_ClassifiesOnce(t *testing.T) {
+	calls := 0
+	client := newSearchClient([]*gh.CodeResult{
+		makeCodeResult("flox", "internal"),
+		makeCodeResult("employee", "dotfiles"),
+		makeCodeResult("alice", "project"),
+		makeCodeResult("bob", "flaky"),
+	})
+	search := client.searchCodeFn
+	client.searchCodeFn = func(ctx context.Context, q string, o *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error) {
+		calls++
+		return search(ctx, q, o)
+	}
+	client.isOrgMemberFn = func(_ context.Context, _, user string) (bool, *gh.Response, error) {
+		switch user {
+		case "flox":
+			t.Error("membership should not be checked for flox organizations")
+		case "bob":
+			return false, nil, errors.New("boom")
+		}
+		return user == "employee", emptyResponse(), nil
+	}
+
+	c := cache.New()
+	full, err := FindManifestRepos(context.Background(), client, c, NewMembershipCache(), SearchOptions{ShowFull: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, r := range full {
+		got[r.FullName()] = r.Classification
+	}
+	want := map[string]string{
+		"flox/internal":     ClassFloxOrg,
+		"employee/dotfiles": ClassEmployee,
+		"alice/project":     ClassExternal,
+		"bob/flaky":         ClassUnknown,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("classifications = %v, want %v", got, want)
+	}
+	if _, found := c.Get(ManifestSignature.cacheKey(SearchOptions{})); found {
+		t.Error("results with unknown classifications should not be cached")
+	}
+
+	client.isOrgMemberFn = func(_ context.Context, _, user string) (bool, *gh.Response, error) {
+		return user == "employee", emptyResponse(), nil
+	}
+	if _, err := FindManifestRepos(context.Background(), client, c, NewMembershipCache(), SearchOptions{ShowFull: true}); err != nil {
+		t.Fatal(err)
+	}
+	scoped, err := FindManifestRepos(context.Background(), client, c, NewMembershipCache(), SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scoped) != 2 || scoped[0].FullName() != "alice/project" || scoped[1].FullName() != "bob/flaky" {
+		t.Errorf("scoped = %v, want alice/project and bob/flaky", scoped)
+	}
+	if calls != 2 {
+		t.Errorf("searches = %d, want 2: the scoped view should reuse the cached full search", calls)
+	}
+}
+
 func TestFindManifestRepos_FetchesStars(t *testing.T) {
 	client := newSearchClient([]*gh.CodeResult{
 		makeCodeResult("alice", "project1"),
@@ -330,3 +397,208 @@ func TestFindManifestRepos_Pagination(t *testing.T) {
 		t.Errorf("expected 2 pages fetched, got %d", page)
 	}
 }
+
+func TestFindManifestRepos_CanceledReturnsPartial(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	page := 0
+	client := newSearchClient(nil)
+	client.searchCodeFn = func(ctx context.Context, _ string, _ *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error) {
+		page++
+		if page > 1 {
+			cancel()
+			return nil, nil, ctx.Err()
+		}
+		resp := emptyResponse()
+		resp.NextPage = 2
+		return &gh.CodeSearchResult{CodeResults: []*gh.CodeResult{makeCodeResult("alice", "project1")}}, resp, nil
+	}
+
+	c := cache.New()
+	repos, err := FindManifestRepos(ctx, client, c, NewMembershipCache(), SearchOptions{ShowFull: true})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(repos) != 1 || repos[0].FullName() != "alice/project1" {
+		t.Errorf("expected partial results, got %v", repos)
+	}
+	if _, found := c.Get(ManifestSignature.cacheKey(SearchOptions{})); found {
+		t.Error("partial results should not be cached")
+	}
+}
+
+type recordingReporter struct {
+	events []progress.Event
+}
+
+func (r *recordingReporter) Report(e progress.Event) { r.events = append(r.events, e) }
+func (r *recordingReporter) Done()                   {}
+
+func (r *recordingReporter) count(k progress.Kind) int {
+	n := 0
+	for _, e := range r.events {
+		if e.Kind == k {
+			n++
+		}
+	}
+	return n
+}
+
+func TestFindManifestRepos_ReportsProgress(t *testing.T) {
+	client := newSearchClient([]*gh.CodeResult{
+		makeCodeResult("alice", "project1"),
+		makeCodeResult("bob", "project2"),
+	})
+	rec := &recordingReporter{}
+
+	_, err := FindManifestRepos(context.Background(), client, cache.New(), NewMembershipCache(), SearchOptions{Progress: rec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.count(progress.StageStarted) != 1 || rec.count(progress.PageFetched) != 1 {
+		t.Errorf("expected one stage and one page event, got %+v", rec.events)
+	}
+	if rec.count(progress.RepoProcessed) != 2 || rec.count(progress.MembershipChecked) != 2 || rec.count(progress.StarsFetched) != 2 {
+		t.Errorf("expected per-repo events, got %+v", rec.events)
+	}
+}
+
+func TestSignature(t *testing.T) {
+	if q := ManifestSignature.SearchQuery(); q != ".flox/env/manifest.toml in:path" {
+		t.Errorf("manifest query = %q", q)
+	}
+	if q := ReadmeSignature.SearchQuery(); q != `"flox install" in:file filename:README` {
+		t.Errorf("readme query = %q", q)
+	}
+	if q := (Signature{Query: "devShells filename:flake.nix"}).SearchQuery(); q != "devShells filename:flake.nix" {
+		t.Errorf("raw query = %q", q)
+	}
+	for _, s := range []Signature{{}, {Path: "devbox.json", Phrase: "devbox shell"}} {
+		if err := s.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", s)
+		}
+	}
+}
+
+func TestFindRepos_Signature(t *testing.T) {
+	client := newSearchClient([]*gh.CodeResult{makeCodeResult("alice", "project1")})
+	var queries []string
+	search := client.searchCodeFn
+	client.searchCodeFn = func(ctx context.Context, q string, o *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error) {
+		queries = append(queries, q)
+		return search(ctx, q, o)
+	}
+	c := cache.New()
+	devbox := Signature{Name: "devbox search", Path: "devbox.json"}
+	if _, err := FindRepos(context.Background(), client, c, NewMembershipCache(), devbox, SearchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FindManifestRepos(context.Background(), client, c, NewMembershipCache(), SearchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FindRepos(context.Background(), client, c, NewMembershipCache(), devbox, SearchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"devbox.json in:path", ".flox/env/manifest.toml in:path"}; !reflect.DeepEqual(queries, want) {
+		t.Errorf("queries = %q, want %q: each signature should be cached separately", queries, want)
+	}
+}
+
+func TestFindRepos_SkipClassification(t *testing.T) {
+	client := newSearchClient([]*gh.CodeResult{
+		makeCodeResult("alice", "project1"),
+		makeCodeResult("flox", "flox"),
+		makeCodeResult("bob", "gone"),
+	})
+	client.isOrgMemberFn = func(_ context.Context, _, user string) (bool, *gh.Response, error) {
+		t.Errorf("membership of %s should not be checked", user)
+		return false, emptyResponse(), nil
+	}
+	client.getRepositoryFn = func(_ context.Context, owner, repo string) (*gh.Repository, *gh.Response, error) {
+		t.Errorf("%s/%s should be looked up by repository search", owner, repo)
+		return &gh.Repository{}, emptyResponse(), nil
+	}
+	var queries []string
+	client.searchReposFn = func(_ context.Context, q string, _ *gh.SearchOptions) (*gh.RepositoriesSearchResult, *gh.Response, error) {
+		queries = append(queries, q)
+		return &gh.RepositoriesSearchResult{Repositories: []*gh.Repository{
+			{FullName: gh.Ptr("Alice/Project1"), StargazersCount: gh.Ptr(7)},
+			{FullName: gh.Ptr("flox/flox"), StargazersCount: gh.Ptr(3000)},
+		}}, emptyResponse(), nil
+	}
+
+	c := cache.New()
+	sig := Signature{Name: "devbox search", Path: "devbox.json"}
+	opts := SearchOptions{ShowFull: true, SkipClassification: true}
+	repos, err := FindRepos(context.Background(), client, c, nil, sig, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, r := range repos {
+		got[fmt.Sprintf("%s %d", r.FullName(), r.Stars)] = r.Classification
+	}
+	want := map[string]string{
+		"alice/project1 7": ClassExternal,
+		"bob/gone 0":       ClassExternal,
+		"flox/flox 3000":   ClassFloxOrg,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("repositories = %v, want %v", got, want)
+	}
+	if len(queries) != 1 || queries[0] != "repo:alice/project1 repo:flox/flox repo:bob/gone" {
+		t.Errorf("repository searches = %q, want one batch", queries)
+	}
+	if _, found := c.Get(sig.cacheKey(SearchOptions{})); found {
+		t.Error("results classified by owner alone should not be cached as classified ones")
+	}
+	if _, found := c.Get(repoMetadataCacheKey("alice", "project1")); found {
+		t.Error("search results lack watchers and should not be cached as full metadata")
+	}
+	if _, found := c.Get(repoSearchMetadataCacheKey("alice", "project1")); !found {
+		t.Error("expected the search result to be cached")
+	}
+	if b := EstimateSearchBudget(cache.New(), nil, opts, sig); b.Core != 0 || b.Search != maxSearchPages*searchPageSize/repoSearchBatch {
+		t.Errorf("uncached estimate = %+v, want only code and repository searches", b)
+	}
+}
+
+func TestSearchRepoMetadata_PrefersFullMetadata(t *testing.T) {
+	client := newSearchClient(nil)
+	client.searchReposFn = func(_ context.Context, q string, _ *gh.SearchOptions) (*gh.RepositoriesSearchResult, *gh.Response, error) {
+		t.Errorf("unexpected repository search %q", q)
+		return &gh.RepositoriesSearchResult{}, emptyResponse(), nil
+	}
+	c := cache.New()
+	c.Set(repoMetadataCacheKey("flox", "flox"), RepoMetadata{Stars: 3000, Watchers: 40})
+	c.Set(repoSearchMetadataCacheKey("flox", "flox"), RepoMetadata{Stars: 2900})
+	repos := []Repo{{Owner: "flox", Name: "flox"}}
+	if err := searchRepoMetadata(context.Background(), client, c, repos, false, progress.Nop{}); err != nil {
+		t.Fatal(err)
+	}
+	if repos[0].Stars != 3000 || repos[0].Watchers != 40 {
+		t.Errorf("metadata = %+v, want the full cache entry", repos[0].RepoMetadata)
+	}
+}
+
+func TestSearchRepoMetadata_WaitsForSearchReset(t *testing.T) {
+	client := newSearchClient(nil)
+	calls := 0
+	client.searchReposFn = func(_ context.Context, q string, _ *gh.SearchOptions) (*gh.RepositoriesSearchResult, *gh.Response, error) {
+		calls++
+		if calls == 1 {
+			return nil, emptyResponse(), &gh.RateLimitError{Rate: gh.Rate{Reset: gh.Timestamp{Time: time.Now()}}}
+		}
+		return &gh.RepositoriesSearchResult{Repositories: []*gh.Repository{
+			{FullName: gh.Ptr("flox/flox"), StargazersCount: gh.Ptr(3000)},
+		}}, emptyResponse(), nil
+	}
+	repos := []Repo{{Owner: "flox", Name: "flox"}}
+	if err := searchRepoMetadata(context.Background(), client, cache.New(), repos, false, progress.Nop{}); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || repos[0].Stars != 3000 {
+		t.Errorf("calls = %d, stars = %d; want a retry after the reset", calls, repos[0].Stars)
+	}
+}
@@ -0,0 +1,94 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+	"github.com/stahnma/gh-flox/internal/cache"
+	"github.com/stahnma/gh-flox/internal/progress"
+)
+
+// stargazerPageSize is the page size used to list stargazers. Cached lists
+// are resumed by page, so it must not change between runs.
+const stargazerPageSize = 100
+
+// Star is one stargazer of a repository.
+type Star struct {
+	User      string
+	StarredAt time.Time
+}
+
+// errStargazersShifted reports that a resumed listing no longer lines up
+// with the cached one, because stars were removed since.
+var errStargazersShifted = errors.New("stargazer pages shifted")
+
+// GetStargazers returns the stargazers of owner/repo, oldest first, with
+// when they starred it. The list is cached permanently and extended
+// incrementally: only the last cached page, which may have been partial, and
+// newer pages are fetched. If that page no longer starts with the cached
+// stargazer, stars were removed and the whole list is fetched again.
+func GetStargazers(ctx context.Context, client Client, c *cache.Cache, owner, repo string, noCache bool, p progress.Reporter) ([]Star, error) {
+	p = progress.OrNop(p)
+	cacheKey := stargazersCacheKey(owner, repo)
+	var cached []Star
+	if !noCache {
+		if val, found := c.Get(cacheKey); found {
+			slog.Debug("cache hit", "key", cacheKey)
+			cached, _ = val.([]Star)
+		}
+	}
+
+	p.Report(progress.Event{Kind: progress.StageStarted, Stage: "stargazers of " + owner + "/" + repo})
+	stars, err := listStargazers(ctx, client, owner, repo, cached, p)
+	if errors.Is(err, errStargazersShifted) {
+		slog.Info("stars were removed, listing all stargazers again", "repo", owner+"/"+repo)
+		stars, err = listStargazers(ctx, client, owner, repo, nil, p)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !noCache {
+		c.SetWithExpiration(cacheKey, stars, cache.NoExpiration)
+	}
+	return stars, nil
+}
+
+// listStargazers lists the stargazers of owner/repo, reusing the complete
+// pages of cached.
+func listStargazers(ctx context.Context, client Client, owner, repo string, cached []Star, p progress.Reporter) ([]Star, error) {
+	page := 1
+	if len(cached) > 0 {
+		page = (len(cached)-1)/stargazerPageSize + 1
+	}
+	stars := slices.Clone(cached[:(page-1)*stargazerPageSize])
+	opts := &gh.ListOptions{Page: page, PerPage: stargazerPageSize}
+	for {
+		batch, resp, err := client.ListStargazers(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing stargazers of %s/%s: %w", owner, repo, err)
+		}
+		p.Report(progress.Event{Kind: progress.PageFetched})
+		if opts.Page == page && len(cached) > len(stars) {
+			if len(batch) == 0 || batch[0].GetUser().GetLogin() != cached[len(stars)].User {
+				return nil, errStargazersShifted
+			}
+		}
+		for _, s := range batch {
+			stars = append(stars, Star{User: s.GetUser().GetLogin(), StarredAt: s.GetStarredAt().Time})
+		}
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return stars, nil
+}
+
+func stargazersCacheKey(owner, repo string) string {
+	return fmt.Sprintf("stargazers:%s/%s", owner, repo)
+}
@@ -0,0 +1,89 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+	"github.com/stahnma/gh-flox/internal/cache"
+)
+
+// stargazerServer pages users as the stargazers API does and records the
+// pages requested.
+func stargazerServer(users *[]string, pages *[]int) func(context.Context, string, string, *gh.ListOptions) ([]*gh.Stargazer, *gh.Response, error) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func(_ context.Context, _, _ string, opts *gh.ListOptions) ([]*gh.Stargazer, *gh.Response, error) {
+		*pages = append(*pages, opts.Page)
+		var batch []*gh.Stargazer
+		for i := (opts.Page - 1) * opts.PerPage; i < len(*users) && i < opts.Page*opts.PerPage; i++ {
+			batch = append(batch, &gh.Stargazer{
+				User:      &gh.User{Login: gh.Ptr((*users)[i])},
+				StarredAt: &gh.Timestamp{Time: start.AddDate(0, 0, i)},
+			})
+		}
+		resp := emptyResponse()
+		if opts.Page*opts.PerPage < len(*users) {
+			resp.NextPage = opts.Page + 1
+		}
+		return batch, resp, nil
+	}
+}
+
+func userNames(n int) []string {
+	users := make([]string, n)
+	for i := range users {
+		users[i] = fmt.Sprintf("user%d", i)
+	}
+	return users
+}
+
+func TestGetStargazers_Incremental(t *testing.T) {
+	users := userNames(250)
+	var pages []int
+	client := &mockClient{}
+	client.listStargazersFn = stargazerServer(&users, &pages)
+	c := cache.New()
+
+	stars, err := GetStargazers(context.Background(), client, c, "flox", "flox", false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stars) != 250 || stars[249].User != "user249" || !stars[1].StarredAt.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("got %d stars, last %+v", len(stars), stars[len(stars)-1])
+	}
+
+	// Only the partial last page and the new one are fetched again.
+	users = append(users, userNames(400)[250:360]...)
+	pages = nil
+	if stars, err = GetStargazers(context.Background(), client, c, "flox", "flox", false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(stars) != 360 || !slices.Equal(pages, []int{3, 4}) {
+		t.Errorf("got %d stars fetching pages %v, want 360 from pages 3 and 4", len(stars), pages)
+	}
+}
+
+func TestGetStargazers_RemovedStars(t *testing.T) {
+	users := userNames(150)
+	var pages []int
+	client := &mockClient{}
+	client.listStargazersFn = stargazerServer(&users, &pages)
+	c := cache.New()
+	if _, err := GetStargazers(context.Background(), client, c, "flox", "flox", false, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// An early unstar shifts every later page, so the list is fetched anew.
+	users = users[1:]
+	pages = nil
+	stars, err := GetStargazers(context.Background(), client, c, "flox", "flox", false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stars) != 149 || stars[0].User != "user1" || !slices.Equal(pages, []int{2, 1, 2}) {
+		t.Errorf("got %d stars starting with %s, fetched pages %v", len(stars), stars[0].User, pages)
+	}
+}
@@ -0,0 +1,230 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+)
+
+// Rate limit resources reported by the GitHub API.
+const (
+	ResourceCore       = "core"
+	ResourceSearch     = "search"
+	ResourceCodeSearch = "code_search"
+	ResourceGraphQL    = "graphql"
+)
+
+// CallStats counts API calls per client method and records the most recent
+// rate limit headers seen for each resource.
+type CallStats struct {
+	mu    sync.Mutex
+	calls map[string]int
+	rates map[string]gh.Rate
+}
+
+// NewCallStats creates an empty CallStats.
+func NewCallStats() *CallStats {
+	return &CallStats{
+		calls: make(map[string]int),
+		rates: make(map[string]gh.Rate),
+	}
+}
+
+// record counts a call to method and stores the rate headers from resp.
+func (s *CallStats) record(method, resource string, resp *gh.Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[method]++
+	if resp == nil || resp.Response == nil || resp.Rate.Limit == 0 {
+		return
+	}
+	if r := resp.Header.Get("X-Ratelimit-Resource"); r != "" {
+		resource = r
+	}
+	s.rates[resource] = resp.Rate
+}
+
+// Calls returns the number of calls made to method.
+func (s *CallStats) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+// Total returns the number of calls made across all methods.
+func (s *CallStats) Total() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	total := 0
+	for _, n := range s.calls {
+		total += n
+	}
+	return total
+}
+
+// LastRate returns the most recent rate limit seen for resource.
+func (s *CallStats) LastRate(resource string) (gh.Rate, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.rates[resource]
+	return r, ok
+}
+
+// WriteSummary writes a table of call counts and last-seen rate limits to w.
+func (s *CallStats) WriteSummary(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tCALLS")
+	total := 0
+	for _, method := range sortedKeys(s.calls) {
+		fmt.Fprintf(tw, "%s\t%d\n", method, s.calls[method])
+		total += s.calls[method]
+	}
+	fmt.Fprintf(tw, "total\t%d\n", total)
+	if len(s.rates) > 0 {
+		fmt.Fprintln(tw, "\nRESOURCE\tLIMIT\tREMAINING\tRESET")
+		for _, resource := range sortedKeys(s.rates) {
+			r := s.rates[resource]
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", resource, r.Limit, r.Remaining, r.Reset.Format(time.TimeOnly))
+		}
+	}
+	tw.Flush()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// InstrumentedClient wraps a Client and records every call in a CallStats.
+type InstrumentedClient struct {
+	inner Client
+	stats *CallStats
+}
+
+// Instrument wraps client so that calls are recorded in stats.
+func Instrument(client Client, stats *CallStats) *InstrumentedClient {
+	return &InstrumentedClient{inner: client, stats: stats}
+}
+
+func (c *InstrumentedClient) SearchCode(ctx context.Context, query string, opts *gh.SearchOptions) (*gh.CodeSearchResult, *gh.Response, error) {
+	res, resp, err := c.inner.SearchCode(ctx, query, opts)
+	c.stats.record("SearchCode", ResourceCodeSearch, resp)
+	return res, resp, err
+}
+
+func (c *InstrumentedClient) SearchRepositories(ctx context.Context, query string, opts *gh.SearchOptions) (*gh.RepositoriesSearchResult, *gh.Response, error) {
+	res, resp, err := c.inner.SearchRepositories(ctx, query, opts)
+	c.stats.record("SearchRepositories", ResourceSearch, resp)
+	return res, resp, err
+}
+
+func (c *InstrumentedClient) GetRepository(ctx context.Context, owner, repo string) (*gh.Repository, *gh.Response, error) {
+	res, resp, err := c.inner.GetRepository(ctx, owner, repo)
+	c.stats.record("GetRepository", ResourceCore, resp)
+	return res, resp, err
+}
+
+func (c *InstrumentedClient) IsOrgMember(ctx context.Context, org, user string) (bool, *gh.Response, error) {
+	res, resp, err := c.inner.IsOrgMember(ctx, org, user)
+	c.stats.record("IsOrgMember", ResourceCore, resp)
+	return res, resp, err
+}
+
+func (c *InstrumentedClient) ListCommits(ctx context.Context, owner, repo string, opts *gh.CommitsListOptions) ([]*gh.RepositoryCommit, *gh.Response, error) {
+	res, resp, err := c.inner.ListCommits(ctx, owner, repo, opts)
+	c.stats.record("ListCommits", ResourceCore, resp)
+	return res, resp, err
+}
+
+func (c *InstrumentedClient) ListContributors(ctx context.Context, owner, repo string, opts *gh.ListContributorsOptions) ([]*gh.Contributor, *gh.Response, error) {
+	res, resp, err := c.inner.ListContributors(ctx, owner, repo, opts)
+	c.stats.record("ListContributors", ResourceCore, resp)
+	return res, resp, err
+}
+
+func (c *InstrumentedClient) GetReadme(ctx context.Context, owner, repo string, opts *gh.RepositoryContentGetOptions) (*gh.RepositoryContent, *gh.Response, error) {
+	res, resp, err := c.inner.GetReadme(ctx, owner, repo, opts)
+	c.stats.record("GetReadme", ResourceCore, resp)
+	return res, resp, err
+}
+
+func (c *InstrumentedClient) GetTree(ctx context.Context, owner, repo, sha string, recursive bool) (*gh.Tree, *gh.Response, error) {
+	res, resp, err := c.inner.GetTree(ctx, owner, repo, sha, recursive)
+	c.stats.record("GetTree", ResourceCore, resp)
+	return res, resp, err
+}
+
+func (c *InstrumentedClient) GetCommitSHA1(ctx context.Context, owner, repo, ref, lastSHA string) (string, *gh.Response, error) {
+	res, resp, err := c.inner.GetCommitSHA1(ctx, owner, repo, ref, lastSHA)
+	c.stats.record("GetCommitSHA1", ResourceCore, resp)
+	return res, resp, err
+}
+
+func (c *InstrumentedClient) GetBlobRaw(ctx context.Context, owner, repo, sha string) ([]byte, *gh.Response, error) {
+	res, resp, err := c.inner.GetBlobRaw(ctx, owner, repo, sha)
+	c.stats.record("GetBlobRaw", ResourceCore, resp)
+	return res, resp, err
+}
+
+func (c *InstrumentedClient) ListStargazers(ctx context.Context, owner, repo string, opts *gh.ListOptions) ([]*gh.Stargazer, *gh.Response, error) {
+	res, resp, err := c.inner.ListStargazers(ctx, owner, repo, opts)
+	c.stats.record("ListStargazers", ResourceCore, resp)
+	return res, resp, err
+}
+
+// GetRateLimits is not counted since /rate_limit does not consume quota.
+func (c *InstrumentedClient) GetRateLimits(ctx context.Context) (*gh.RateLimits, *gh.Response, error) {
+	return c.inner.GetRateLimits(ctx)
+}
+
+// Budget is an estimate of the API calls an operation needs.
+type Budget struct {
+	Core       int
+	Search     int
+	CodeSearch int
+}
+
+// BudgetShortfall describes a resource that does not have enough remaining calls.
+type BudgetShortfall struct {
+	Resource  string
+	Needed    int
+	Remaining int
+	Reset     time.Time
+}
+
+func (s BudgetShortfall) String() string {
+	return fmt.Sprintf("%s: need ~%d calls, %d remaining (resets %s)", s.Resource, s.Needed, s.Remaining, s.Reset.Format(time.TimeOnly))
+}
+
+// CheckBudget compares need against limits and returns any shortfalls.
+func CheckBudget(limits *gh.RateLimits, need Budget) []BudgetShortfall {
+	var shortfalls []BudgetShortfall
+	check := func(resource string, rate *gh.Rate, needed int) {
+		if rate == nil || needed <= rate.Remaining {
+			return
+		}
+		shortfalls = append(shortfalls, BudgetShortfall{
+			Resource:  resource,
+			Needed:    needed,
+			Remaining: rate.Remaining,
+			Reset:     rate.Reset.Time,
+		})
+	}
+	check(ResourceCore, limits.Core, need.Core)
+	check(ResourceSearch, limits.Search, need.Search)
+	check(ResourceCodeSearch, limits.CodeSearch, need.CodeSearch)
+	return shortfalls
+}
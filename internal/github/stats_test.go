@@ -0,0 +1,82 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	gh "github.com/google/go-github/v68/github"
+	"github.com/stahnma/gh-flox/internal/cache"
+)
+
+func TestInstrumentedClient_CountsCalls(t *testing.T) {
+	stats := NewCallStats()
+	client := Instrument(&mockClient{
+		getRepositoryFn: func(_ context.Context, _, _ string) (*gh.Repository, *gh.Response, error) {
+			resp := &gh.Response{
+				Response: &http.Response{StatusCode: 200, Header: http.Header{}},
+				Rate:     gh.Rate{Limit: 5000, Remaining: 4999},
+			}
+			return &gh.Repository{}, resp, nil
+		},
+	}, stats)
+
+	client.GetRepository(context.Background(), "a", "b")
+	client.GetRepository(context.Background(), "a", "c")
+
+	if got := stats.Calls("GetRepository"); got != 2 {
+		t.Errorf("got %d calls, want 2", got)
+	}
+	rate, ok := stats.LastRate(ResourceCore)
+	if !ok || rate.Remaining != 4999 {
+		t.Errorf("last core rate = %+v, want remaining 4999", rate)
+	}
+
+	var buf bytes.Buffer
+	stats.WriteSummary(&buf)
+	if !strings.Contains(buf.String(), "GetRepository") || !strings.Contains(buf.String(), "4999") {
+		t.Errorf("unexpected summary:\n%s", buf.String())
+	}
+}
+
+func TestCheckBudget(t *testing.T) {
+	limits := &gh.RateLimits{
+		Core:       &gh.Rate{Remaining: 100},
+		CodeSearch: &gh.Rate{Remaining: 10},
+	}
+
+	if s := CheckBudget(limits, Budget{Core: 50, CodeSearch: 5}); len(s) != 0 {
+		t.Errorf("expected no shortfalls, got %v", s)
+	}
+
+	s := CheckBudget(limits, Budget{Core: 200, CodeSearch: 5})
+	if len(s) != 1 || s[0].Resource != ResourceCore {
+		t.Errorf("expected core shortfall, got %v", s)
+	}
+}
+
+func TestEstimateSearchBudget(t *testing.T) {
+	c := cache.New()
+	b := EstimateSearchBudget(c, nil, SearchOptions{}, ManifestSignature, ReadmeSignature)
+	if b.CodeSearch != 2*maxSearchPages {
+		t.Errorf("uncached search pages = %d, want %d", b.CodeSearch, 2*maxSearchPages)
+	}
+	if want := 2 * 2 * maxSearchPages * searchPageSize; b.Core != want {
+		t.Errorf("uncached core calls = %d, want %d", b.Core, want)
+	}
+
+	c.Set(ManifestSignature.cacheKey(SearchOptions{}), []Repo{{Owner: "a", Name: "b"}, {Owner: "a", Name: "c"}, {Owner: "flox", Name: "flox"}})
+	c.Set(ReadmeSignature.cacheKey(SearchOptions{}), []Repo{{Owner: "a", Name: "b"}, {Owner: "d", Name: "e"}})
+	c.Set(repoMetadataCacheKey("a", "b"), RepoMetadata{Stars: 1})
+	c.Set(repoMetadataCacheKey("a", "c"), RepoMetadata{Stars: 1})
+	c.Set(repoMetadataCacheKey("flox", "flox"), RepoMetadata{Stars: 1})
+	mc := NewMembershipCache()
+	mc.entries["flox/d"] = false
+	b = EstimateSearchBudget(c, mc, SearchOptions{}, ManifestSignature, ReadmeSignature)
+	// d/e's stars, and owner a's membership once per search.
+	if b.CodeSearch != 0 || b.Core != 3 {
+		t.Errorf("cached estimate = %+v, want {Core:3 CodeSearch:0}", b)
+	}
+}
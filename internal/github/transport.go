@@ -0,0 +1,164 @@
+package github
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stahnma/gh-flox/internal/cache"
+)
+
+func init() {
+	gob.Register(cachedResponse{})
+}
+
+// conditionalCacheTTL is how long a validator is kept without being
+// revalidated. It is deliberately much longer than the result cache so that
+// expired results can be refreshed with a free 304.
+const conditionalCacheTTL = 7 * 24 * time.Hour
+
+// maxConditionalBody is the largest response body kept for revalidation.
+// Repository metadata, READMEs and short commit lists fit; recursive trees
+// and long listings would only grow the cache file.
+const maxConditionalBody = 64 << 10
+
+// cachedResponse is a stored API response plus the validators needed to
+// revalidate it.
+type cachedResponse struct {
+	ETag         string
+	LastModified string
+	Header       http.Header
+	Body         []byte
+}
+
+// ConditionalTransport is an http.RoundTripper that stores ETag and
+// Last-Modified validators for repository GET requests and revalidates them
+// with If-None-Match / If-Modified-Since. A 304 response is turned back into
+// the cached 200 response, so callers never see it. GitHub does not count
+// 304 responses against the core rate limit.
+type ConditionalTransport struct {
+	Base  http.RoundTripper
+	Cache *cache.Cache
+}
+
+func (t *ConditionalTransport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// cacheable reports whether req is eligible for conditional caching. Git
+// blobs are excluded: they are immutable, tracked by the download index, and
+// would bloat the cache file. So are stargazer pages, which GetStargazers
+// caches itself, and requests bounded by since or until, whose URL changes
+// with every run and could never be revalidated.
+func (t *ConditionalTransport) cacheable(req *http.Request) bool {
+	query := req.URL.Query()
+	return t.Cache != nil &&
+		req.Method == http.MethodGet &&
+		req.Header.Get("Range") == "" &&
+		strings.HasPrefix(req.URL.Path, "/repos/") &&
+		!strings.Contains(req.URL.Path, "/git/blobs/") &&
+		!strings.HasSuffix(req.URL.Path, "/stargazers") &&
+		!query.Has("since") && !query.Has("until")
+}
+
+func conditionalCacheKey(req *http.Request) string {
+	return "conditional:" + req.Header.Get("Accept") + ":" + req.URL.String()
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *ConditionalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.cacheable(req) {
+		return t.base().RoundTrip(req)
+	}
+
+	key := conditionalCacheKey(req)
+	var entry cachedResponse
+	var hasEntry bool
+	if val, found := t.Cache.Get(key); found {
+		entry, hasEntry = val.(cachedResponse)
+	}
+
+	if hasEntry {
+		req = req.Clone(req.Context())
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if hasEntry && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		t.Cache.SetWithExpiration(key, entry, conditionalCacheTTL)
+		return entry.toResponse(req, resp.Header), nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	if resp.StatusCode != http.StatusOK || (etag == "" && lastModified == "") {
+		return resp, nil
+	}
+
+	if resp.ContentLength > maxConditionalBody {
+		return resp, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxConditionalBody+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > maxConditionalBody {
+		// Too large to keep: hand back what was read followed by the rest.
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	t.Cache.SetWithExpiration(key, cachedResponse{
+		ETag:         etag,
+		LastModified: lastModified,
+		Header:       resp.Header.Clone(),
+		Body:         body,
+	}, conditionalCacheTTL)
+	return resp, nil
+}
+
+// toResponse rebuilds a 200 response from the cache, overlaying the headers
+// of the 304 so that rate limit information stays current.
+func (e cachedResponse) toResponse(req *http.Request, fresh http.Header) *http.Response {
+	header := e.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	for k, v := range fresh {
+		header[k] = v
+	}
+	header.Set("Content-Length", strconv.Itoa(len(e.Body)))
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
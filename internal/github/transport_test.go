@@ -0,0 +1,141 @@
+package github
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stahnma/gh-flox/internal/cache"
+)
+
+func newETagServer(t *testing.T, hits, notModified *int) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*hits++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			*notModified++
+			w.Header().Set("X-Ratelimit-Remaining", "4998")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("X-Ratelimit-Remaining", "4999")
+		io.WriteString(w, `{"stargazers_count": 42}`)
+	}))
+}
+
+func TestConditionalTransport_Revalidates(t *testing.T) {
+	var hits, notModified int
+	srv := newETagServer(t, &hits, &notModified)
+	defer srv.Close()
+
+	c := cache.New()
+	client := &http.Client{Transport: &ConditionalTransport{Cache: c}}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL + "/repos/flox/flox")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("request %d: status = %d, want 200", i, resp.StatusCode)
+		}
+		if string(body) != `{"stargazers_count": 42}` {
+			t.Errorf("request %d: body = %q", i, body)
+		}
+		if i == 1 && resp.Header.Get("X-Ratelimit-Remaining") != "4998" {
+			t.Errorf("expected fresh rate headers from 304, got %q", resp.Header.Get("X-Ratelimit-Remaining"))
+		}
+	}
+
+	if hits != 2 || notModified != 1 {
+		t.Errorf("hits = %d, notModified = %d; want 2 and 1", hits, notModified)
+	}
+}
+
+func TestConditionalTransport_SkipsNonRepoPaths(t *testing.T) {
+	var hits, notModified int
+	srv := newETagServer(t, &hits, &notModified)
+	defer srv.Close()
+
+	c := cache.New()
+	client := &http.Client{Transport: &ConditionalTransport{Cache: c}}
+	for _, path := range []string{"/search/code?q=x", "/repos/flox/flox/git/blobs/abc123", "/repos/flox/flox/stargazers", "/repos/flox/flox/commits?since=2024-01-01T00:00:00Z"} {
+		for i := 0; i < 2; i++ {
+			resp, err := client.Get(srv.URL + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+		}
+	}
+	if notModified != 0 {
+		t.Errorf("search, blob, stargazer and time-bounded requests should not be conditional, got %d 304s", notModified)
+	}
+}
+
+func TestConditionalTransport_PersistsAcrossRuns(t *testing.T) {
+	var hits, notModified int
+	srv := newETagServer(t, &hits, &notModified)
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "cache.gob")
+	c := cache.New()
+	client := &http.Client{Transport: &ConditionalTransport{Cache: c}}
+	resp, err := client.Get(srv.URL + "/repos/flox/flox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if err := c.SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := cache.LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: &ConditionalTransport{Cache: loaded}}
+	resp, err = client.Get(srv.URL + "/repos/flox/flox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if notModified != 1 {
+		t.Errorf("expected revalidation after reload, got %d 304s", notModified)
+	}
+}
+
+func TestConditionalTransport_SkipsLargeBodies(t *testing.T) {
+	large := strings.Repeat("x", maxConditionalBody+1)
+	conditional := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			conditional++
+		}
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, large)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &ConditionalTransport{Cache: cache.New()}}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL + "/repos/flox/flox/git/trees/abc?recursive=1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != large {
+			t.Errorf("request %d: got a %d byte body, want %d bytes", i, len(body), len(large))
+		}
+	}
+	if conditional != 0 {
+		t.Errorf("large responses should not be kept for revalidation, got %d conditional requests", conditional)
+	}
+}
@@ -1,16 +1,58 @@
 package github
 
-import "encoding/gob"
+import (
+	"encoding/gob"
+	"time"
+
+	"github.com/stahnma/gh-flox/internal/progress"
+)
 
 func init() {
 	gob.Register([]Repo{})
+	gob.Register(RepoMetadata{})
+	gob.Register(RepoActivity{})
+	gob.Register([]Star{})
+	gob.Register(time.Time{})
 }
 
-// Repo represents a GitHub repository with optional star count.
+// Repository classifications. Scoped results count only external
+// repositories; full results count all of them.
+const (
+	ClassExternal = "external"
+	ClassFloxOrg  = "flox-org"
+	ClassEmployee = "employee"
+	// ClassHandAdded marks repositories listed in additional_repos.json.
+	ClassHandAdded = "hand-added"
+	// ClassUnknown marks repositories whose owner's flox membership could
+	// not be checked. They are left out of scoped results.
+	ClassUnknown = "unknown"
+)
+
+// Repo represents a GitHub repository with its enrichment metadata.
 type Repo struct {
 	Owner string
 	Name  string
-	Stars int
+	// Classification is one of the Class constants.
+	Classification string
+	RepoMetadata
+}
+
+// RepoMetadata holds the repository attributes fetched during enrichment.
+type RepoMetadata struct {
+	Stars         int
+	Forks         int
+	Watchers      int
+	Language      string
+	Topics        []string
+	License       string
+	Archived      bool
+	Fork          bool
+	Template      bool
+	CreatedAt     time.Time
+	PushedAt      time.Time
+	OwnerType     string // "User" or "Organization"
+	DefaultBranch string
+	Description   string
 }
 
 // FullName returns the "owner/name" form.
@@ -18,17 +60,101 @@ func (r Repo) FullName() string {
 	return r.Owner + "/" + r.Name
 }
 
+// InScope reports whether r counts toward scoped results, which leave out
+// the flox organizations and flox members' own repositories.
+func (r Repo) InScope() bool {
+	return r.Classification == ClassExternal || r.Classification == ClassHandAdded
+}
+
+// ScopeRepos returns repos unchanged if showFull is set, and otherwise only
+// the repositories in scope.
+func ScopeRepos(repos []Repo, showFull bool) []Repo {
+	if showFull {
+		return repos
+	}
+	var scoped []Repo
+	for _, r := range repos {
+		if r.InScope() {
+			scoped = append(scoped, r)
+		}
+	}
+	return scoped
+}
+
 // SearchOptions controls the behavior of repository search functions.
 type SearchOptions struct {
-	ShowFull  bool
-	NoCache   bool
-	DebugMode bool
+	// ShowFull returns every repository found instead of only those in
+	// scope. Both views come from the same classified search.
+	ShowFull bool
+	NoCache  bool
+	// SkipClassification classifies repositories by owner alone, without
+	// membership checks, so flox members' own repositories count as
+	// external, and looks up their metadata with batched repository
+	// searches instead of a core API call each. Comparisons with other
+	// tools use it to fit the core rate limit.
+	SkipClassification bool
+	// Progress receives events as the search proceeds. Nil disables reporting.
+	Progress progress.Reporter
 }
 
-// RepoInfo holds repository information for JSON export.
+// RepoInfo holds repository information for JSON export. The first four
+// fields make up schema version 1; the rest were added in version 2, except
+// Classification, added in version 3. Score and ScoreComponents are only set
+// when the export includes the adoption score.
 type RepoInfo struct {
-	Date       string `json:"date"`
-	Repository string `json:"repository"`
-	Type       string `json:"type"`
-	StarCount  int    `json:"starcount"`
+	Date           string   `json:"date"`
+	Repository     string   `json:"repository"`
+	Type           string   `json:"type"`
+	StarCount      int      `json:"starcount"`
+	Forks          int      `json:"forks"`
+	Watchers       int      `json:"watchers"`
+	Language       string   `json:"language,omitempty"`
+	Topics         []string `json:"topics,omitempty"`
+	License        string   `json:"license,omitempty"`
+	Archived       bool     `json:"archived"`
+	Fork           bool     `json:"fork"`
+	Template       bool     `json:"is_template"`
+	CreatedAt      string   `json:"created_at,omitempty"`
+	PushedAt       string   `json:"pushed_at,omitempty"`
+	OwnerType      string   `json:"owner_type,omitempty"`
+	DefaultBranch  string   `json:"default_branch,omitempty"`
+	Description    string   `json:"description,omitempty"`
+	Classification string   `json:"classification,omitempty"`
+	// AdoptedAt is when the repository adopted flox according to its commit
+	// history, in RFC 3339 format.
+	AdoptedAt string `json:"adopted_at,omitempty"`
+
+	Score           float64            `json:"score,omitempty"`
+	ScoreComponents map[string]float64 `json:"score_components,omitempty"`
+}
+
+// NewRepoInfo builds the export record for r.
+func NewRepoInfo(date, repoType string, r Repo) RepoInfo {
+	return RepoInfo{
+		Date:           date,
+		Repository:     r.FullName(),
+		Type:           repoType,
+		StarCount:      r.Stars,
+		Forks:          r.Forks,
+		Watchers:       r.Watchers,
+		Language:       r.Language,
+		Topics:         r.Topics,
+		License:        r.License,
+		Archived:       r.Archived,
+		Fork:           r.Fork,
+		Template:       r.Template,
+		CreatedAt:      formatTime(r.CreatedAt),
+		PushedAt:       formatTime(r.PushedAt),
+		OwnerType:      r.OwnerType,
+		DefaultBranch:  r.DefaultBranch,
+		Description:    r.Description,
+		Classification: r.Classification,
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
 }
@@ -0,0 +1,283 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ghub "github.com/stahnma/gh-flox/internal/github"
+)
+
+// Repository types, as in exports.
+const (
+	TypeDotflox    = "dotflox"
+	TypeReadme     = "readme"
+	TypeAdditional = "additional"
+)
+
+// Import is one parsed source, ready to be applied to a Store.
+type Import struct {
+	Date      string
+	Source    string
+	Types     map[string]*Observation
+	FloxIndex *int
+	Stars     map[string]int
+}
+
+// ErrIncomplete marks a source holding partial results, which would record
+// a false drop if imported.
+var ErrIncomplete = errors.New("results are incomplete")
+
+// ErrFiltered marks an export narrowed by filter flags, whose counts are not
+// comparable with those of unfiltered scans.
+var ErrFiltered = errors.New("results are filtered")
+
+// ErrUnknownJSON marks JSON that is neither an export nor a floxindex
+// artifact, such as a diff uploaded next to them.
+var ErrUnknownJSON = errors.New("not an export or floxindex artifact")
+
+// Parse parses one source: export JSON of any schema version, a floxindex
+// artifact uploaded by the Lambda, or the text printed by repos, readmes,
+// floxindex and stars, gzipped or not. date, if set, overrides the date found
+// in the content or, failing that, in the source name.
+func Parse(source string, data []byte, date string) (*Import, error) {
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			return nil, fmt.Errorf("decompressing: %w", err)
+		}
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, errors.New("empty file")
+	}
+
+	var imp *Import
+	var err error
+	if trimmed[0] == '{' || trimmed[0] == '[' {
+		imp, err = parseJSON(trimmed)
+	} else {
+		imp, err = parseText(trimmed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	imp.Source = source
+
+	switch {
+	case date != "":
+		imp.Date, err = normalizeDate(date)
+	case imp.Date == "":
+		imp.Date, err = dateFromName(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return imp, nil
+}
+
+// parseJSON parses a floxindex artifact or an export.
+func parseJSON(data []byte) (*Import, error) {
+	var artifact struct {
+		GeneratedAt  time.Time       `json:"generated_at"`
+		FloxIndex    *int            `json:"floxindex"`
+		Repositories json.RawMessage `json:"repositories"`
+	}
+	if data[0] == '{' {
+		if err := json.Unmarshal(data, &artifact); err != nil {
+			return nil, err
+		}
+		if artifact.FloxIndex != nil {
+			return &Import{Date: formatDate(artifact.GeneratedAt), FloxIndex: artifact.FloxIndex}, nil
+		}
+		if artifact.Repositories == nil {
+			return nil, ErrUnknownJSON
+		}
+	}
+
+	exp, err := ghub.DecodeExport(data)
+	if err != nil {
+		return nil, err
+	}
+	if exp.Incomplete {
+		return nil, ErrIncomplete
+	}
+	if exp.Filter != "" {
+		return nil, ErrFiltered
+	}
+	imp := &Import{Date: formatDate(exp.GeneratedAt), Types: make(map[string]*Observation)}
+	if imp.Date == "" && exp.Date != "" {
+		if imp.Date, err = normalizeDate(exp.Date); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range exp.Repositories {
+		obs := imp.Types[r.Type]
+		if obs == nil {
+			obs = &Observation{Origin: OriginExport, Scope: exp.Scope, Stars: new(int)}
+			imp.Types[r.Type] = obs
+		}
+		rs := RepoStar{Repository: r.Repository, Stars: ptr(r.StarCount), Classification: r.Classification}
+		if rs.InScope() {
+			obs.Count++
+			*obs.Stars += r.StarCount
+		}
+		obs.Repos = append(obs.Repos, rs)
+	}
+	for _, obs := range imp.Types {
+		sortRepos(obs.Repos)
+	}
+	return imp, nil
+}
+
+// ScopedStars returns the stars of the in-scope repositories of an imported
+// export, which is its floxindex as floxindex computes it without --full.
+func (imp *Import) ScopedStars() int {
+	total := 0
+	for _, obs := range imp.Types {
+		for _, r := range obs.Repos {
+			if r.Stars != nil && r.InScope() {
+				total += *r.Stars
+			}
+		}
+	}
+	return total
+}
+
+var (
+	reposHeader     = regexp.MustCompile(`^Total unique repositories found: (\d+)(?:, Total stars: (\d+))?`)
+	readmesHeader   = regexp.MustCompile(`^Total repositories with 'flox install' in README found: (\d+)(?:, Total stars: (\d+))?`)
+	floxIndexLine   = regexp.MustCompile(`^Total floxindex \(sum of stars\): (\d+)$`)
+	trackedStarLine = regexp.MustCompile("^The repository (?::star2: )?`?([\\w.-]+/[\\w.-]+)`? has (\\d+) stars")
+	repoLine        = regexp.MustCompile(`^([\w.-]+/[\w.-]+)(?:,(\d+)(?:,(?:\d+|\?))?)?$`)
+)
+
+// parseText parses the output of repos, readmes, floxindex and stars, with
+// or without -v and Slack formatting; several outputs may be concatenated.
+// The readmes list mixes README and hand-added repositories, so all of them
+// are recorded as readme.
+func parseText(data []byte) (*Import, error) {
+	imp := &Import{Types: make(map[string]*Observation)}
+	var section *Observation
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(strings.ReplaceAll(sc.Text(), "*", ""))
+		if line == "" || line == "```" {
+			continue
+		}
+		if strings.HasPrefix(line, "INCOMPLETE:") {
+			return nil, ErrIncomplete
+		}
+
+		header, repoType := reposHeader.FindStringSubmatch(line), TypeDotflox
+		if header == nil {
+			header, repoType = readmesHeader.FindStringSubmatch(line), TypeReadme
+		}
+		if header != nil {
+			if _, dup := imp.Types[repoType]; dup {
+				return nil, fmt.Errorf("line %d: second %s listing", n, repoType)
+			}
+			count, _ := strconv.Atoi(header[1])
+			section = &Observation{Origin: OriginText, Count: count}
+			if header[2] != "" {
+				stars, _ := strconv.Atoi(header[2])
+				section.Stars = &stars
+			}
+			imp.Types[repoType] = section
+			continue
+		}
+		if m := floxIndexLine.FindStringSubmatch(line); m != nil {
+			total, _ := strconv.Atoi(m[1])
+			imp.FloxIndex = &total
+			section = nil
+			continue
+		}
+		if m := trackedStarLine.FindStringSubmatch(line); m != nil {
+			if imp.Stars == nil {
+				imp.Stars = make(map[string]int)
+			}
+			imp.Stars[m[1]], _ = strconv.Atoi(m[2])
+			section = nil
+			continue
+		}
+		if m := repoLine.FindStringSubmatch(line); m != nil {
+			if section == nil {
+				return nil, fmt.Errorf("line %d: repository %s outside a repos or readmes listing", n, m[1])
+			}
+			r := RepoStar{Repository: m[1]}
+			if m[2] != "" {
+				stars, _ := strconv.Atoi(m[2])
+				r.Stars = &stars
+			}
+			section.Repos = append(section.Repos, r)
+			continue
+		}
+		return nil, fmt.Errorf("line %d: unrecognized line %q", n, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(imp.Types) == 0 && imp.FloxIndex == nil && imp.Stars == nil {
+		return nil, errors.New("no gh-flox output found")
+	}
+	for _, obs := range imp.Types {
+		sortRepos(obs.Repos)
+	}
+	return imp, nil
+}
+
+// dateLayouts are the date layouts accepted in content and source names.
+var dateLayouts = []string{DateLayout, "2006-Jan-02", "2006/01/02", "20060102"}
+
+// namedDate matches a date in any of dateLayouts.
+var namedDate = regexp.MustCompile(`\d{4}(?:-\d{2}-|-[A-Z][a-z]{2}-|/\d{2}/)\d{2}|\d{8}`)
+
+// normalizeDate converts a date in any of dateLayouts to DateLayout.
+func normalizeDate(s string) (string, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(DateLayout), nil
+		}
+	}
+	return "", fmt.Errorf("invalid date %q: use YYYY-MM-DD", s)
+}
+
+// dateFromName finds a date in a file name or object key, preferring the
+// base name, so that both history/2024-Mar-01 and 2024/03/01/export.json
+// work.
+func dateFromName(name string) (string, error) {
+	for _, s := range []string{path.Base(name), name} {
+		for _, m := range namedDate.FindAllString(s, -1) {
+			if date, err := normalizeDate(m); err == nil {
+				return date, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no date in content or name of %s; set one with --date", name)
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(DateLayout)
+}
+
+func sortRepos(repos []RepoStar) {
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Repository < repos[j].Repository })
+}
+
+func ptr(n int) *int { return &n }
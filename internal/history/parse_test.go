@@ -0,0 +1,121 @@
+package history
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+)
+
+func TestParse_ReposVerbose(t *testing.T) {
+	text := "Total unique repositories found: 2, Total stars: 15, Total environments: 3\n" +
+		"```\nalice/app,10,2\nbob/lib,5,?\n```\n"
+	imp, err := Parse("history/2024-Mar-01", []byte(text), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imp.Date != "2024-03-01" {
+		t.Errorf("date = %q, want 2024-03-01", imp.Date)
+	}
+	obs := imp.Types[TypeDotflox]
+	if obs == nil || obs.Count != 2 || obs.Stars == nil || *obs.Stars != 15 || len(obs.Repos) != 2 {
+		t.Fatalf("unexpected observation %+v", obs)
+	}
+	if r := obs.Repos[0]; r.Repository != "alice/app" || r.Stars == nil || *r.Stars != 10 {
+		t.Errorf("unexpected repo %+v", r)
+	}
+}
+
+func TestParse_LegacyAndSlack(t *testing.T) {
+	text := "Total unique repositories found: 1\nalice/app\n" +
+		"Total repositories with 'flox install' in README found: *3*\n" +
+		"Total floxindex (sum of stars): 99\n" +
+		"The repository :star2: `flox/flox` has 2500 stars :star2:.\n"
+	imp, err := Parse("out.txt", []byte(text), "2024-01-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imp.Date != "2024-01-02" {
+		t.Errorf("date = %q", imp.Date)
+	}
+	if obs := imp.Types[TypeDotflox]; obs.Count != 1 || obs.Stars != nil || len(obs.Repos) != 1 || obs.Repos[0].Stars != nil {
+		t.Errorf("unexpected dotflox %+v", obs)
+	}
+	if obs := imp.Types[TypeReadme]; obs.Count != 3 {
+		t.Errorf("readme count = %d, want 3", obs.Count)
+	}
+	if imp.FloxIndex == nil || *imp.FloxIndex != 99 {
+		t.Errorf("floxindex = %v", imp.FloxIndex)
+	}
+	if imp.Stars["flox/flox"] != 2500 {
+		t.Errorf("stars = %v", imp.Stars)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name, text string
+	}{
+		{"orphan repo", "alice/app,1\n"},
+		{"unknown line", "Total unique repositories found: 1\nsomething else\n"},
+		{"no date", "Total unique repositories found: 1\n"},
+		{"empty", "  \n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse("out.txt", []byte(tt.text), ""); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	_, err := Parse("2024-01-02.txt", []byte("Total unique repositories found: 1\nINCOMPLETE: run interrupted, results above are partial.\n"), "")
+	if !errors.Is(err, ErrIncomplete) {
+		t.Errorf("err = %v, want ErrIncomplete", err)
+	}
+}
+
+func TestParse_Exports(t *testing.T) {
+	v1 := `[{"date":"2024-Mar-01","repository":"alice/app","type":"dotflox","starcount":4}]`
+	imp, err := Parse("a.json", []byte(v1), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imp.Date != "2024-03-01" || imp.Types[TypeDotflox].Origin != OriginExport || *imp.Types[TypeDotflox].Stars != 4 {
+		t.Errorf("unexpected v1 import %+v", imp)
+	}
+
+	v3 := `{"schema_version":3,"date":"2024-Mar-02","generated_at":"2024-03-02T10:00:00Z","scope":"all","repositories":[
+		{"repository":"carol/extra","type":"additional","starcount":7,"classification":"hand-added"},
+		{"repository":"alice/app","type":"readme","starcount":4,"classification":"external"},
+		{"repository":"flox/flox","type":"readme","starcount":900,"classification":"flox-org"}]}`
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(v3))
+	zw.Close()
+	imp, err = Parse("s3://b/2024/03/02/export.json", gz.Bytes(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	add := imp.Types[TypeAdditional]
+	if imp.Date != "2024-03-02" || add == nil || add.Scope != "all" || add.Repos[0].Classification != "hand-added" {
+		t.Errorf("unexpected v3 import %+v", imp)
+	}
+	if readme := imp.Types[TypeReadme]; readme.Count != 1 || *readme.Stars != 4 || len(readme.Repos) != 2 {
+		t.Errorf("readme observation = %+v, want only alice/app counted and both listed", readme)
+	}
+
+	if _, err := Parse("2024/03/02/diff.json", []byte(`{"from_date":"x","added":[]}`), ""); !errors.Is(err, ErrUnknownJSON) {
+		t.Errorf("err = %v, want ErrUnknownJSON", err)
+	}
+	if _, err := Parse("e.json", []byte(`{"schema_version":3,"incomplete":true,"repositories":[]}`), ""); !errors.Is(err, ErrIncomplete) {
+		t.Errorf("err = %v, want ErrIncomplete", err)
+	}
+	if _, err := Parse("e.json", []byte(`{"schema_version":3,"filter":"exclude forks","repositories":[]}`), ""); !errors.Is(err, ErrFiltered) {
+		t.Errorf("err = %v, want ErrFiltered", err)
+	}
+	imp, err = Parse("f.json", []byte(`{"generated_at":"2024-03-03T01:00:00Z","full":false,"floxindex":12}`), "")
+	if err != nil || imp.Date != "2024-03-03" || *imp.FloxIndex != 12 {
+		t.Errorf("floxindex import = %+v, %v", imp, err)
+	}
+}
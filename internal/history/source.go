@@ -0,0 +1,98 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Client is the part of the S3 client used to read S3 prefixes.
+type S3Client interface {
+	s3.ListObjectsV2APIClient
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// ParseS3URL splits s3://bucket/prefix into its bucket and prefix.
+func ParseS3URL(s string) (bucket, prefix string, err error) {
+	rest, ok := strings.CutPrefix(s, "s3://")
+	if !ok {
+		return "", "", fmt.Errorf("invalid S3 URL %q: expected s3://bucket/prefix", s)
+	}
+	bucket, prefix, _ = strings.Cut(rest, "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("invalid S3 URL %q: missing bucket", s)
+	}
+	return bucket, prefix, nil
+}
+
+// Visit is called with the name and content of each source, or with the
+// error reading it.
+type Visit func(name string, data []byte, err error) error
+
+// WalkFiles visits root, or every regular file below it if it is a
+// directory, in lexical order. Hidden files are skipped.
+func WalkFiles(root string, visit Visit) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return visit(p, nil, err)
+		}
+		if p != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		return visit(p, data, err)
+	})
+}
+
+// WalkS3 visits every JSON object under prefix in bucket, in key order.
+// Sources are named s3://bucket/key.
+func WalkS3(ctx context.Context, client S3Client, bucket, prefix string, visit Visit) error {
+	var keys []string
+	pages := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{Bucket: aws.String(bucket), Prefix: aws.String(prefix)})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("listing s3://%s/%s: %w", bucket, prefix, err)
+		}
+		for _, obj := range page.Contents {
+			if key := aws.ToString(obj.Key); strings.HasSuffix(key, ".json") || strings.HasSuffix(key, ".json.gz") {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		data, err := getObject(ctx, client, bucket, key)
+		if err := visit("s3://"+bucket+"/"+key, data, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getObject(ctx context.Context, client S3Client, bucket, key string) ([]byte, error) {
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
@@ -0,0 +1,309 @@
+// Package history keeps a JSON store of past scans: per-date repository
+// counts and star totals by type, the floxindex, and per-repository star
+// history. Snapshots are imported from the legacy text outputs and from
+// export JSON.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+
+	"github.com/stahnma/gh-flox/internal/download"
+	ghub "github.com/stahnma/gh-flox/internal/github"
+)
+
+// StoreVersion is the current store file format version.
+const StoreVersion = 1
+
+// DateLayout is the layout of snapshot dates.
+const DateLayout = "2006-01-02"
+
+// Store is the history of all imported snapshots, one per date.
+type Store struct {
+	Version   int         `json:"version"`
+	Snapshots []*Snapshot `json:"snapshots"`
+}
+
+// Snapshot is everything known about one date.
+type Snapshot struct {
+	Date string `json:"date"`
+	// Types holds the observation for each repository type, keyed by
+	// "dotflox", "readme" or "additional".
+	Types map[string]*Observation `json:"types,omitempty"`
+	// FloxIndex is the reported floxindex, if any.
+	FloxIndex *int `json:"floxindex,omitempty"`
+	// Stars holds star counts of individually tracked repositories, such as
+	// flox/flox from the stars command.
+	Stars map[string]int `json:"stars,omitempty"`
+	// Sources lists the imported files and objects, for reference.
+	Sources []string `json:"sources,omitempty"`
+}
+
+// Origins of observations.
+const (
+	OriginExport = "export"
+	OriginText   = "text"
+)
+
+// Observation is what one scan reported for one repository type.
+type Observation struct {
+	// Origin is OriginExport or OriginText.
+	Origin string `json:"origin"`
+	// Scope is "all" or "external" as recorded by version 3 exports, or
+	// empty when the output did not say.
+	Scope string `json:"scope,omitempty"`
+	// Count is the number of repositories reported. Exports of scope "all"
+	// count only their repositories in scope, as floxindex does by default.
+	Count int `json:"count"`
+	// Stars is the reported star sum of the counted repositories, if known.
+	Stars *int `json:"stars,omitempty"`
+	// Repos lists every repository reported, in scope or not.
+	Repos []RepoStar `json:"repositories,omitempty"`
+}
+
+// RepoStar is one repository in an observation.
+type RepoStar struct {
+	Repository     string `json:"repository"`
+	Stars          *int   `json:"stars,omitempty"`
+	Classification string `json:"classification,omitempty"`
+}
+
+// Load reads the store at path. A missing file yields an empty store.
+func Load(path string) (*Store, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Store{Version: StoreVersion}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s Store
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if s.Version > StoreVersion {
+		return nil, fmt.Errorf("%s: unsupported history version %d", path, s.Version)
+	}
+	s.Version = StoreVersion
+	return &s, nil
+}
+
+// Save writes the store to path atomically.
+func (s *Store) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return download.WriteFile(path, append(data, '\n'))
+}
+
+// Snapshot returns the snapshot for date, or nil.
+func (s *Store) Snapshot(date string) *Snapshot {
+	i, ok := s.find(date)
+	if !ok {
+		return nil
+	}
+	return s.Snapshots[i]
+}
+
+func (s *Store) find(date string) (int, bool) {
+	return sort.Find(len(s.Snapshots), func(i int) int {
+		switch {
+		case date < s.Snapshots[i].Date:
+			return -1
+		case date > s.Snapshots[i].Date:
+			return 1
+		}
+		return 0
+	})
+}
+
+// Outcome is the effect of applying an import.
+type Outcome string
+
+const (
+	OutcomeAdded     Outcome = "added"
+	OutcomeUpdated   Outcome = "updated"
+	OutcomeUnchanged Outcome = "unchanged"
+)
+
+// Apply merges imp into the snapshot for its date, replacing what was known
+// for the types, floxindex and star counts it carries. An export's
+// observation is never replaced by a legacy text one for the same date, so
+// the result does not depend on import order. Applying the same import twice
+// leaves the store unchanged, so re-imports are safe.
+func (s *Store) Apply(imp *Import) Outcome {
+	i, ok := s.find(imp.Date)
+	if !ok {
+		s.Snapshots = slices.Insert(s.Snapshots, i, &Snapshot{Date: imp.Date})
+	}
+	snap := s.Snapshots[i]
+	before := snap.clone()
+
+	for t, obs := range imp.Types {
+		if snap.Types == nil {
+			snap.Types = make(map[string]*Observation)
+		}
+		if prev, ok := snap.Types[t]; ok && prev.Origin == OriginExport && obs.Origin == OriginText {
+			continue
+		}
+		snap.Types[t] = obs
+	}
+	if imp.FloxIndex != nil {
+		snap.FloxIndex = imp.FloxIndex
+	}
+	for repo, stars := range imp.Stars {
+		if snap.Stars == nil {
+			snap.Stars = make(map[string]int)
+		}
+		snap.Stars[repo] = stars
+	}
+	if imp.Source != "" && !slices.Contains(snap.Sources, imp.Source) {
+		snap.Sources = append(snap.Sources, imp.Source)
+		sort.Strings(snap.Sources)
+	}
+
+	switch {
+	case !ok:
+		return OutcomeAdded
+	case reflect.DeepEqual(before, snap):
+		return OutcomeUnchanged
+	}
+	return OutcomeUpdated
+}
+
+// clone returns a copy of snap deep enough to compare against after Apply,
+// which replaces observations rather than modifying them.
+func (snap *Snapshot) clone() *Snapshot {
+	c := *snap
+	c.Types = maps.Clone(snap.Types)
+	c.Stars = maps.Clone(snap.Stars)
+	c.Sources = slices.Clone(snap.Sources)
+	return &c
+}
+
+// StarPoint is a repository's star count on a date.
+type StarPoint struct {
+	Date  string `json:"date"`
+	Stars int    `json:"stars"`
+}
+
+// RepoHistory returns the known star counts of repo by date, from tracked
+// star counts and from every observation listing it.
+func (s *Store) RepoHistory(repo string) []StarPoint {
+	var points []StarPoint
+	for _, snap := range s.Snapshots {
+		if stars, ok := snap.RepoStars(repo); ok {
+			points = append(points, StarPoint{Date: snap.Date, Stars: stars})
+		}
+	}
+	return points
+}
+
+// RepoStars returns repo's star count in snap, if recorded.
+func (snap *Snapshot) RepoStars(repo string) (int, bool) {
+	if stars, ok := snap.Stars[repo]; ok {
+		return stars, true
+	}
+	for _, t := range snap.typeNames() {
+		for _, r := range snap.Types[t].Repos {
+			if r.Repository == repo && r.Stars != nil {
+				return *r.Stars, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// StarsOn returns repo's latest recorded star count on or before date.
+func (s *Store) StarsOn(repo, date string) (int, bool) {
+	for i := len(s.Snapshots) - 1; i >= 0; i-- {
+		if s.Snapshots[i].Date > date {
+			continue
+		}
+		if stars, ok := s.Snapshots[i].RepoStars(repo); ok {
+			return stars, true
+		}
+	}
+	return 0, false
+}
+
+// InScope reports whether r counts as an adopter: external and hand-added
+// repositories, and unclassified ones from older outputs, which were already
+// scoped.
+func (r RepoStar) InScope() bool {
+	switch r.Classification {
+	case "", ghub.ClassExternal, ghub.ClassHandAdded:
+		return true
+	}
+	return false
+}
+
+// AdopterTypes are the repository types, in order of precedence for
+// repositories listed under several.
+var AdopterTypes = []string{TypeDotflox, TypeReadme, TypeAdditional}
+
+// Adopter is an in-scope repository of a snapshot.
+type Adopter struct {
+	RepoStar
+	// Type is the first type listing the repository.
+	Type string
+}
+
+// Adopters returns the in-scope repositories listed under types, by default
+// AdopterTypes, keyed by name. It returns false if an observation lacks its
+// repository list, as legacy outputs without -v do.
+func (snap *Snapshot) Adopters(types ...string) (map[string]Adopter, bool) {
+	if len(types) == 0 {
+		types = AdopterTypes
+	}
+	out := make(map[string]Adopter)
+	for _, t := range types {
+		obs, ok := snap.Types[t]
+		if !ok {
+			continue
+		}
+		if obs.Count > 0 && len(obs.Repos) == 0 {
+			return nil, false
+		}
+		for _, r := range obs.Repos {
+			if _, seen := out[r.Repository]; seen || !r.InScope() {
+				continue
+			}
+			out[r.Repository] = Adopter{RepoStar: r, Type: t}
+		}
+	}
+	return out, true
+}
+
+// FirstSeen returns the first date repo was listed in any observation, or
+// "" if it never was.
+func (s *Store) FirstSeen(repo string) string {
+	for _, snap := range s.Snapshots {
+		for _, obs := range snap.Types {
+			for _, r := range obs.Repos {
+				if r.Repository == repo {
+					return snap.Date
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// typeNames returns the observed types in sorted order.
+func (snap *Snapshot) typeNames() []string {
+	names := make([]string, 0, len(snap.Types))
+	for t := range snap.Types {
+		names = append(names, t)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,83 @@
+package history
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStore_ApplyIdempotent(t *testing.T) {
+	s := &Store{Version: StoreVersion}
+	parse := func(name, text string) *Import {
+		t.Helper()
+		imp, err := Parse(name, []byte(text), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return imp
+	}
+	later := parse("2024-03-02", "Total unique repositories found: 1, Total stars: 12, Total environments: 1\nalice/app,12,1\n")
+	earlier := parse("2024-03-01", "Total unique repositories found: 1\nalice/app\nThe repository flox/flox has 100 stars\n")
+
+	if got := s.Apply(later); got != OutcomeAdded {
+		t.Errorf("first apply = %s, want added", got)
+	}
+	if got := s.Apply(earlier); got != OutcomeAdded {
+		t.Errorf("second apply = %s, want added", got)
+	}
+	if got := s.Apply(later); got != OutcomeUnchanged {
+		t.Errorf("re-apply = %s, want unchanged", got)
+	}
+	if s.Snapshots[0].Date != "2024-03-01" || len(s.Snapshots) != 2 {
+		t.Fatalf("snapshots not sorted by date: %+v", s.Snapshots)
+	}
+
+	if got := s.FirstSeen("alice/app"); got != "2024-03-01" {
+		t.Errorf("FirstSeen = %q", got)
+	}
+	want := []StarPoint{{Date: "2024-03-02", Stars: 12}}
+	if got := s.RepoHistory("alice/app"); !reflect.DeepEqual(got, want) {
+		t.Errorf("RepoHistory = %+v, want %+v", got, want)
+	}
+	if got := s.RepoHistory("flox/flox"); len(got) != 1 || got[0].Stars != 100 {
+		t.Errorf("RepoHistory(flox/flox) = %+v", got)
+	}
+	if stars, ok := s.StarsOn("alice/app", "2024-03-05"); !ok || stars != 12 {
+		t.Errorf("StarsOn after the last count = %d, %v", stars, ok)
+	}
+	if _, ok := s.StarsOn("alice/app", "2024-03-01"); ok {
+		t.Error("StarsOn before the first count should find nothing")
+	}
+
+	path := filepath.Join(t.TempDir(), "history.json")
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, s) {
+		t.Errorf("round trip changed the store")
+	}
+}
+
+func TestStore_ExportWinsOverText(t *testing.T) {
+	s := &Store{Version: StoreVersion}
+	exp, err := Parse("e.json", []byte(`[{"date":"2024-Mar-01","repository":"alice/app","type":"dotflox","starcount":4}]`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := Parse("2024-03-01.txt", []byte("Total unique repositories found: 9\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Apply(exp)
+	s.Apply(text)
+	if got := s.Snapshot("2024-03-01").Types[TypeDotflox].Count; got != 1 {
+		t.Errorf("count = %d, want the export's 1", got)
+	}
+	if got := s.Apply(text); got != OutcomeUnchanged {
+		t.Errorf("re-apply = %s, want unchanged", got)
+	}
+}
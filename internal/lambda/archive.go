@@ -0,0 +1,119 @@
+package lambda
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/stahnma/gh-flox/internal/download"
+)
+
+// Default key templates for archived manifests. Dated prefixes keep every
+// run, building a longitudinal corpus of real-world manifests.
+const (
+	DefaultManifestKey = `manifests/{{.Date.Format "2006/01/02"}}/{{.Owner}}/{{.Repo}}/{{.Path}}`
+	DefaultArchiveKey  = `manifests/{{.Date.Format "2006/01/02"}}/manifests.tar.gz`
+)
+
+// ManifestArchiver uploads downloaded environment files to S3, one object
+// per file plus a tarball of the whole set.
+type ManifestArchiver struct {
+	Client objectPutter
+	Bucket string
+	// ManifestKey names each file's object; ArchiveKey names the tarball.
+	ManifestKey *template.Template
+	ArchiveKey  *template.Template
+	// Date is the run date passed to the key templates.
+	Date time.Time
+}
+
+// NewManifestArchiver returns an archiver using the given key templates,
+// falling back to DefaultManifestKey and DefaultArchiveKey when empty.
+func NewManifestArchiver(client objectPutter, bucket, manifestKey, archiveKey string, date time.Time) (*ManifestArchiver, error) {
+	mt, err := parseKeyTemplate("manifest", defaultString(manifestKey, DefaultManifestKey))
+	if err != nil {
+		return nil, err
+	}
+	at, err := parseKeyTemplate("archive", defaultString(archiveKey, DefaultArchiveKey))
+	if err != nil {
+		return nil, err
+	}
+	return &ManifestArchiver{Client: client, Bucket: bucket, ManifestKey: mt, ArchiveKey: at, Date: runDate(date)}, nil
+}
+
+// Archive uploads every downloaded or unchanged file in report and then the
+// tarball. It returns the number of files archived.
+func (m *ManifestArchiver) Archive(ctx context.Context, report *download.Report) (int, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	n := 0
+	for _, r := range report.Results {
+		if r.Status != download.StatusDownloaded && r.Status != download.StatusUnchanged {
+			continue
+		}
+		data, err := os.ReadFile(r.LocalPath)
+		if err != nil {
+			return n, err
+		}
+		key, err := renderKey(m.ManifestKey, KeyData{Date: m.Date, Owner: r.Owner, Repo: r.Repo, Path: r.Path})
+		if err != nil {
+			return n, err
+		}
+		if err := putObject(ctx, m.Client, m.Bucket, key, object{Body: data, ContentType: contentType(r.Path)}); err != nil {
+			return n, err
+		}
+
+		hdr := &tar.Header{
+			Name:    path.Join(r.Owner, r.Repo, r.Path),
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: m.Date,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return n, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := tw.Close(); err != nil {
+		return n, err
+	}
+	if err := gz.Close(); err != nil {
+		return n, err
+	}
+
+	key, err := renderKey(m.ArchiveKey, KeyData{Date: m.Date})
+	if err != nil {
+		return n, err
+	}
+	return n, putObject(ctx, m.Client, m.Bucket, key, object{
+		Body:        buf.Bytes(),
+		ContentType: "application/gzip",
+		Metadata:    map[string]string{"records": strconv.Itoa(n)},
+	})
+}
+
+// contentType returns the media type of an environment file.
+func contentType(p string) string {
+	if path.Ext(p) == ".toml" {
+		return "application/toml"
+	}
+	return "application/json"
+}
+
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
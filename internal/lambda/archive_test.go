@@ -0,0 +1,152 @@
+package lambda
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stahnma/gh-flox/internal/download"
+	"github.com/stahnma/gh-flox/internal/s3client"
+)
+
+// fakeS3 is a minimal S3-compatible server that records PUT requests made
+// with path-style addressing.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	headers map[string]http.Header
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{objects: map[string][]byte{}, headers: map[string]http.Header{}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "unsupported", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.objects[r.URL.Path] = body
+		f.headers[r.URL.Path] = r.Header.Clone()
+		f.mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func testS3Client(t *testing.T, endpoint string) objectPutter {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	client, err := s3client.New(context.Background(), s3client.Settings{Region: "us-east-1", Endpoint: endpoint, UsePathStyle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestManifestArchiver_Archive(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	dir := t.TempDir()
+	write := func(rel, data string) string {
+		p := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := download.WriteFile(p, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	report := &download.Report{Results: []download.Result{
+		{Owner: "alice", Repo: "app", Path: ".flox/env/manifest.toml", Status: download.StatusDownloaded,
+			LocalPath: write("alice/app/.flox/env/manifest.toml", "version = 1\n")},
+		{Owner: "alice", Repo: "app", Path: ".flox/env/manifest.lock", Status: download.StatusUnchanged,
+			LocalPath: write("alice/app/.flox/env/manifest.lock", "{}\n")},
+		{Owner: "bob", Repo: "lib", Path: ".flox/env/manifest.toml", Status: download.StatusFailed, Reason: "boom"},
+	}}
+
+	date := time.Date(2025, 3, 7, 12, 0, 0, 0, time.UTC)
+	archiver, err := NewManifestArchiver(testS3Client(t, srv.URL), "corpus", "", "", date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := archiver.Archive(context.Background(), report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("archived %d files, want 2", n)
+	}
+
+	var keys []string
+	for k := range fake.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	want := []string{
+		"/corpus/manifests/2025/03/07/alice/app/.flox/env/manifest.lock",
+		"/corpus/manifests/2025/03/07/alice/app/.flox/env/manifest.toml",
+		"/corpus/manifests/2025/03/07/manifests.tar.gz",
+	}
+	if strings.Join(keys, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("uploaded keys:\n%s\nwant:\n%s", strings.Join(keys, "\n"), strings.Join(want, "\n"))
+	}
+	if ct := fake.headers[want[1]].Get("Content-Type"); ct != "application/toml" {
+		t.Errorf("manifest content type = %q", ct)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(fake.objects[want[2]]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+	if strings.Join(names, ",") != "alice/app/.flox/env/manifest.toml,alice/app/.flox/env/manifest.lock" {
+		t.Errorf("tarball entries = %v", names)
+	}
+}
+
+func TestNewManifestArchiver_CustomKeys(t *testing.T) {
+	archiver, err := NewManifestArchiver(nil, "b", "{{.Owner}}-{{.Repo}}/{{.Path}}", "all-{{.Date.Format \"20060102\"}}.tgz", time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := renderKey(archiver.ManifestKey, KeyData{Owner: "a", Repo: "r", Path: ".flox/env/manifest.toml"})
+	if err != nil || key != "a-r/.flox/env/manifest.toml" {
+		t.Errorf("manifest key = %q, %v", key, err)
+	}
+	key, err = renderKey(archiver.ArchiveKey, KeyData{Date: archiver.Date})
+	if err != nil || key != "all-20250102.tgz" {
+		t.Errorf("archive key = %q, %v", key, err)
+	}
+
+	if _, err := NewManifestArchiver(nil, "b", "{{.Nope", "", time.Now()); err == nil {
+		t.Error("expected an invalid template error")
+	}
+	bad, _ := parseKeyTemplate("manifest", "{{.Branch}}")
+	if _, err := renderKey(bad, KeyData{}); err == nil {
+		t.Error("expected an unknown field error")
+	}
+}
@@ -0,0 +1,122 @@
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/stahnma/gh-flox/internal/digest"
+	"github.com/stahnma/gh-flox/internal/history"
+)
+
+// digestDays is the period the digest task covers; the export task must
+// have run that many days before.
+const digestDays = 7
+
+// digestRepo is the repository whose stars the digest reports.
+const digestRepo = "flox/flox"
+
+// digestResult is the digest artifact: the digest data and its renderings,
+// ready to post to Slack or send as an email body.
+type digestResult struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	// Stars is digestRepo's star count, read by the next digest.
+	Stars    int            `json:"stars"`
+	Digest   *digest.Digest `json:"digest"`
+	Markdown string         `json:"markdown"`
+	Slack    string         `json:"slack"`
+	HTML     string         `json:"html"`
+}
+
+// digestTask summarizes the week between the export uploaded digestDays ago
+// and a fresh one. The previous star count comes from the digest uploaded
+// then, if any.
+func digestTask(ctx context.Context, env *Env, req Request) (*Result, error) {
+	if env.Store == nil {
+		return nil, errors.New("S3_BUCKET_NAME environment variable must be set")
+	}
+	p, err := NewPublisher(env.Store, env.Bucket, env.Keys.ObjectKey, env.Keys.LatestKey, env.Now)
+	if err != nil {
+		return nil, err
+	}
+	prevDate := p.Date.AddDate(0, 0, -digestDays)
+	prevKey, err := renderKey(p.ObjectKey, KeyData{Date: prevDate, Kind: "export"})
+	if err != nil {
+		return nil, err
+	}
+	prevData, err := getObject(ctx, env.Store, env.Bucket, prevKey)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := history.Parse(prevKey, prevData, prevDate.Format(history.DateLayout))
+	if err != nil {
+		return nil, err
+	}
+	prevIndex := prev.ScopedStars()
+	prev.FloxIndex = &prevIndex
+	if stars, ok := env.previousStars(ctx, p, prevDate); ok {
+		prev.Stars = map[string]int{digestRepo: stars}
+	}
+
+	data, _, err := env.export(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := history.Parse("export", data, p.Date.Format(history.DateLayout))
+	if err != nil {
+		return nil, err
+	}
+	curIndex := cur.ScopedStars()
+	cur.FloxIndex = &curIndex
+	owner, name, _ := strings.Cut(digestRepo, "/")
+	stars, err := env.App.StarCount(ctx, owner, name)
+	if err != nil {
+		return nil, err
+	}
+	cur.Stars = map[string]int{digestRepo: stars}
+
+	store := &history.Store{Version: history.StoreVersion}
+	store.Apply(prev)
+	store.Apply(cur)
+	d, err := digest.Build(store, digest.Options{Days: digestDays, Repo: digestRepo})
+	if err != nil {
+		return nil, err
+	}
+
+	out := digestResult{GeneratedAt: env.Now.UTC(), Stars: stars, Digest: d}
+	for format, dst := range map[string]*string{digest.FormatMarkdown: &out.Markdown, digest.FormatSlack: &out.Slack, digest.FormatHTML: &out.HTML} {
+		var b strings.Builder
+		if err := digest.Render(&b, d, format); err != nil {
+			return nil, err
+		}
+		*dst = b.String()
+	}
+	artifact, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return env.publish(ctx, req, Artifact{Kind: "digest", Data: artifact, Records: len(d.NewAdopters) + len(d.LostAdopters)})
+}
+
+// previousStars returns the star count reported by the digest uploaded on
+// date, or false if there is none.
+func (env *Env) previousStars(ctx context.Context, p *Publisher, date time.Time) (int, bool) {
+	key, err := renderKey(p.ObjectKey, KeyData{Date: date, Kind: "digest"})
+	if err != nil {
+		return 0, false
+	}
+	data, err := getObject(ctx, env.Store, env.Bucket, key)
+	if err != nil {
+		slog.Info("no previous digest, star change unavailable", "key", key)
+		return 0, false
+	}
+	var prev digestResult
+	if err := json.Unmarshal(data, &prev); err != nil {
+		slog.Warn("previous digest unreadable, star change unavailable", "key", key, "err", err)
+		return 0, false
+	}
+	return prev.Stars, true
+}
@@ -0,0 +1,186 @@
+package lambda
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Request names a task and its parameters. It is decoded from a direct
+// invocation payload, the detail of an EventBridge event, or the query
+// string and body of an HTTP request.
+type Request struct {
+	// Task is the task to run; empty means "export".
+	Task string `json:"task"`
+	// Full includes repositories from excluded organizations in floxindex.
+	Full bool `json:"full,omitempty"`
+	// Scoped limits exports to external and hand-added repositories.
+	Scoped bool `json:"scoped,omitempty"`
+
+	// Filter parameters, matching the CLI filter flags.
+	ExcludeForks    bool   `json:"exclude_forks,omitempty"`
+	ExcludeArchived bool   `json:"exclude_archived,omitempty"`
+	ActiveWithin    string `json:"active_within,omitempty"`
+	MinStars        int    `json:"min_stars,omitempty"`
+	OwnerType       string `json:"owner_type,omitempty"`
+	Filter          string `json:"filter,omitempty"`
+
+	// Destination is where results go: "s3" (the default) uploads them,
+	// "response" returns them in the response instead.
+	Destination string `json:"destination,omitempty"`
+}
+
+// Destinations for task results.
+const (
+	DestinationS3       = "s3"
+	DestinationResponse = "response"
+)
+
+// eventSource identifies the shape of an incoming event.
+type eventSource int
+
+const (
+	sourceDirect eventSource = iota
+	sourceEventBridge
+	sourceAPIGatewayV1
+	// sourceHTTP covers API Gateway HTTP APIs and Lambda function URLs,
+	// which share the version 2.0 payload.
+	sourceHTTP
+)
+
+// isHTTP reports whether the caller expects an HTTP response.
+func (s eventSource) isHTTP() bool {
+	return s == sourceAPIGatewayV1 || s == sourceHTTP
+}
+
+// requestError is a problem with the request rather than the run, reported
+// to HTTP callers as 400 Bad Request.
+type requestError struct {
+	status int
+	msg    string
+}
+
+func (e *requestError) Error() string { return e.msg }
+
+func badRequest(format string, args ...any) error {
+	return &requestError{status: http.StatusBadRequest, msg: fmt.Sprintf(format, args...)}
+}
+
+// parseEvent decodes any supported event into a Request. An empty payload is
+// a direct invocation of the default task.
+func parseEvent(raw json.RawMessage) (Request, eventSource, error) {
+	var req Request
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" || trimmed == "null" {
+		return req, sourceDirect, nil
+	}
+
+	var probe struct {
+		RequestContext json.RawMessage `json:"requestContext"`
+		RawPath        *string         `json:"rawPath"`
+		HTTPMethod     string          `json:"httpMethod"`
+		Source         string          `json:"source"`
+		DetailType     string          `json:"detail-type"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return req, sourceDirect, badRequest("invalid event: %v", err)
+	}
+
+	switch {
+	case probe.RequestContext != nil && probe.RawPath != nil:
+		var e events.APIGatewayV2HTTPRequest
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return req, sourceHTTP, badRequest("invalid HTTP event: %v", err)
+		}
+		req, err := httpRequest(e.RequestContext.HTTP.Method, e.RawPath, e.QueryStringParameters, e.Body, e.IsBase64Encoded)
+		return req, sourceHTTP, err
+	case probe.RequestContext != nil && probe.HTTPMethod != "":
+		var e events.APIGatewayProxyRequest
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return req, sourceAPIGatewayV1, badRequest("invalid HTTP event: %v", err)
+		}
+		req, err := httpRequest(e.HTTPMethod, e.Path, e.QueryStringParameters, e.Body, e.IsBase64Encoded)
+		return req, sourceAPIGatewayV1, err
+	case probe.Source != "" && probe.DetailType != "":
+		var e events.CloudWatchEvent
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return req, sourceEventBridge, badRequest("invalid EventBridge event: %v", err)
+		}
+		// Scheduled events carry an empty detail and run the default task.
+		if len(e.Detail) > 0 && string(e.Detail) != "null" {
+			if err := json.Unmarshal(e.Detail, &req); err != nil {
+				return req, sourceEventBridge, badRequest("invalid EventBridge detail: %v", err)
+			}
+		}
+		return req, sourceEventBridge, nil
+	}
+
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return req, sourceDirect, badRequest("invalid request: %v", err)
+	}
+	return req, sourceDirect, nil
+}
+
+// httpRequest builds a Request from an HTTP request. The task is the last
+// path segment, e.g. /export, or the task parameter; a JSON body overrides
+// query parameters.
+func httpRequest(method, urlPath string, query map[string]string, body string, base64Body bool) (Request, error) {
+	var req Request
+	if method != http.MethodGet && method != http.MethodPost {
+		return req, &requestError{status: http.StatusMethodNotAllowed, msg: "method " + method + " not allowed, use GET or POST"}
+	}
+	if seg := path.Base(urlPath); seg != "/" && seg != "." {
+		req.Task = seg
+	}
+
+	var err error
+	for name, value := range query {
+		switch name {
+		case "task":
+			req.Task = value
+		case "full":
+			req.Full, err = strconv.ParseBool(value)
+		case "scoped":
+			req.Scoped, err = strconv.ParseBool(value)
+		case "exclude_forks":
+			req.ExcludeForks, err = strconv.ParseBool(value)
+		case "exclude_archived":
+			req.ExcludeArchived, err = strconv.ParseBool(value)
+		case "active_within":
+			req.ActiveWithin = value
+		case "min_stars":
+			req.MinStars, err = strconv.Atoi(value)
+		case "owner_type":
+			req.OwnerType = value
+		case "filter":
+			req.Filter = value
+		case "destination":
+			req.Destination = value
+		default:
+			return req, badRequest("unknown parameter %q", name)
+		}
+		if err != nil {
+			return req, badRequest("invalid %s %q", name, value)
+		}
+	}
+
+	if base64Body {
+		data, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return req, badRequest("invalid base64 body: %v", err)
+		}
+		body = string(data)
+	}
+	if strings.TrimSpace(body) != "" {
+		if err := json.Unmarshal([]byte(body), &req); err != nil {
+			return req, badRequest("invalid JSON body: %v", err)
+		}
+	}
+	return req, nil
+}
@@ -1,56 +1,128 @@
 package lambda
 
 import (
-	"bytes"
 	"context"
-	"fmt"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
 	"os"
+	"path"
+	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	awsconfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-lambda-go/events"
 	"github.com/stahnma/gh-flox/internal/commands"
+	"github.com/stahnma/gh-flox/internal/s3client"
 )
 
-// NewHandler returns a Lambda handler function that exports data and uploads to S3.
-func NewHandler(app *commands.App) func(context.Context, interface{}) (string, error) {
-	return func(ctx context.Context, event interface{}) (string, error) {
-		var buf bytes.Buffer
-		if err := app.ExportJSON(ctx, &buf, false); err != nil {
-			return "", fmt.Errorf("export: %w", err)
-		}
+// shutdownMargin is reserved before the Lambda deadline so that cache
+// progress can be saved and an error reported before the runtime kills us.
+const shutdownMargin = 10 * time.Second
 
-		if buf.Len() == 0 {
-			return "", fmt.Errorf("export command produced no output")
+// NewHandler returns a Lambda handler that runs the task named by the event.
+// EventBridge events, API Gateway and function URL requests, and direct
+// invocations are accepted; an empty or scheduled event runs the export.
+// HTTP callers get a status code and JSON body; other callers get the
+// Result, or an error.
+func NewHandler(app *commands.App) func(context.Context, json.RawMessage) (any, error) {
+	return func(ctx context.Context, raw json.RawMessage) (any, error) {
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline.Add(-shutdownMargin))
+			defer cancel()
 		}
 
-		s3Bucket := os.Getenv("S3_BUCKET_NAME")
-		s3ObjectKey := os.Getenv("S3_OBJECT_KEY")
-
-		if s3Bucket == "" || s3ObjectKey == "" {
-			return "", fmt.Errorf("S3_BUCKET_NAME and S3_OBJECT_KEY environment variables must be set")
+		req, source, err := parseEvent(raw)
+		var res *Result
+		if err == nil {
+			var env *Env
+			if env, err = newEnv(ctx, app); err == nil {
+				res, err = Run(ctx, env, req)
+			}
 		}
-
-		date := time.Now().Format("2006-Jan-02")
-		s3ObjectKey = fmt.Sprintf(s3ObjectKey, date)
-
-		cfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(os.Getenv("AWS_REGION")))
 		if err != nil {
-			return "", fmt.Errorf("failed to load AWS config: %w", err)
+			slog.Error("task failed", "task", req.Task, "err", err)
 		}
 
-		svc := s3.NewFromConfig(cfg)
+		if source.isHTTP() {
+			return httpResponse(source, res, err), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+}
 
-		_, err = svc.PutObject(ctx, &s3.PutObjectInput{
-			Bucket: aws.String(s3Bucket),
-			Key:    aws.String(s3ObjectKey),
-			Body:   bytes.NewReader(buf.Bytes()),
-		})
+// newEnv builds the task environment from the process environment.
+func newEnv(ctx context.Context, app *commands.App) (*Env, error) {
+	settings := s3client.SettingsFromEnvironment()
+	env := &Env{
+		App:    app,
+		Bucket: settings.Bucket,
+		Keys: KeySettings{
+			ObjectKey:   objectKeyTemplate(os.Getenv("S3_OBJECT_KEY")),
+			LatestKey:   os.Getenv("S3_LATEST_KEY"),
+			ManifestKey: os.Getenv("S3_MANIFEST_KEY"),
+			ArchiveKey:  os.Getenv("S3_ARCHIVE_KEY"),
+		},
+		GitSHA:   app.GitSHA,
+		GitDirty: app.GitDirty,
+		Now:      time.Now(),
+	}
+	if settings.Bucket != "" {
+		client, err := s3client.New(ctx, settings)
 		if err != nil {
-			return "", fmt.Errorf("failed to upload file to S3: %w", err)
+			return nil, err
 		}
+		env.Store = client
+	}
+	return env, nil
+}
+
+// objectKeyTemplate returns the S3_OBJECT_KEY template, converting the
+// legacy fmt form, whose %s was replaced with the date, to a template. The
+// legacy form named only the export, so the artifact kind is added before the
+// extension to keep the other tasks from overwriting it.
+func objectKeyTemplate(key string) string {
+	if !strings.Contains(key, "%s") {
+		return key
+	}
+	slog.Warn("S3_OBJECT_KEY uses the deprecated %s date placeholder, use a template such as " + DefaultObjectKey)
+	key = strings.ReplaceAll(key, "%s", `{{.Date.Format "2006-Jan-02"}}`)
+	ext := path.Ext(key)
+	return strings.TrimSuffix(key, ext) + "-{{.Kind}}" + ext
+}
+
+// httpResponse renders a task outcome for API Gateway or a function URL.
+func httpResponse(source eventSource, res *Result, err error) any {
+	status := http.StatusOK
+	var body any = res
+	if err != nil {
+		status = errorStatus(err)
+		body = map[string]string{"error": err.Error()}
+	}
+	data, marshalErr := json.Marshal(body)
+	if marshalErr != nil {
+		status = http.StatusInternalServerError
+		data = []byte(`{"error":"encoding response failed"}`)
+	}
+	headers := map[string]string{"Content-Type": "application/json"}
+	if source == sourceAPIGatewayV1 {
+		return events.APIGatewayProxyResponse{StatusCode: status, Headers: headers, Body: string(data)}
+	}
+	return events.APIGatewayV2HTTPResponse{StatusCode: status, Headers: headers, Body: string(data)}
+}
 
-		return "Lambda executed successfully and output uploaded to S3", nil
+// errorStatus maps an error to an HTTP status code.
+func errorStatus(err error) int {
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr):
+		return reqErr.status
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
 	}
+	return http.StatusInternalServerError
 }
@@ -0,0 +1,296 @@
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stahnma/gh-flox/internal/commands"
+	"github.com/stahnma/gh-flox/internal/download"
+	"github.com/stahnma/gh-flox/internal/filter"
+	ghub "github.com/stahnma/gh-flox/internal/github"
+)
+
+// fakeApp stands in for commands.App.
+type fakeApp struct {
+	export    ghub.Export
+	exportErr error
+	floxIndex int
+	stars     int
+	lastOpts  commands.ExportOptions
+	lastFull  bool
+	saved     int
+}
+
+func (f *fakeApp) ExportJSON(_ context.Context, w io.Writer, eo commands.ExportOptions) error {
+	f.lastOpts = eo
+	if f.exportErr != nil {
+		return f.exportErr
+	}
+	return json.NewEncoder(w).Encode(f.export)
+}
+
+func (f *fakeApp) FloxIndex(_ context.Context, showFull bool, _ filter.Filter) (int, error) {
+	f.lastFull = showFull
+	return f.floxIndex, nil
+}
+
+func (f *fakeApp) StarCount(context.Context, string, string) (int, error) {
+	return f.stars, nil
+}
+
+func (f *fakeApp) DownloadManifests(context.Context, commands.DownloadOptions) (*download.Report, error) {
+	return &download.Report{}, nil
+}
+
+func (f *fakeApp) SaveCache() error {
+	f.saved++
+	return nil
+}
+
+// memStore is an in-memory objectStore.
+type memStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (m *memStore) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[*in.Key] = data
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *memStore) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.objects[*in.Key]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))}, nil
+}
+
+func testEnv(app *fakeApp) (*Env, *memStore) {
+	store := &memStore{objects: map[string][]byte{}}
+	return &Env{App: app, Store: store, Bucket: "bucket", Now: time.Date(2025, 3, 7, 8, 0, 0, 0, time.UTC)}, store
+}
+
+func testExport(stars ...int) ghub.Export {
+	exp := ghub.Export{SchemaVersion: ghub.ExportSchemaVersion, Date: "2025-Mar-07"}
+	for i, s := range stars {
+		exp.Repositories = append(exp.Repositories, ghub.RepoInfo{Repository: "o/r" + string(rune('a'+i)), Type: "dotflox", StarCount: s})
+	}
+	return exp
+}
+
+func TestParseEvent(t *testing.T) {
+	v2 := events.APIGatewayV2HTTPRequest{RawPath: "/floxindex", QueryStringParameters: map[string]string{"full": "true"}}
+	v2.RequestContext.HTTP.Method = http.MethodGet
+	v2Body, _ := json.Marshal(v2)
+	v1 := events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Path: "/", IsBase64Encoded: true,
+		Body: base64.StdEncoding.EncodeToString([]byte(`{"task":"diff","min_stars":5}`))}
+	v1Body, _ := json.Marshal(v1)
+
+	tests := []struct {
+		name   string
+		raw    string
+		want   Request
+		source eventSource
+	}{
+		{"empty", ``, Request{}, sourceDirect},
+		{"direct", `{"task":"export","full":true,"filter":"stars >= 10"}`, Request{Task: "export", Full: true, Filter: "stars >= 10"}, sourceDirect},
+		{"scheduled", `{"source":"aws.events","detail-type":"Scheduled Event","detail":{}}`, Request{}, sourceEventBridge},
+		{"eventbridge detail", `{"source":"custom","detail-type":"run","detail":{"task":"manifests"}}`, Request{Task: "manifests"}, sourceEventBridge},
+		{"function url", string(v2Body), Request{Task: "floxindex", Full: true}, sourceHTTP},
+		{"api gateway", string(v1Body), Request{Task: "diff", MinStars: 5}, sourceAPIGatewayV1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, source, err := parseEvent(json.RawMessage(tt.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || source != tt.source {
+				t.Errorf("got %+v from %v, want %+v from %v", got, source, tt.want, tt.source)
+			}
+		})
+	}
+}
+
+func TestParseEvent_BadHTTPRequest(t *testing.T) {
+	v2 := events.APIGatewayV2HTTPRequest{RawPath: "/export", QueryStringParameters: map[string]string{"full": "maybe"}}
+	v2.RequestContext.HTTP.Method = http.MethodGet
+	raw, _ := json.Marshal(v2)
+	if _, _, err := parseEvent(raw); errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad parameter, got %v", err)
+	}
+
+	v2.RequestContext.HTTP.Method = http.MethodDelete
+	v2.QueryStringParameters = nil
+	raw, _ = json.Marshal(v2)
+	if _, _, err := parseEvent(raw); errorStatus(err) != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for DELETE, got %v", err)
+	}
+}
+
+func TestRun_Export(t *testing.T) {
+	app := &fakeApp{export: testExport(3, 4)}
+	env, store := testEnv(app)
+
+	res, err := Run(context.Background(), env, Request{Scoped: true, MinStars: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Task != "export" || res.Records != 2 || len(res.Keys) != 2 {
+		t.Errorf("result = %+v", res)
+	}
+	if !app.lastOpts.Scoped || app.lastOpts.Filter.MinStars != 2 {
+		t.Errorf("export options = %+v", app.lastOpts)
+	}
+	if _, ok := store.objects["2025/03/07/export.json"]; !ok {
+		t.Errorf("expected dated export, got keys %v", res.Keys)
+	}
+	if app.saved != 1 {
+		t.Errorf("cache saved %d times, want 1", app.saved)
+	}
+}
+
+func TestRun_ResponseDestination(t *testing.T) {
+	app := &fakeApp{floxIndex: 42}
+	env, store := testEnv(app)
+	env.Store = nil
+
+	res, err := Run(context.Background(), env, Request{Task: "floxindex", Destination: DestinationResponse})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out floxIndexResult
+	if err := json.Unmarshal(res.Output, &out); err != nil || out.FloxIndex != 42 {
+		t.Errorf("output = %s, %v", res.Output, err)
+	}
+	if len(store.objects) != 0 {
+		t.Error("expected nothing uploaded")
+	}
+}
+
+func TestRun_Diff(t *testing.T) {
+	app := &fakeApp{export: testExport(3, 4)}
+	env, store := testEnv(app)
+
+	if _, err := Run(context.Background(), env, Request{Task: "diff"}); err == nil || !strings.Contains(err.Error(), "no previous export") {
+		t.Fatalf("expected missing previous export error, got %v", err)
+	}
+	if _, err := Run(context.Background(), env, Request{Task: "export"}); err != nil {
+		t.Fatal(err)
+	}
+
+	app.export = testExport(3, 10, 1)
+	res, err := Run(context.Background(), env, Request{Task: "diff", Destination: DestinationResponse})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d ghub.ExportDiff
+	if err := json.Unmarshal(res.Output, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.StarsBefore != 7 || d.StarsAfter != 14 || len(d.Added) != 1 || len(d.Changed) != 1 {
+		t.Errorf("diff = %+v", d)
+	}
+	if len(store.objects) != 2 {
+		t.Errorf("expected only the export objects, got %d", len(store.objects))
+	}
+}
+
+func TestRun_Digest(t *testing.T) {
+	app := &fakeApp{export: testExport(3, 4), stars: 100}
+	env, store := testEnv(app)
+	env.Now = env.Now.AddDate(0, 0, -7)
+	if _, err := Run(context.Background(), env, Request{Task: "digest"}); err == nil || !strings.Contains(err.Error(), "no previous export") {
+		t.Fatalf("expected missing previous export error, got %v", err)
+	}
+	if _, err := Run(context.Background(), env, Request{Task: "export"}); err != nil {
+		t.Fatal(err)
+	}
+	// A digest a week ago records the star count the next one starts from.
+	store.objects["2025/02/28/digest.json"] = []byte(`{"stars": 90}`)
+
+	env.Now = env.Now.AddDate(0, 0, 7)
+	app.export = testExport(5, 4, 20)
+	res, err := Run(context.Background(), env, Request{Task: "digest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := getObject(context.Background(), store, "bucket", "2025/03/07/digest.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out digestResult
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	d := out.Digest
+	if d.From != "2025-02-28" || d.To != "2025-03-07" || out.Stars != 100 {
+		t.Errorf("digest = %+v", out)
+	}
+	if d.Stars == nil || d.Stars.Delta != 10 || d.FloxIndex == nil || d.FloxIndex.Delta != 22 {
+		t.Errorf("stars = %+v, floxindex = %+v", d.Stars, d.FloxIndex)
+	}
+	if len(d.NewAdopters) != 1 || d.NewAdopters[0].Repository != "o/rc" || res.Records != 1 {
+		t.Errorf("new adopters = %+v, records = %d", d.NewAdopters, res.Records)
+	}
+	if !strings.Contains(out.Slack, "<https://github.com/o/rc|o/rc>") || !strings.Contains(out.HTML, `href="https://github.com/o/rc"`) || !strings.HasPrefix(out.Markdown, "# flox digest") {
+		t.Errorf("renderings = %q, %q, %q", out.Markdown, out.Slack, out.HTML)
+	}
+}
+
+func TestHTTPResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"ok", nil, http.StatusOK},
+		{"unknown task", &requestError{status: http.StatusNotFound, msg: "unknown task"}, http.StatusNotFound},
+		{"timeout", context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{"failure", errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := httpResponse(sourceHTTP, &Result{Task: "export"}, tt.err).(events.APIGatewayV2HTTPResponse)
+			if resp.StatusCode != tt.status || resp.Headers["Content-Type"] != "application/json" || !json.Valid([]byte(resp.Body)) {
+				t.Errorf("response = %+v", resp)
+			}
+		})
+	}
+	if _, ok := httpResponse(sourceAPIGatewayV1, &Result{}, nil).(events.APIGatewayProxyResponse); !ok {
+		t.Error("expected a REST API response for API Gateway v1 events")
+	}
+}
+
+func TestRun_UnknownTask(t *testing.T) {
+	env, _ := testEnv(&fakeApp{})
+	_, err := Run(context.Background(), env, Request{Task: "nope"})
+	if errorStatus(err) != http.StatusNotFound {
+		t.Errorf("expected 404, got %v", err)
+	}
+	_, err = Run(context.Background(), env, Request{Filter: "stars >>"})
+	if errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad filter, got %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package lambda
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Default key templates for published artifacts. Keys depend only on the
+// run date and artifact kind, so re-invocations on the same day overwrite
+// the same objects.
+const (
+	DefaultObjectKey = `{{.Date.Format "2006/01/02"}}/{{.Kind}}.json`
+	DefaultLatestKey = `{{.Kind}}/latest.json`
+)
+
+// Artifact is one JSON document produced by a run.
+type Artifact struct {
+	// Kind names the artifact in keys and metadata, e.g. "export".
+	Kind string
+	Data []byte
+	// Records is the number of records in Data, attached as metadata.
+	Records int
+}
+
+// Publisher uploads artifacts gzip-encoded to a dated key and a latest key.
+type Publisher struct {
+	Client objectPutter
+	Bucket string
+	// ObjectKey names the dated object; LatestKey the copy that is always
+	// overwritten with the newest run.
+	ObjectKey *template.Template
+	LatestKey *template.Template
+	// Date is the run date passed to the key templates.
+	Date time.Time
+	// GitSHA and GitDirty describe the build, attached as metadata.
+	GitSHA   string
+	GitDirty string
+}
+
+// NewPublisher returns a publisher using the given key templates, falling
+// back to DefaultObjectKey and DefaultLatestKey when empty. date is truncated
+// to its UTC day. Every task publishes with the same templates, so each must
+// use .Kind to keep the artifacts apart.
+func NewPublisher(client objectPutter, bucket, objectKey, latestKey string, date time.Time) (*Publisher, error) {
+	ot, err := parseArtifactKeyTemplate("object", defaultString(objectKey, DefaultObjectKey))
+	if err != nil {
+		return nil, err
+	}
+	lt, err := parseArtifactKeyTemplate("latest", defaultString(latestKey, DefaultLatestKey))
+	if err != nil {
+		return nil, err
+	}
+	return &Publisher{Client: client, Bucket: bucket, ObjectKey: ot, LatestKey: lt, Date: runDate(date)}, nil
+}
+
+// parseArtifactKeyTemplate parses an artifact key template, rejecting one
+// that does not reference .Kind.
+func parseArtifactKeyTemplate(name, text string) (*template.Template, error) {
+	if !strings.Contains(text, ".Kind") {
+		return nil, fmt.Errorf("%s key template %q must include {{.Kind}}", name, text)
+	}
+	return parseKeyTemplate(name, text)
+}
+
+// Publish uploads a to its dated and latest keys and returns the keys.
+func (p *Publisher) Publish(ctx context.Context, a Artifact) ([]string, error) {
+	// A zero gzip header carries no name or timestamp, so identical data
+	// always compresses to identical bytes.
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(a.Data); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(a.Data)
+	obj := object{
+		Body:            buf.Bytes(),
+		ContentType:     "application/json",
+		ContentEncoding: "gzip",
+		Metadata: map[string]string{
+			"kind":           a.Kind,
+			"records":        strconv.Itoa(a.Records),
+			"content-sha256": hex.EncodeToString(sum[:]),
+			"git-sha":        p.GitSHA,
+			"git-dirty":      p.GitDirty,
+		},
+	}
+
+	var keys []string
+	for _, t := range []*template.Template{p.ObjectKey, p.LatestKey} {
+		key, err := renderKey(t, KeyData{Date: p.Date, Kind: a.Kind})
+		if err != nil {
+			return keys, err
+		}
+		if err := putObject(ctx, p.Client, p.Bucket, key, obj); err != nil {
+			return keys, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// runDate truncates t to midnight UTC, so every key rendered on one day is
+// the same however often the Lambda runs.
+func runDate(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,114 @@
+package lambda
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestPublisher_Publish(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	date := time.Date(2025, 3, 7, 23, 59, 0, 0, time.FixedZone("PST", -8*3600))
+	p, err := NewPublisher(testS3Client(t, srv.URL), "bucket", "", "", date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.GitSHA = "abc1234"
+	data := []byte(`{"repositories":[]}`)
+
+	keys, err := p.Publish(context.Background(), Artifact{Kind: "export", Data: data, Records: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] != "2025/03/08/export.json" || keys[1] != "export/latest.json" {
+		t.Fatalf("keys = %v", keys)
+	}
+
+	for _, key := range keys {
+		h := fake.headers["/bucket/"+key]
+		if h.Get("Content-Type") != "application/json" || h.Get("Content-Encoding") != "gzip" {
+			t.Errorf("%s: content type %q, encoding %q", key, h.Get("Content-Type"), h.Get("Content-Encoding"))
+		}
+		if h.Get("X-Amz-Meta-Git-Sha") != "abc1234" || h.Get("X-Amz-Meta-Records") != "3" || h.Get("X-Amz-Meta-Kind") != "export" {
+			t.Errorf("%s: metadata %v", key, h)
+		}
+		if h.Get("X-Amz-Meta-Git-Dirty") != "" {
+			t.Errorf("%s: expected empty metadata to be left out", key)
+		}
+		gz, err := gzip.NewReader(bytes.NewReader(fake.objects["/bucket/"+key]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(gz)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("%s: body %q, %v", key, got, err)
+		}
+	}
+
+	// A second run the same day overwrites the same objects with the same
+	// bytes.
+	first := append([]byte(nil), fake.objects["/bucket/"+keys[0]]...)
+	later, err := NewPublisher(p.Client, "bucket", "", "", date.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := later.Publish(context.Background(), Artifact{Kind: "export", Data: data, Records: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again[0] != keys[0] || !bytes.Equal(fake.objects["/bucket/"+keys[0]], first) {
+		t.Errorf("expected a deterministic overwrite, got keys %v", again)
+	}
+}
+
+func TestObjectKeyTemplate_Legacy(t *testing.T) {
+	tmpl, err := parseKeyTemplate("object", objectKeyTemplate("gh-flox-%s.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := renderKey(tmpl, KeyData{Date: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), Kind: "export"})
+	if err != nil || key != "gh-flox-2025-Jan-02-export.json" {
+		t.Errorf("key = %q, %v", key, err)
+	}
+}
+
+func TestPublisher_LegacyKeySeparatesKinds(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	date := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	p, err := NewPublisher(testS3Client(t, srv.URL), "bucket", objectKeyTemplate("gh-flox-%s.json"), "", date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exportKeys, err := p.Publish(context.Background(), Artifact{Kind: "export", Data: []byte(`{"repositories":[]}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexKeys, err := p.Publish(context.Background(), Artifact{Kind: "floxindex", Data: []byte(`{"floxindex":1}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exportKeys[0] != "gh-flox-2025-Jan-02-export.json" || indexKeys[0] != "gh-flox-2025-Jan-02-floxindex.json" {
+		t.Fatalf("keys = %v and %v", exportKeys, indexKeys)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(fake.objects["/bucket/"+exportKeys[0]]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(gz); string(got) != `{"repositories":[]}` {
+		t.Errorf("export was overwritten: %q", got)
+	}
+}
+
+func TestNewPublisher_RequiresKind(t *testing.T) {
+	for _, keys := range [][2]string{
+		{`{{.Date.Format "2006/01/02"}}.json`, ""},
+		{"", "latest.json"},
+	} {
+		if _, err := NewPublisher(nil, "bucket", keys[0], keys[1], time.Now()); err == nil {
+			t.Errorf("expected templates %q to be rejected", keys)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// objectPutter is the part of the S3 client used for uploads.
+type objectPutter interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// KeyData is the data available to S3 key templates.
+type KeyData struct {
+	// Date is the day of the run, at midnight UTC.
+	Date time.Time
+	// Kind names the artifact, e.g. "export".
+	Kind string
+	// Owner, Repo and Path identify an archived file; they are empty for
+	// whole-run artifacts.
+	Owner string
+	Repo  string
+	Path  string
+}
+
+// object is an S3 object to upload.
+type object struct {
+	Body            []byte
+	ContentType     string
+	ContentEncoding string
+	Metadata        map[string]string
+}
+
+// putObject uploads obj to key. Empty metadata values are left out.
+func putObject(ctx context.Context, client objectPutter, bucket, key string, obj object) error {
+	in := &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(obj.Body),
+		ContentType: aws.String(obj.ContentType),
+	}
+	if obj.ContentEncoding != "" {
+		in.ContentEncoding = aws.String(obj.ContentEncoding)
+	}
+	for k, v := range obj.Metadata {
+		if v == "" {
+			continue
+		}
+		if in.Metadata == nil {
+			in.Metadata = map[string]string{}
+		}
+		in.Metadata[k] = v
+	}
+	if _, err := client.PutObject(ctx, in); err != nil {
+		return fmt.Errorf("failed to upload %s to S3: %w", key, err)
+	}
+	return nil
+}
+
+// parseKeyTemplate parses an S3 key template, rejecting unknown fields.
+func parseKeyTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s key template: %w", name, err)
+	}
+	return t, nil
+}
+
+// renderKey executes a key template. An empty key is an error, since S3
+// would reject it.
+func renderKey(t *template.Template, data KeyData) (string, error) {
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("rendering %s key: %w", t.Name(), err)
+	}
+	key := strings.TrimPrefix(b.String(), "/")
+	if key == "" {
+		return "", fmt.Errorf("rendering %s key: empty key", t.Name())
+	}
+	return key, nil
+}
@@ -0,0 +1,323 @@
+package lambda
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stahnma/gh-flox/internal/commands"
+	"github.com/stahnma/gh-flox/internal/download"
+	"github.com/stahnma/gh-flox/internal/filter"
+	ghub "github.com/stahnma/gh-flox/internal/github"
+)
+
+// App is the part of commands.App that tasks use.
+type App interface {
+	ExportJSON(ctx context.Context, w io.Writer, eo commands.ExportOptions) error
+	FloxIndex(ctx context.Context, showFull bool, f filter.Filter) (int, error)
+	StarCount(ctx context.Context, owner, repo string) (int, error)
+	DownloadManifests(ctx context.Context, do commands.DownloadOptions) (*download.Report, error)
+	SaveCache() error
+}
+
+// objectStore is the part of the S3 client that tasks use.
+type objectStore interface {
+	objectPutter
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// KeySettings holds the S3 key templates; empty values use the defaults.
+type KeySettings struct {
+	ObjectKey   string
+	LatestKey   string
+	ManifestKey string
+	ArchiveKey  string
+}
+
+// Env is everything a task needs besides its request.
+type Env struct {
+	App App
+	// Store and Bucket are the S3 destination. Store is nil when no bucket
+	// is configured, in which case only the response destination works.
+	Store  objectStore
+	Bucket string
+	Keys   KeySettings
+	// GitSHA and GitDirty describe the build.
+	GitSHA   string
+	GitDirty string
+	// Now is the time of the run.
+	Now time.Time
+}
+
+// Result is the outcome of a task.
+type Result struct {
+	Task    string `json:"task"`
+	Message string `json:"message"`
+	// Keys are the S3 objects written.
+	Keys    []string `json:"keys,omitempty"`
+	Records int      `json:"records"`
+	// Output holds the artifact when the destination is "response".
+	Output json.RawMessage `json:"output,omitempty"`
+}
+
+// Task runs one kind of work for a request.
+type Task func(ctx context.Context, env *Env, req Request) (*Result, error)
+
+// tasks maps task names to their implementations.
+var tasks = map[string]Task{
+	"export":    exportTask,
+	"floxindex": floxIndexTask,
+	"diff":      diffTask,
+	"digest":    digestTask,
+	"manifests": manifestsTask,
+}
+
+// TaskNames returns the names of all tasks, sorted.
+func TaskNames() []string {
+	names := make([]string, 0, len(tasks))
+	for name := range tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run runs the task named by req and saves the cache afterwards, even if the
+// task failed, so progress carries over to the next invocation.
+func Run(ctx context.Context, env *Env, req Request) (*Result, error) {
+	name := defaultString(req.Task, "export")
+	task, ok := tasks[name]
+	if !ok {
+		return nil, &requestError{status: http.StatusNotFound, msg: fmt.Sprintf("unknown task %q, use one of %v", name, TaskNames())}
+	}
+	switch req.Destination {
+	case "", DestinationS3, DestinationResponse:
+	default:
+		return nil, badRequest("invalid destination %q, use %s or %s", req.Destination, DestinationS3, DestinationResponse)
+	}
+
+	res, err := task(ctx, env, req)
+	if saveErr := env.App.SaveCache(); saveErr != nil {
+		slog.Error("saving cache failed", "err", saveErr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	res.Task = name
+	return res, nil
+}
+
+// filter builds the repository filter from the request's parameters.
+func (req Request) filter() (filter.Filter, error) {
+	f := filter.Filter{
+		ExcludeForks:    req.ExcludeForks,
+		ExcludeArchived: req.ExcludeArchived,
+		MinStars:        req.MinStars,
+	}
+	if req.ActiveWithin != "" {
+		d, err := filter.ParseDuration(req.ActiveWithin)
+		if err != nil {
+			return f, badRequest("active_within: %v", err)
+		}
+		f.ActiveWithin = d
+	}
+	ot, err := filter.NormalizeOwnerType(req.OwnerType)
+	if err != nil {
+		return f, badRequest("owner_type: %v", err)
+	}
+	f.OwnerType = ot
+	if req.Filter != "" {
+		expr, err := filter.Parse(req.Filter)
+		if err != nil {
+			return f, badRequest("filter: %v", err)
+		}
+		f.Expr = expr
+	}
+	return f, nil
+}
+
+// export runs an export for req and decodes it.
+func (env *Env) export(ctx context.Context, req Request) ([]byte, *ghub.Export, error) {
+	f, err := req.filter()
+	if err != nil {
+		return nil, nil, err
+	}
+	var buf bytes.Buffer
+	if err := env.App.ExportJSON(ctx, &buf, commands.ExportOptions{Scoped: req.Scoped, Filter: f}); err != nil {
+		return nil, nil, err
+	}
+	exp, err := ghub.DecodeExport(buf.Bytes())
+	if err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), exp, nil
+}
+
+// publish delivers an artifact to the request's destination.
+func (env *Env) publish(ctx context.Context, req Request, a Artifact) (*Result, error) {
+	res := &Result{Records: a.Records}
+	if req.Destination == DestinationResponse {
+		res.Message = fmt.Sprintf("%s returned in response", a.Kind)
+		res.Output = json.RawMessage(a.Data)
+		return res, nil
+	}
+	if env.Store == nil {
+		return nil, errors.New("S3_BUCKET_NAME environment variable must be set")
+	}
+	p, err := NewPublisher(env.Store, env.Bucket, env.Keys.ObjectKey, env.Keys.LatestKey, env.Now)
+	if err != nil {
+		return nil, err
+	}
+	p.GitSHA, p.GitDirty = env.GitSHA, env.GitDirty
+	if res.Keys, err = p.Publish(ctx, a); err != nil {
+		return nil, err
+	}
+	res.Message = fmt.Sprintf("%s uploaded to S3", a.Kind)
+	return res, nil
+}
+
+func exportTask(ctx context.Context, env *Env, req Request) (*Result, error) {
+	data, exp, err := env.export(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return env.publish(ctx, req, Artifact{Kind: "export", Data: data, Records: len(exp.Repositories)})
+}
+
+// floxIndexResult is the floxindex artifact.
+type floxIndexResult struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Full        bool      `json:"full"`
+	Filter      string    `json:"filter,omitempty"`
+	FloxIndex   int       `json:"floxindex"`
+}
+
+func floxIndexTask(ctx context.Context, env *Env, req Request) (*Result, error) {
+	f, err := req.filter()
+	if err != nil {
+		return nil, err
+	}
+	total, err := env.App.FloxIndex(ctx, req.Full, f)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(floxIndexResult{GeneratedAt: env.Now.UTC(), Full: req.Full, Filter: f.String(), FloxIndex: total}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return env.publish(ctx, req, Artifact{Kind: "floxindex", Data: data, Records: 1})
+}
+
+// diffTask compares a fresh export with the latest one uploaded by the
+// export task.
+func diffTask(ctx context.Context, env *Env, req Request) (*Result, error) {
+	if env.Store == nil {
+		return nil, errors.New("S3_BUCKET_NAME environment variable must be set")
+	}
+	p, err := NewPublisher(env.Store, env.Bucket, env.Keys.ObjectKey, env.Keys.LatestKey, env.Now)
+	if err != nil {
+		return nil, err
+	}
+	key, err := renderKey(p.LatestKey, KeyData{Date: p.Date, Kind: "export"})
+	if err != nil {
+		return nil, err
+	}
+	prevData, err := getObject(ctx, env.Store, env.Bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := ghub.DecodeExport(prevData)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", key, err)
+	}
+
+	_, cur, err := env.export(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	d := ghub.DiffExports(prev, cur)
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return env.publish(ctx, req, Artifact{Kind: "diff", Data: data, Records: len(d.Added) + len(d.Removed) + len(d.Changed)})
+}
+
+// getObject reads an object, decompressing gzip-encoded ones.
+func getObject(ctx context.Context, store objectStore, bucket, key string) ([]byte, error) {
+	out, err := store.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		var noKey *types.NoSuchKey
+		if errors.As(err, &noKey) {
+			return nil, fmt.Errorf("no previous export at %s, run the export task first", key)
+		}
+		return nil, fmt.Errorf("failed to download %s from S3: %w", key, err)
+	}
+	defer out.Body.Close()
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(gz)
+	}
+	return data, nil
+}
+
+// manifestsTask downloads the manifest and lock file of every repository
+// into a temporary directory and archives them to S3.
+func manifestsTask(ctx context.Context, env *Env, req Request) (*Result, error) {
+	if req.Destination == DestinationResponse {
+		return nil, badRequest("the manifests task only supports the %s destination", DestinationS3)
+	}
+	if env.Store == nil {
+		return nil, errors.New("S3_BUCKET_NAME environment variable must be set")
+	}
+	archiver, err := NewManifestArchiver(env.Store, env.Bucket, env.Keys.ManifestKey, env.Keys.ArchiveKey, env.Now)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "manifests")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	report, err := env.App.DownloadManifests(ctx, commands.DownloadOptions{
+		OutputDir:   dir,
+		Files:       []string{ghub.FileManifest, ghub.FileLock},
+		Concurrency: 4,
+		Retries:     3,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if report.Summary.Failed > 0 {
+		slog.Warn("some manifest downloads failed", "failed", report.Summary.Failed)
+	}
+	n, err := archiver.Archive(ctx, report)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Message: fmt.Sprintf("%d manifest files archived to S3, %d failed", n, report.Summary.Failed),
+		Records: n,
+	}, nil
+}
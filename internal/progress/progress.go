@@ -0,0 +1,216 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind identifies what happened in a progress Event.
+type Kind int
+
+const (
+	// StageStarted begins a new stage, resetting the done/total counters.
+	StageStarted Kind = iota
+	// PageFetched reports a page of search results; Total carries the
+	// number of results the search expects to return.
+	PageFetched
+	// MembershipChecked reports an org membership lookup.
+	MembershipChecked
+	// RepoProcessed reports one search result handled.
+	RepoProcessed
+	// StarsFetched reports a star count fetched from the API.
+	StarsFetched
+	// CacheHit reports a value served from the cache.
+	CacheHit
+	// ManifestDownloaded reports one repository's manifests handled.
+	ManifestDownloaded
+)
+
+// Event is a single progress notification.
+type Event struct {
+	Kind  Kind
+	Stage string
+	Total int
+}
+
+// Reporter receives progress events. Implementations must be safe for
+// concurrent use.
+type Reporter interface {
+	Report(Event)
+	Done()
+}
+
+// Nop is a Reporter that discards all events.
+type Nop struct{}
+
+func (Nop) Report(Event) {}
+func (Nop) Done()        {}
+
+// OrNop returns r, or Nop if r is nil.
+func OrNop(r Reporter) Reporter {
+	if r == nil {
+		return Nop{}
+	}
+	return r
+}
+
+const (
+	ttyInterval   = 100 * time.Millisecond
+	plainInterval = 10 * time.Second
+)
+
+var spinner = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// writerReporter renders progress to a writer: a redrawn status line on a
+// terminal, or a periodic log line otherwise.
+type writerReporter struct {
+	w        io.Writer
+	tty      bool
+	interval time.Duration
+	now      func() time.Time
+
+	mu         sync.Mutex
+	stage      string
+	stageStart time.Time
+	lastDraw   time.Time
+	total      int
+	done       int
+	pages      int
+	members    int
+	stars      int
+	cacheHits  int
+	manifests  int
+	frame      int
+	printed    bool
+}
+
+// New returns a Reporter writing to w. Output is a spinner line if w is a
+// terminal and plain periodic lines otherwise.
+func New(w io.Writer) Reporter {
+	tty := isTerminal(w)
+	interval := plainInterval
+	if tty {
+		interval = ttyInterval
+	}
+	return newWriterReporter(w, tty, interval, time.Now)
+}
+
+func newWriterReporter(w io.Writer, tty bool, interval time.Duration, now func() time.Time) *writerReporter {
+	t := now()
+	return &writerReporter{w: w, tty: tty, interval: interval, now: now, stageStart: t, lastDraw: t}
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func (r *writerReporter) Report(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch e.Kind {
+	case StageStarted:
+		r.stage = e.Stage
+		r.stageStart = r.now()
+		r.total = e.Total
+		r.done = 0
+	case PageFetched:
+		r.pages++
+		if e.Total > 0 {
+			r.total = e.Total
+		}
+	case MembershipChecked:
+		r.members++
+	case RepoProcessed:
+		r.done++
+	case StarsFetched:
+		r.stars++
+	case CacheHit:
+		r.cacheHits++
+	case ManifestDownloaded:
+		r.manifests++
+		r.done++
+	}
+
+	if now := r.now(); now.Sub(r.lastDraw) >= r.interval {
+		r.lastDraw = now
+		r.draw()
+	}
+}
+
+func (r *writerReporter) Done() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.printed {
+		return
+	}
+	if r.tty {
+		fmt.Fprint(r.w, "\r\033[K")
+		return
+	}
+	r.draw()
+}
+
+// draw writes the current status. Callers must hold r.mu.
+func (r *writerReporter) draw() {
+	r.printed = true
+	line := r.status()
+	if r.tty {
+		r.frame = (r.frame + 1) % len(spinner)
+		fmt.Fprintf(r.w, "\r\033[K%s %s", spinner[r.frame], line)
+		return
+	}
+	fmt.Fprintf(r.w, "progress: %s\n", line)
+}
+
+func (r *writerReporter) status() string {
+	var parts []string
+	if r.total > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d", r.done, r.total))
+	} else if r.done > 0 {
+		parts = append(parts, fmt.Sprintf("%d done", r.done))
+	}
+	for _, c := range []struct {
+		n     int
+		label string
+	}{
+		{r.pages, "pages"},
+		{r.members, "membership checks"},
+		{r.stars, "star lookups"},
+		{r.manifests, "manifests"},
+		{r.cacheHits, "cache hits"},
+	} {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.n, c.label))
+		}
+	}
+	if eta, ok := r.eta(); ok {
+		parts = append(parts, "ETA "+eta.Round(time.Second).String())
+	}
+	stage := r.stage
+	if stage == "" {
+		stage = "working"
+	}
+	return stage + ": " + strings.Join(parts, ", ")
+}
+
+func (r *writerReporter) eta() (time.Duration, bool) {
+	if r.total <= 0 || r.done <= 0 || r.done >= r.total {
+		return 0, false
+	}
+	elapsed := r.now().Sub(r.stageStart)
+	perItem := elapsed / time.Duration(r.done)
+	return perItem * time.Duration(r.total-r.done), true
+}
@@ -0,0 +1,72 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func TestWriterReporter_PlainPeriodicLines(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	var buf bytes.Buffer
+	r := newWriterReporter(&buf, false, 10*time.Second, clock.now)
+
+	r.Report(Event{Kind: StageStarted, Stage: "manifest search"})
+	r.Report(Event{Kind: PageFetched, Total: 4})
+	r.Report(Event{Kind: RepoProcessed})
+	if buf.Len() != 0 {
+		t.Fatalf("expected no output before interval, got %q", buf.String())
+	}
+
+	clock.t = clock.t.Add(10 * time.Second)
+	r.Report(Event{Kind: RepoProcessed})
+
+	out := buf.String()
+	for _, want := range []string{"manifest search", "2/4", "1 pages", "ETA 10s"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in %q", want, out)
+		}
+	}
+	if strings.Contains(out, "\r") {
+		t.Error("plain output should not contain carriage returns")
+	}
+
+	r.Done()
+	if strings.Count(buf.String(), "\n") != 2 {
+		t.Errorf("expected a final summary line, got %q", buf.String())
+	}
+}
+
+func TestWriterReporter_SilentWhenQuick(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	var buf bytes.Buffer
+	r := newWriterReporter(&buf, false, 10*time.Second, clock.now)
+	r.Report(Event{Kind: CacheHit})
+	r.Done()
+	if buf.Len() != 0 {
+		t.Errorf("expected no output for a quick run, got %q", buf.String())
+	}
+}
+
+func TestWriterReporter_TTYClearsLine(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	var buf bytes.Buffer
+	r := newWriterReporter(&buf, true, 100*time.Millisecond, clock.now)
+
+	clock.t = clock.t.Add(time.Second)
+	r.Report(Event{Kind: ManifestDownloaded})
+	r.Done()
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "\r\033[K") || !strings.HasSuffix(out, "\r\033[K") {
+		t.Errorf("expected redrawn and cleared status line, got %q", out)
+	}
+	if strings.Contains(out, "\n") {
+		t.Error("tty output should not contain newlines")
+	}
+}
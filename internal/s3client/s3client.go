@@ -0,0 +1,56 @@
+// Package s3client creates S3 clients configured from the environment, shared
+// by the Lambda uploads and history imports.
+package s3client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Settings describes the S3 bucket and service. Endpoint and UsePathStyle
+// allow a local S3-compatible server, such as MinIO, to stand in for AWS.
+type Settings struct {
+	Bucket       string
+	Region       string
+	Endpoint     string
+	UsePathStyle bool
+}
+
+// SettingsFromEnvironment reads Settings from S3_BUCKET_NAME, AWS_REGION,
+// S3_ENDPOINT and S3_USE_PATH_STYLE.
+func SettingsFromEnvironment() Settings {
+	return Settings{
+		Bucket:       os.Getenv("S3_BUCKET_NAME"),
+		Region:       os.Getenv("AWS_REGION"),
+		Endpoint:     os.Getenv("S3_ENDPOINT"),
+		UsePathStyle: envBool("S3_USE_PATH_STYLE"),
+	}
+}
+
+func envBool(name string) bool {
+	v := os.Getenv(name)
+	return v != "" && v != "0" && strings.ToLower(v) != "false"
+}
+
+// New returns an S3 client for s using the default AWS credential chain.
+func New(ctx context.Context, s Settings) (*s3.Client, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(s.Region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if s.Endpoint != "" {
+			o.BaseEndpoint = aws.String(s.Endpoint)
+			// S3-compatible servers do not all support the flexible
+			// checksums AWS adds by default.
+			o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+		}
+		o.UsePathStyle = s.UsePathStyle
+	}), nil
+}
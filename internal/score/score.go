@@ -0,0 +1,165 @@
+// Package score computes the activity-weighted adoption score.
+//
+// Each repository contributes the weighted sum of five signals:
+//
+//	stars         log10(1 + stars)
+//	activity      log10(1 + commits in the last 90 days)
+//	contributors  log10(1 + contributors)
+//	prominence    1 for a committed .flox manifest, 0.5 for a README mention
+//	              or a hand-added repository
+//	recency       0.5^(days since flox was adopted / 180), 0 if unknown
+//
+// The logarithms keep a single very popular repository from dominating the
+// total the way it dominates the floxindex star sum.
+package score
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Signal names, used for --weight flags, the config file and exports.
+const (
+	SignalStars        = "stars"
+	SignalActivity     = "activity"
+	SignalContributors = "contributors"
+	SignalProminence   = "prominence"
+	SignalRecency      = "recency"
+)
+
+// Signals lists the signal names in display order.
+var Signals = []string{SignalStars, SignalActivity, SignalContributors, SignalProminence, SignalRecency}
+
+// RecencyHalfLife is the time after which the recency signal halves.
+const RecencyHalfLife = 180 * 24 * time.Hour
+
+// Repository types, matching the export's type field.
+const (
+	TypeDotflox    = "dotflox"
+	TypeReadme     = "readme"
+	TypeAdditional = "additional"
+)
+
+// Weights multiplies each signal. The zero value scores everything 0.
+type Weights map[string]float64
+
+// DefaultWeights returns the weights used when none are configured.
+func DefaultWeights() Weights {
+	return Weights{
+		SignalStars:        1,
+		SignalActivity:     1,
+		SignalContributors: 0.5,
+		SignalProminence:   1,
+		SignalRecency:      0.5,
+	}
+}
+
+// Set changes the weight of the named signal.
+func (w Weights) Set(name string, v float64) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, s := range Signals {
+		if s == name {
+			w[name] = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown score signal %q (signals: %s)", name, strings.Join(Signals, ", "))
+}
+
+// String formats the weights as "stars=1 activity=1 ...".
+func (w Weights) String() string {
+	parts := make([]string, len(Signals))
+	for i, s := range Signals {
+		parts[i] = fmt.Sprintf("%s=%g", s, w[s])
+	}
+	return strings.Join(parts, " ")
+}
+
+// Input is the data scored for one repository.
+type Input struct {
+	Repo          string
+	Type          string
+	Stars         int
+	RecentCommits int
+	Contributors  int
+	// AdoptedAt is when the repository adopted flox, or zero if unknown.
+	AdoptedAt time.Time
+}
+
+// Contribution is one repository's share of the score, with each weighted
+// signal kept so changes can be explained.
+type Contribution struct {
+	Repo  string
+	Type  string
+	Stars int
+	// Components maps signal names to weighted values; they sum to Score.
+	Components map[string]float64
+	Score      float64
+}
+
+// Result is a computed adoption score.
+type Result struct {
+	Total         float64
+	Weights       Weights
+	Contributions []Contribution
+}
+
+// Compute scores inputs with weights w at time now. Contributions are sorted
+// by descending score, then by repository name.
+func Compute(inputs []Input, w Weights, now time.Time) Result {
+	res := Result{Weights: w, Contributions: make([]Contribution, 0, len(inputs))}
+	for _, in := range inputs {
+		signals := map[string]float64{
+			SignalStars:        logScale(in.Stars),
+			SignalActivity:     logScale(in.RecentCommits),
+			SignalContributors: logScale(in.Contributors),
+			SignalProminence:   prominence(in.Type),
+			SignalRecency:      recency(in.AdoptedAt, now),
+		}
+		c := Contribution{Repo: in.Repo, Type: in.Type, Stars: in.Stars, Components: make(map[string]float64, len(signals))}
+		// Add the signals in a fixed order so float rounding, and with it
+		// the ranking of ties, is the same on every run.
+		for _, name := range Signals {
+			c.Components[name] = signals[name] * w[name]
+			c.Score += c.Components[name]
+		}
+		res.Total += c.Score
+		res.Contributions = append(res.Contributions, c)
+	}
+	sort.SliceStable(res.Contributions, func(i, j int) bool {
+		a, b := res.Contributions[i], res.Contributions[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Repo < b.Repo
+	})
+	return res
+}
+
+func logScale(n int) float64 {
+	if n <= 0 {
+		return 0
+	}
+	return math.Log10(1 + float64(n))
+}
+
+func prominence(repoType string) float64 {
+	if repoType == TypeDotflox {
+		return 1
+	}
+	return 0.5
+}
+
+func recency(adopted, now time.Time) float64 {
+	if adopted.IsZero() {
+		return 0
+	}
+	age := now.Sub(adopted)
+	if age < 0 {
+		age = 0
+	}
+	return math.Pow(0.5, float64(age)/float64(RecencyHalfLife))
+}
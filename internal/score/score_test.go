@@ -0,0 +1,100 @@
+package score
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCompute_Signals(t *testing.T) {
+	in := Input{
+		Repo:          "alice/project",
+		Type:          TypeDotflox,
+		Stars:         99,
+		RecentCommits: 9,
+		Contributors:  999,
+		AdoptedAt:     now.Add(-RecencyHalfLife),
+	}
+	res := Compute([]Input{in}, DefaultWeights(), now)
+
+	c := res.Contributions[0]
+	want := map[string]float64{
+		SignalStars:        2,
+		SignalActivity:     1,
+		SignalContributors: 1.5, // log10(1000) * 0.5
+		SignalProminence:   1,
+		SignalRecency:      0.25, // half-life reached, weight 0.5
+	}
+	for name, v := range want {
+		if !approx(c.Components[name], v) {
+			t.Errorf("%s = %v, want %v", name, c.Components[name], v)
+		}
+	}
+	if !approx(c.Score, 5.75) || !approx(res.Total, 5.75) {
+		t.Errorf("score = %v, total = %v, want 5.75", c.Score, res.Total)
+	}
+}
+
+func TestCompute_DampensLargeRepos(t *testing.T) {
+	inputs := []Input{
+		{Repo: "big/llama", Type: TypeAdditional, Stars: 70000},
+		{Repo: "a/one", Type: TypeDotflox, Stars: 10, RecentCommits: 50, Contributors: 5, AdoptedAt: now},
+		{Repo: "b/two", Type: TypeDotflox, Stars: 10, RecentCommits: 50, Contributors: 5, AdoptedAt: now},
+	}
+	res := Compute(inputs, DefaultWeights(), now)
+
+	big := res.Contributions[0]
+	if big.Repo != "big/llama" {
+		t.Fatalf("expected big/llama first, got %v", res.Contributions)
+	}
+	if share := big.Score / res.Total; share > 0.5 {
+		t.Errorf("big/llama share = %.2f, want under half", share)
+	}
+	if res.Contributions[1].Repo != "a/one" {
+		t.Errorf("expected ties broken by name, got %s second", res.Contributions[1].Repo)
+	}
+}
+
+func TestCompute_UnknownAdditionIsNotRecent(t *testing.T) {
+	res := Compute([]Input{{Repo: "a/b", Type: TypeReadme}}, DefaultWeights(), now)
+	c := res.Contributions[0]
+	if c.Components[SignalRecency] != 0 {
+		t.Errorf("recency = %v, want 0", c.Components[SignalRecency])
+	}
+	if !approx(c.Components[SignalProminence], 0.5) {
+		t.Errorf("prominence = %v, want 0.5 for readme", c.Components[SignalProminence])
+	}
+}
+
+func TestCompute_Deterministic(t *testing.T) {
+	in := []Input{{Repo: "alice/project", Type: TypeReadme, Stars: 1234, RecentCommits: 17, Contributors: 3, AdoptedAt: now.AddDate(0, -5, -3)}}
+	w := Weights{SignalStars: 0.3, SignalActivity: 0.7, SignalContributors: 0.1, SignalProminence: 0.9, SignalRecency: 0.11}
+	first := Compute(in, w, now).Total
+	for i := 0; i < 50; i++ {
+		if got := Compute(in, w, now).Total; got != first {
+			t.Fatalf("run %d total = %v, want exactly %v", i, got, first)
+		}
+	}
+}
+
+func TestWeights_Set(t *testing.T) {
+	w := DefaultWeights()
+	if err := w.Set(" Stars ", 3); err != nil {
+		t.Fatal(err)
+	}
+	if w[SignalStars] != 3 {
+		t.Errorf("stars weight = %v, want 3", w[SignalStars])
+	}
+	if err := w.Set("popularity", 1); err == nil {
+		t.Error("expected error for unknown signal")
+	}
+	if got := w.String(); got != "stars=3 activity=1 contributors=0.5 prominence=1 recency=0.5" {
+		t.Errorf("String() = %q", got)
+	}
+}
@@ -0,0 +1,148 @@
+// Package stargazers turns a repository's stargazer list into new stars per
+// day, week or month and the cumulative star curve, optionally telling
+// apart stargazers who own flox-adopting repositories.
+package stargazers
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	ghub "github.com/stahnma/gh-flox/internal/github"
+	"github.com/stahnma/gh-flox/internal/trend"
+)
+
+// Options selects what Build reports.
+type Options struct {
+	// Since and Until bound the reported buckets, inclusive. Zero means from
+	// the first star and up to the last one.
+	Since, Until time.Time
+	Bucket       trend.Bucketing
+	// Adopters, if non-nil, holds the lowercased logins owning
+	// flox-adopting repositories; new stargazers among them are counted
+	// separately.
+	Adopters map[string]bool
+}
+
+// Bucket is the stars of one period.
+type Bucket struct {
+	Start time.Time `json:"start"`
+	New   int       `json:"new"`
+	// Total is the cumulative star count at the end of the bucket.
+	Total int `json:"total"`
+	// Adopters is how many of the new stargazers own a flox-adopting
+	// repository, when adopters were given.
+	Adopters *int `json:"adopters,omitempty"`
+}
+
+// Report is a repository's star growth.
+type Report struct {
+	Repo    string          `json:"repo"`
+	Total   int             `json:"total"`
+	Bucket  trend.Bucketing `json:"bucket"`
+	Buckets []Bucket        `json:"buckets"`
+	// AdopterStargazers are the stargazers in the reported range who own a
+	// flox-adopting repository, when adopters were given.
+	AdopterStargazers []string `json:"adopter_stargazers,omitempty"`
+}
+
+// Build buckets stars, which must be oldest first, as listed by the API.
+// Every bucket in the range is reported, including those without new stars.
+func Build(repo string, stars []ghub.Star, o Options) *Report {
+	bucket := o.Bucket
+	if bucket == "" {
+		bucket = trend.Weekly
+	}
+	r := &Report{Repo: repo, Total: len(stars), Bucket: bucket, Buckets: []Bucket{}}
+	if len(stars) == 0 && (o.Since.IsZero() || o.Until.IsZero()) {
+		return r
+	}
+	since, until := o.Since, o.Until
+	if since.IsZero() {
+		since = stars[0].StarredAt
+	}
+	if until.IsZero() {
+		until = stars[len(stars)-1].StarredAt
+	}
+
+	total, i := 0, 0
+	for start := bucket.Start(since); !start.After(until); start = bucket.Next(start) {
+		end := bucket.Next(start)
+		b := Bucket{Start: start}
+		if o.Adopters != nil {
+			b.Adopters = new(int)
+		}
+		for ; i < len(stars) && stars[i].StarredAt.Before(end); i++ {
+			total++
+			if stars[i].StarredAt.Before(start) {
+				continue
+			}
+			b.New++
+			if o.Adopters[strings.ToLower(stars[i].User)] {
+				*b.Adopters++
+				r.AdopterStargazers = append(r.AdopterStargazers, stars[i].User)
+			}
+		}
+		b.Total = total
+		r.Buckets = append(r.Buckets, b)
+	}
+	sort.Strings(r.AdopterStargazers)
+	return r
+}
+
+// Chart returns the new and cumulative stars as trend series, for SVG output.
+func (r *Report) Chart() *trend.Chart {
+	c := &trend.Chart{Bucket: r.Bucket}
+	newStars := trend.Series{Name: "new stars per " + string(r.Bucket)}
+	totals := trend.Series{Name: "total stars"}
+	for _, b := range r.Buckets {
+		c.Buckets = append(c.Buckets, b.Start)
+		newStars.Values = append(newStars.Values, &b.New)
+		totals.Values = append(totals.Values, &b.Total)
+	}
+	c.Series = []trend.Series{newStars, totals}
+	return c
+}
+
+// WriteText prints a summary line and a table of the buckets. In Slack mode
+// the table is wrapped in a code block so it stays aligned.
+func WriteText(w io.Writer, r *Report, slack bool) {
+	if len(r.Buckets) == 0 {
+		fmt.Fprintf(w, "%s has no stargazers in the selected range.\n", r.Repo)
+		return
+	}
+	first, last := r.Buckets[0], r.Buckets[len(r.Buckets)-1]
+	newStars := last.Total - first.Total + first.New
+	fmt.Fprintf(w, "%s: %d new stars from %s to %s, %d in total\n", r.Repo, newStars, first.Start.Format("2006-01-02"), last.Start.Format("2006-01-02"), r.Total)
+	if slack {
+		fmt.Fprintln(w, "```")
+	}
+	adopters := first.Adopters != nil
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := strings.ToUpper(string(r.Bucket)) + "\tNEW\tTOTAL"
+	if adopters {
+		header += "\tADOPTERS"
+	}
+	fmt.Fprintln(tw, header)
+	for _, b := range r.Buckets {
+		row := fmt.Sprintf("%s\t%d\t%d", b.Start.Format("2006-01-02"), b.New, b.Total)
+		if adopters {
+			row += fmt.Sprintf("\t%d", *b.Adopters)
+		}
+		fmt.Fprintln(tw, row)
+	}
+	tw.Flush()
+	if slack {
+		fmt.Fprintln(w, "```")
+	}
+	if adopters {
+		fmt.Fprintf(w, "%d of %d new stargazers own a flox-adopting repository", len(r.AdopterStargazers), newStars)
+		if len(r.AdopterStargazers) > 0 {
+			fmt.Fprintf(w, ": %s", strings.Join(r.AdopterStargazers, ", "))
+		}
+		fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,67 @@
+package stargazers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	ghub "github.com/stahnma/gh-flox/internal/github"
+	"github.com/stahnma/gh-flox/internal/trend"
+)
+
+func day(d int) time.Time {
+	return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC)
+}
+
+var testStars = []ghub.Star{
+	{User: "early", StarredAt: day(1)},
+	{User: "Alice", StarredAt: day(4)},
+	{User: "bob", StarredAt: day(5)},
+	{User: "carol", StarredAt: day(19)},
+}
+
+func TestBuild(t *testing.T) {
+	r := Build("flox/flox", testStars, Options{Bucket: trend.Weekly, Since: day(4), Adopters: map[string]bool{"alice": true, "carol": true}})
+	if r.Total != 4 || len(r.Buckets) != 3 {
+		t.Fatalf("report = %+v", r)
+	}
+	want := []struct{ new, total, adopters int }{{2, 3, 1}, {0, 3, 0}, {1, 4, 1}}
+	for i, b := range r.Buckets {
+		if b.New != want[i].new || b.Total != want[i].total || *b.Adopters != want[i].adopters {
+			t.Errorf("bucket %s = %d new, %d total, %d adopters; want %+v", b.Start.Format("2006-01-02"), b.New, b.Total, *b.Adopters, want[i])
+		}
+	}
+	if !r.Buckets[0].Start.Equal(time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("first bucket starts %v, want Monday 2024-03-04", r.Buckets[0].Start)
+	}
+	if strings.Join(r.AdopterStargazers, ",") != "Alice,carol" {
+		t.Errorf("adopter stargazers = %v", r.AdopterStargazers)
+	}
+
+	if r := Build("flox/flox", testStars, Options{Bucket: trend.Daily}); len(r.Buckets) != 19 || r.Buckets[0].Adopters != nil {
+		t.Errorf("got %d daily buckets, want 19 without adopter counts", len(r.Buckets))
+	}
+	if r := Build("flox/flox", nil, Options{}); len(r.Buckets) != 0 {
+		t.Errorf("expected no buckets without stars, got %+v", r.Buckets)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	WriteText(&buf, Build("flox/flox", testStars, Options{Bucket: trend.Monthly, Adopters: map[string]bool{"bob": true}}), false)
+	want := `flox/flox: 4 new stars from 2024-03-01 to 2024-03-01, 4 in total
+MONTH       NEW  TOTAL  ADOPTERS
+2024-03-01  4    4      1
+1 of 4 new stargazers own a flox-adopting repository: bob
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	WriteText(&buf, Build("flox/flox", nil, Options{}), true)
+	if !strings.Contains(buf.String(), "no stargazers") {
+		t.Errorf("unexpected output for no stars: %q", buf.String())
+	}
+}
@@ -0,0 +1,201 @@
+package trend
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// sparkBlocks are the sparkline levels, lowest first.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders values as one block character each, scaled between their
+// minimum and maximum. Missing values are blank.
+func Sparkline(values []*int) string {
+	lo, hi, ok := bounds(values)
+	var b strings.Builder
+	for _, v := range values {
+		switch {
+		case v == nil || !ok:
+			b.WriteRune(' ')
+		case hi == lo:
+			b.WriteRune(sparkBlocks[len(sparkBlocks)/2])
+		default:
+			b.WriteRune(sparkBlocks[(*v-lo)*(len(sparkBlocks)-1)/(hi-lo)])
+		}
+	}
+	return b.String()
+}
+
+func bounds(values []*int) (lo, hi int, ok bool) {
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		if !ok || *v < lo {
+			lo = *v
+		}
+		if !ok || *v > hi {
+			hi = *v
+		}
+		ok = true
+	}
+	return lo, hi, ok
+}
+
+// firstLast returns the first and last values present.
+func firstLast(values []*int) (first, last *int) {
+	for _, v := range values {
+		if v != nil {
+			if first == nil {
+				first = v
+			}
+			last = v
+		}
+	}
+	return first, last
+}
+
+// WriteText prints a sparkline per series with its first and last value,
+// a row of ^ markers under annotated buckets and the annotations. In Slack
+// mode the chart is wrapped in a code block so it stays aligned.
+func WriteText(w io.Writer, c *Chart, slack bool) {
+	if len(c.Buckets) == 0 {
+		fmt.Fprintln(w, "No history in the selected range.")
+		return
+	}
+	fmt.Fprintf(w, "%s to %s, %d %s buckets\n", c.Buckets[0].Format("2006-01-02"), c.Buckets[len(c.Buckets)-1].Format("2006-01-02"), len(c.Buckets), c.Bucket)
+	if slack {
+		fmt.Fprintln(w, "```")
+	}
+
+	width := 0
+	for _, s := range c.Series {
+		width = max(width, len(s.Name))
+	}
+	for _, s := range c.Series {
+		summary := "no data"
+		if first, last := firstLast(s.Values); first != nil {
+			summary = fmt.Sprintf("%d -> %d (%+d)", *first, *last, *last-*first)
+		}
+		fmt.Fprintf(w, "%-*s  %s  %s\n", width, s.Name, Sparkline(s.Values), summary)
+	}
+
+	if len(c.Annotations) > 0 {
+		markers := []rune(strings.Repeat(" ", len(c.Buckets)))
+		for _, a := range c.Annotations {
+			if i := c.BucketOf(a.Date); i >= 0 {
+				markers[i] = '^'
+			}
+		}
+		fmt.Fprintf(w, "%-*s  %s\n", width, "", strings.TrimRight(string(markers), " "))
+		for _, a := range c.Annotations {
+			fmt.Fprintf(w, "^ %s  %s\n", a.Date.Format("2006-01-02"), a.Note)
+		}
+	}
+	if slack {
+		fmt.Fprintln(w, "```")
+	}
+}
+
+// SVG layout, in pixels.
+const (
+	svgWidth       = 800
+	svgMarginLeft  = 70
+	svgMarginRight = 20
+	svgPanelHeight = 110
+	svgPanelGap    = 30
+	svgTop         = 40
+	svgNoteHeight  = 18
+)
+
+// svgColors are the series line colors, reused in order.
+var svgColors = []string{"#2563eb", "#16a34a", "#d97706", "#9333ea", "#dc2626", "#0891b2"}
+
+// WriteSVG writes c as a standalone SVG document with one panel per series,
+// each scaled to its own range, sharing the time axis. Annotations are drawn
+// as numbered dashed lines across all panels and listed below them.
+func WriteSVG(w io.Writer, c *Chart, title string) error {
+	plotWidth := svgWidth - svgMarginLeft - svgMarginRight
+	panelsHeight := len(c.Series) * (svgPanelHeight + svgPanelGap)
+	height := svgTop + panelsHeight + 20 + len(c.Annotations)*svgNoteHeight
+
+	x := func(i int) float64 {
+		if len(c.Buckets) < 2 {
+			return svgMarginLeft + float64(plotWidth)/2
+		}
+		first, last := c.Buckets[0], c.Buckets[len(c.Buckets)-1]
+		return svgMarginLeft + float64(plotWidth)*c.Buckets[i].Sub(first).Hours()/last.Sub(first).Hours()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`+"\n", svgWidth, height, svgWidth, height)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="#ffffff"/>`+"\n")
+	fmt.Fprintf(&b, `<text x="%d" y="22" font-size="15" font-weight="bold">%s</text>`+"\n", svgMarginLeft, html.EscapeString(title))
+
+	for n, s := range c.Series {
+		top := svgTop + n*(svgPanelHeight+svgPanelGap)
+		bottom := top + svgPanelHeight
+		color := svgColors[n%len(svgColors)]
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-weight="bold" fill="%s">%s</text>`+"\n", svgMarginLeft, top-6, color, html.EscapeString(s.Name))
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#9ca3af"/>`+"\n", svgMarginLeft, bottom, svgMarginLeft+plotWidth, bottom)
+
+		lo, hi, ok := bounds(s.Values)
+		if !ok {
+			fmt.Fprintf(&b, `<text x="%d" y="%d" fill="#6b7280">no data</text>`+"\n", svgMarginLeft+plotWidth/2, top+svgPanelHeight/2)
+			continue
+		}
+		y := func(v int) float64 {
+			if hi == lo {
+				return float64(top + svgPanelHeight/2)
+			}
+			return float64(bottom) - float64(svgPanelHeight)*float64(v-lo)/float64(hi-lo)
+		}
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" fill="#374151">%d</text>`+"\n", svgMarginLeft-6, y(hi)+4, hi)
+		if hi != lo {
+			fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" fill="#374151">%d</text>`+"\n", svgMarginLeft-6, y(lo)+4, lo)
+		}
+
+		// Gaps split the line so missing buckets are not interpolated.
+		var points []string
+		flush := func() {
+			if len(points) > 1 {
+				fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`+"\n", color, strings.Join(points, " "))
+			}
+			points = nil
+		}
+		for i, v := range s.Values {
+			if v == nil {
+				flush()
+				continue
+			}
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x(i), y(*v)))
+			fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="2.5" fill="%s"><title>%s: %d</title></circle>`+"\n", x(i), y(*v), color, c.Buckets[i].Format("2006-01-02"), *v)
+		}
+		flush()
+	}
+
+	axisY := svgTop + panelsHeight - svgPanelGap + 16
+	if len(c.Buckets) > 0 {
+		last := len(c.Buckets) - 1
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" fill="#374151">%s</text>`+"\n", x(0), axisY, c.Buckets[0].Format("2006-01-02"))
+		if last > 0 {
+			fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="end" fill="#374151">%s</text>`+"\n", x(last), axisY, c.Buckets[last].Format("2006-01-02"))
+		}
+	}
+
+	for n, a := range c.Annotations {
+		i := c.BucketOf(a.Date)
+		if i < 0 {
+			continue
+		}
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#dc2626" stroke-dasharray="4 3"/>`+"\n", x(i), svgTop-12, x(i), svgTop+panelsHeight-svgPanelGap)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" fill="#dc2626" text-anchor="middle">%d</text>`+"\n", x(i), svgTop-14, n+1)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" fill="#374151">%d. %s: %s</text>`+"\n", svgMarginLeft, axisY+20+n*svgNoteHeight, n+1, a.Date.Format("2006-01-02"), html.EscapeString(a.Note))
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,72 @@
+package trend
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func ints(vs ...int) []*int {
+	out := make([]*int, len(vs))
+	for i, v := range vs {
+		if v >= 0 {
+			out[i] = &vs[i]
+		}
+	}
+	return out
+}
+
+func TestSparkline(t *testing.T) {
+	if got := Sparkline(ints(0, 7, -1, 14)); got != "▁▄ █" {
+		t.Errorf("Sparkline = %q", got)
+	}
+	if got := Sparkline(ints(5, 5)); got != "▅▅" {
+		t.Errorf("flat Sparkline = %q", got)
+	}
+}
+
+func testChart() *Chart {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	return &Chart{
+		Bucket:      Daily,
+		Buckets:     []time.Time{day(1), day(2), day(3)},
+		Series:      []Series{{Name: "dotflox", Values: ints(1, -1, 3)}, {Name: "flox/flox", Values: ints(-1, -1, -1)}},
+		Annotations: []Annotation{{Date: day(3), Note: "counting <changed>"}},
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	WriteText(&buf, testChart(), true)
+	out := buf.String()
+	for _, want := range []string{"2024-03-01 to 2024-03-03, 3 day buckets", "dotflox    ▁ █  1 -> 3 (+2)", "flox/flox       no data", "             ^\n", "^ 2024-03-03  counting <changed>", "```"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSVG(&buf, testChart(), "trends"); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	dec := xml.NewDecoder(strings.NewReader(out))
+	for {
+		_, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("SVG is not well-formed: %v", err)
+		}
+	}
+	// The gap in the dotflox series leaves no line with two points.
+	if strings.Contains(out, "<polyline") || !strings.Contains(out, "counting &lt;changed&gt;") {
+		t.Errorf("unexpected SVG:\n%s", out)
+	}
+}
@@ -0,0 +1,251 @@
+// Package trend turns the history store into bucketed time series and
+// renders them as terminal sparklines and standalone SVG charts.
+package trend
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/stahnma/gh-flox/internal/history"
+)
+
+// Bucketing groups snapshots by period. Within a bucket, the latest snapshot
+// having a value wins.
+type Bucketing string
+
+const (
+	Daily   Bucketing = "day"
+	Weekly  Bucketing = "week"
+	Monthly Bucketing = "month"
+)
+
+// ParseBucketing parses a --bucket value.
+func ParseBucketing(s string) (Bucketing, error) {
+	switch b := Bucketing(s); b {
+	case Daily, Weekly, Monthly:
+		return b, nil
+	}
+	return "", fmt.Errorf("invalid bucket %q: use day, week or month", s)
+}
+
+// Start returns the first day of the bucket containing t: t's day, the
+// Monday of its ISO week or the first of its month.
+func (b Bucketing) Start(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch b {
+	case Weekly:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case Monthly:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+// Next returns the first day of the bucket after the one starting at start.
+func (b Bucketing) Next(start time.Time) time.Time {
+	switch b {
+	case Weekly:
+		return start.AddDate(0, 0, 7)
+	case Monthly:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// Series names accepted by Options.Series, besides an owner/repo name, which
+// selects that repository's stars.
+const (
+	SeriesDotflox    = history.TypeDotflox
+	SeriesReadme     = history.TypeReadme
+	SeriesAdditional = history.TypeAdditional
+	SeriesFloxIndex  = "floxindex"
+)
+
+// DefaultSeries are the series charted when none are chosen.
+var DefaultSeries = []string{SeriesDotflox, SeriesReadme, SeriesAdditional, SeriesFloxIndex, "flox/flox"}
+
+// Annotation marks a date on the chart, such as a change in how repositories
+// were counted.
+type Annotation struct {
+	Date time.Time `json:"date"`
+	Note string    `json:"note"`
+}
+
+// Options selects what Build charts.
+type Options struct {
+	// Since and Until bound the snapshot dates, inclusive; zero means
+	// unbounded.
+	Since, Until time.Time
+	Bucket       Bucketing
+	// Series names the charted series; empty means DefaultSeries.
+	Series []string
+	// Notes are extra annotations, such as those from the config file.
+	Notes []Annotation
+}
+
+// Series is one charted metric. Values has one entry per chart bucket, nil
+// where no snapshot in the bucket had a value.
+type Series struct {
+	Name   string `json:"name"`
+	Values []*int `json:"values"`
+}
+
+// Chart is a set of series over common buckets.
+type Chart struct {
+	Bucket      Bucketing    `json:"bucket"`
+	Buckets     []time.Time  `json:"buckets"`
+	Series      []Series     `json:"series"`
+	Annotations []Annotation `json:"annotations,omitempty"`
+}
+
+// Build charts the snapshots of s selected by o. Changes in how a type was
+// counted, such as from legacy text output to exports or between export
+// scopes, are annotated automatically.
+func Build(s *history.Store, o Options) (*Chart, error) {
+	names := o.Series
+	if len(names) == 0 {
+		names = DefaultSeries
+	}
+	for _, name := range names {
+		if !validSeries(name) {
+			return nil, fmt.Errorf("invalid series %q: use dotflox, readme, additional, floxindex or owner/repo", name)
+		}
+	}
+	bucket := o.Bucket
+	if bucket == "" {
+		bucket = Daily
+	}
+
+	c := &Chart{Bucket: bucket}
+	index := make(map[time.Time]int)
+	values := make([]map[int]int, len(names))
+	for i := range values {
+		values[i] = make(map[int]int)
+	}
+	for _, snap := range s.Snapshots {
+		date, err := time.Parse(history.DateLayout, snap.Date)
+		if err != nil {
+			return nil, fmt.Errorf("history snapshot %q: %w", snap.Date, err)
+		}
+		if !inRange(date, o.Since, o.Until) {
+			continue
+		}
+		start := bucket.Start(date)
+		b, ok := index[start]
+		if !ok {
+			b = len(c.Buckets)
+			index[start] = b
+			c.Buckets = append(c.Buckets, start)
+		}
+		for i, name := range names {
+			if v, ok := value(snap, name); ok {
+				values[i][b] = v
+			}
+		}
+	}
+	for i, name := range names {
+		series := Series{Name: name, Values: make([]*int, len(c.Buckets))}
+		for b, v := range values[i] {
+			series.Values[b] = &v
+		}
+		c.Series = append(c.Series, series)
+	}
+
+	c.Annotations = methodologyChanges(s, names, o.Since, o.Until)
+	for _, n := range o.Notes {
+		if inRange(n.Date, o.Since, o.Until) {
+			c.Annotations = append(c.Annotations, n)
+		}
+	}
+	sort.SliceStable(c.Annotations, func(i, j int) bool { return c.Annotations[i].Date.Before(c.Annotations[j].Date) })
+	return c, nil
+}
+
+// BucketOf returns the index of the bucket containing t, or -1.
+func (c *Chart) BucketOf(t time.Time) int {
+	start := c.Bucket.Start(t)
+	for i, b := range c.Buckets {
+		if b.Equal(start) {
+			return i
+		}
+	}
+	return -1
+}
+
+func validSeries(name string) bool {
+	switch name {
+	case SeriesDotflox, SeriesReadme, SeriesAdditional, SeriesFloxIndex:
+		return true
+	}
+	owner, repo, ok := strings.Cut(name, "/")
+	return ok && owner != "" && repo != ""
+}
+
+func inRange(t, since, until time.Time) bool {
+	return (since.IsZero() || !t.Before(since)) && (until.IsZero() || !t.After(until))
+}
+
+// value returns the named series' value in snap.
+func value(snap *history.Snapshot, name string) (int, bool) {
+	switch name {
+	case SeriesDotflox, SeriesReadme, SeriesAdditional:
+		obs, ok := snap.Types[name]
+		if !ok {
+			return 0, false
+		}
+		return obs.Count, true
+	case SeriesFloxIndex:
+		if snap.FloxIndex == nil {
+			return 0, false
+		}
+		return *snap.FloxIndex, true
+	}
+	return snap.RepoStars(name)
+}
+
+// methodology describes how an observation was counted.
+func methodology(obs *history.Observation) string {
+	if obs.Origin != history.OriginExport {
+		return "text output"
+	}
+	if obs.Scope == "" {
+		return "export"
+	}
+	return "export (scope " + obs.Scope + ")"
+}
+
+// methodologyChanges annotates each date where a charted type was counted
+// differently from its previous snapshot.
+func methodologyChanges(s *history.Store, names []string, since, until time.Time) []Annotation {
+	prev := make(map[string]string)
+	var notes []Annotation
+	for _, snap := range s.Snapshots {
+		date, _ := time.Parse(history.DateLayout, snap.Date)
+		changes := make(map[string][]string)
+		var order []string
+		for _, name := range names {
+			obs, ok := snap.Types[name]
+			if !ok {
+				continue
+			}
+			m := methodology(obs)
+			if p, seen := prev[name]; seen && p != m {
+				change := p + " to " + m
+				if _, ok := changes[change]; !ok {
+					order = append(order, change)
+				}
+				changes[change] = append(changes[change], name)
+			}
+			prev[name] = m
+		}
+		if !inRange(date, since, until) {
+			continue
+		}
+		for _, change := range order {
+			notes = append(notes, Annotation{Date: date, Note: fmt.Sprintf("%s counted from %s", strings.Join(changes[change], ", "), change)})
+		}
+	}
+	return notes
+}
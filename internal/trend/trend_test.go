@@ -0,0 +1,76 @@
+package trend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stahnma/gh-flox/internal/history"
+)
+
+func testStore(t *testing.T) *history.Store {
+	t.Helper()
+	s := &history.Store{Version: history.StoreVersion}
+	sources := map[string]string{
+		"2024-03-01": "Total unique repositories found: 10\nThe repository flox/flox has 100 stars\n",
+		"2024-03-03": "Total unique repositories found: 12\nTotal floxindex (sum of stars): 500\n",
+		"2024-03-12": `{"schema_version":3,"generated_at":"2024-03-12T00:00:00Z","scope":"all","repositories":[
+			{"repository":"a/b","type":"dotflox","starcount":1},{"repository":"c/d","type":"readme","starcount":2}]}`,
+	}
+	for name, text := range sources {
+		imp, err := history.Parse(name, []byte(text), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Apply(imp)
+	}
+	return s
+}
+
+func TestBucketing_Start(t *testing.T) {
+	thu := time.Date(2024, 3, 14, 15, 0, 0, 0, time.UTC)
+	if got := Weekly.Start(thu); !got.Equal(time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("week start = %v", got)
+	}
+	sun := time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)
+	if got := Weekly.Start(sun); !got.Equal(time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("week start of Sunday = %v", got)
+	}
+	if got := Monthly.Start(thu); got.Day() != 1 || got.Month() != time.March {
+		t.Errorf("month start = %v", got)
+	}
+	if _, err := ParseBucketing("year"); err == nil {
+		t.Error("expected an error for an unknown bucket")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	c, err := Build(testStore(t), Options{Bucket: Weekly, Series: []string{SeriesDotflox, SeriesFloxIndex, "flox/flox"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Buckets) != 2 {
+		t.Fatalf("got %d buckets, want 2", len(c.Buckets))
+	}
+	dotflox := c.Series[0].Values
+	if *dotflox[0] != 12 || *dotflox[1] != 1 {
+		t.Errorf("dotflox = %d, %d; want the latest value per week, 12 and 1", *dotflox[0], *dotflox[1])
+	}
+	if c.Series[1].Values[1] != nil || *c.Series[2].Values[0] != 100 {
+		t.Errorf("unexpected floxindex or stars series: %+v", c.Series)
+	}
+	if len(c.Annotations) != 1 || c.Annotations[0].Note != "dotflox counted from text output to export (scope all)" {
+		t.Errorf("annotations = %+v", c.Annotations)
+	}
+
+	c, err = Build(testStore(t), Options{Since: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), Until: time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Buckets) != 1 || len(c.Annotations) != 0 {
+		t.Errorf("range not applied: %+v", c)
+	}
+
+	if _, err := Build(testStore(t), Options{Series: []string{"stars"}}); err == nil {
+		t.Error("expected an error for an unknown series")
+	}
+}
@@ -1,5 +0,0 @@
-module github.com/stahnma/gh-flox/post-processing
-
-go 1.21.8
-
-require github.com/mattn/go-sqlite3 v1.14.22